@@ -3,56 +3,262 @@ package mseedio
 import (
 	"fmt"
 	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// m.Append() appends data to 1000 blockette MiniSeedData
+// m.Append() appends data to 1000 blockette MiniSeedData, as one record sized
+// to fit, or split across records of options.RecordLength bytes if set
 func (m *MiniSeedData) Append(data []int32, options *AppendOptions) error {
-	// Check if sequence number is valid
-	for _, v := range m.Series {
-		if v.FixedSection.SequenceNumber == options.SequenceNumber &&
-			v.FixedSection.ChannelCode == options.ChannelCode {
-			return fmt.Errorf("sequence number %s already exists", options.SequenceNumber)
+	if options.RecordLength != 0 {
+		return m.appendSplit(data, options)
+	}
+
+	dataBytes, err := packData(data, m.Type, m.Order)
+	if err != nil {
+		return err
+	}
+
+	return m.appendPacked(data, dataBytes, options)
+}
+
+// m.AppendBatch() appends the records of each element of data, as many calls
+// to Append would, packing the samples on up to concurrency goroutines (all
+// available CPUs if concurrency < 1), including the runs split into records
+// of options.RecordLength bytes. Records keep the order of data.
+func (m *MiniSeedData) AppendBatch(data [][]int32, options []*AppendOptions, concurrency int) error {
+	if len(data) != len(options) {
+		return fmt.Errorf("got %d data slices but %d options", len(data), len(options))
+	}
+
+	var (
+		records = make([][]packedRecord, len(data))
+		errs    = make([]error, len(data))
+	)
+	parallelFor(len(data), concurrency, func(i int) {
+		if options[i].RecordLength != 0 {
+			records[i], errs[i] = m.packSplit(data[i], options[i])
+			return
+		}
+		packed, err := packData(data[i], m.Type, m.Order)
+		records[i], errs[i] = []packedRecord{{data[i], packed, *options[i]}}, err
+	})
+
+	for i := range data {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		for j := range records[i] {
+			r := &records[i][j]
+			if err := m.appendPacked(r.data, r.packed, &r.options); err != nil {
+				return err
+			}
 		}
 	}
 
-	// Pack the data
-	var dataBytes []byte
-	switch m.Type {
+	return nil
+}
+
+// packData packs samples with the given encoding format and bit order.
+func packData(data []int32, dataType, bitOrder int) ([]byte, error) {
+	switch dataType {
 	case ASCII:
-		dataBytes = append(dataBytes, packAscii(data)...)
+		return packAscii(data), nil
 	case INT16:
-		dataBytes = append(dataBytes, packInt(data, 16, m.Order)...)
+		return packInt(data, 16, bitOrder), nil
 	case INT24:
-		dataBytes = append(dataBytes, packInt(data, 24, m.Order)...)
+		return packInt(data, 24, bitOrder), nil
 	case INT32:
-		dataBytes = append(dataBytes, packInt(data, 32, m.Order)...)
+		return packInt(data, 32, bitOrder), nil
 	case FLOAT32:
-		dataBytes = append(dataBytes, packFloat(data, 32, m.Order)...)
+		return packFloat(data, 32, bitOrder), nil
 	case FLOAT64:
-		dataBytes = append(dataBytes, packFloat(data, 64, m.Order)...)
+		return packFloat(data, 64, bitOrder), nil
 	case STEIM1:
-		result, err := packSteim1(data, m.Order)
-		if err != nil {
+		return packSteim1(data, bitOrder)
+	case STEIM2:
+		return packSteim2(data, bitOrder)
+	}
+
+	return nil, fmt.Errorf("%d is not a valid encoding format", dataType)
+}
+
+// packDataLimited packs as many leading samples as fit in capacity bytes and
+// returns the packed bytes along with the number of samples they hold.
+func packDataLimited(data []int32, capacity, dataType, bitOrder int) ([]byte, int, error) {
+	var width int
+	switch dataType {
+	case STEIM1, STEIM2:
+		if bitOrder == LSBFIRST {
+			return nil, 0, fmt.Errorf("Steim with LSBFIRST is not allowed")
+		}
+		frames := capacity / STEIM_FRAME_LENGTH
+		if frames == 0 {
+			return nil, 0, nil
+		}
+		if dataType == STEIM1 {
+			return EncodeSteim1(data, frames)
+		}
+		return EncodeSteim2(data, frames)
+	case ASCII:
+		width = 1
+	case INT16:
+		width = 2
+	case INT24:
+		width = 3
+	case INT32, FLOAT32:
+		width = 4
+	case FLOAT64:
+		width = 8
+	default:
+		return nil, 0, fmt.Errorf("%d is not a valid encoding format", dataType)
+	}
+
+	n := capacity / width
+	if n > len(data) {
+		n = len(data)
+	}
+	packed, err := packData(data[:n], dataType, bitOrder)
+	return packed, n, err
+}
+
+// packedRecord is a record packed ahead of appendPacked.
+type packedRecord struct {
+	data    []int32
+	packed  []byte
+	options AppendOptions
+}
+
+// appendSplit appends data as a run of records of options.RecordLength bytes.
+// Sequence numbers count up from options.SequenceNumber (1 if empty) and
+// start times follow on from options.StartTime; chained blockettes only go
+// into the first record.
+func (m *MiniSeedData) appendSplit(data []int32, options *AppendOptions) error {
+	records, err := m.packSplit(data, options)
+	if err != nil {
+		return err
+	}
+	for i := range records {
+		r := &records[i]
+		if err := m.appendPacked(r.data, r.packed, &r.options); err != nil {
 			return err
 		}
-		dataBytes = append(dataBytes, result...)
-	case STEIM2:
-		result, err := packSteim2(data, m.Order)
+	}
+
+	return nil
+}
+
+// packSplit packs the records of appendSplit without touching m, so that runs
+// can be packed concurrently.
+func (m *MiniSeedData) packSplit(data []int32, options *AppendOptions) ([]packedRecord, error) {
+	length := options.RecordLength
+	if length < 256 || length > 1<<16 || length&(length-1) != 0 {
+		return nil, fmt.Errorf("record length %d is not a power of 2 between 256 and 65536", length)
+	}
+
+	sequence := 1
+	if s := strings.TrimSpace(options.SequenceNumber); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 999999 {
+			return nil, fmt.Errorf("sequence number %q is not a number of up to 6 digits", options.SequenceNumber)
+		}
+		sequence = n
+	}
+
+	var records []packedRecord
+	for consumed := 0; consumed == 0 || consumed < len(data); {
+		opts := *options
+		if consumed > 0 {
+			opts.Blockettes = nil
+			opts.LeapSecond = false
+			if opts.SampleRate > 0 {
+				offset := math.Round(float64(consumed) / opts.SampleRate * float64(time.Second))
+				opts.StartTime = options.StartTime.Add(time.Duration(offset))
+			}
+		}
+		opts.SequenceNumber = fmt.Sprintf("%06d", sequence)
+
+		dataOffset, err := m.getAppendDataOffset(opts.Blockettes)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		dataBytes = append(dataBytes, result...)
-	default:
-		return fmt.Errorf("%d is not a valid encoding format", m.Type)
+		packed, n, err := packDataLimited(data[consumed:], length-dataOffset, m.Type, m.Order)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 && consumed < len(data) {
+			return nil, fmt.Errorf("no sample fits in a %d-byte record", length)
+		}
+
+		records = append(records, packedRecord{data[consumed : consumed+n], packed, opts})
+		if consumed += n; n == 0 {
+			break
+		}
+		sequence = sequence%999999 + 1
+	}
+
+	return records, nil
+}
+
+// getAppendDataOffset returns the data offset of a record holding blockette
+// 1000 followed by blockettes.
+func (m *MiniSeedData) getAppendDataOffset(blockettes []BlocketteSection) (int, error) {
+	blocketteEnd := FIXED_SECTION_LENGTH + 8
+	for _, v := range blockettes {
+		b, err := v.Compose(m.Order)
+		if err != nil {
+			return 0, err
+		}
+		blocketteEnd += len(b)
+	}
+
+	return getDataOffset(blocketteEnd), nil
+}
+
+// appendPacked appends a record holding data, already packed into dataBytes.
+func (m *MiniSeedData) appendPacked(data []int32, dataBytes []byte, options *AppendOptions) error {
+	// Check if sequence number is valid
+	for _, v := range m.Series {
+		if v.FixedSection.SequenceNumber == options.SequenceNumber &&
+			v.FixedSection.ChannelCode == options.ChannelCode {
+			return fmt.Errorf("sequence number %s already exists", options.SequenceNumber)
+		}
+	}
+
+	// Check if data quality is valid
+	dataQuality := options.DataQuality
+	if dataQuality == "" {
+		dataQuality = "D"
+	}
+	if QualityRank(dataQuality) == 0 {
+		return fmt.Errorf("%q is not a valid data quality", dataQuality)
+	}
+
+	// Positive leap seconds are 23:59:60 UTC, mapped onto the next midnight
+	if options.LeapSecond && !inLeapSecond(options.StartTime) {
+		return fmt.Errorf("start time %s cannot lie in a leap second, which ends a UTC day", options.StartTime.UTC().Format(time.RFC3339Nano))
+	}
+
+	// Measure blockettes chained after blockette 1000
+	dataOffset, err := m.getAppendDataOffset(options.Blockettes)
+	if err != nil {
+		return err
 	}
 
 	// Get entire record length
 	recordLength := math.Log2(float64(
-		nextPow2(FIXED_SECTION_LENGTH + BLOCKETTE100X_SECTION_LENGTH + len(dataBytes)),
+		nextPow2(dataOffset + len(dataBytes)),
 	))
 	if recordLength < 8 {
 		recordLength = 8
 	}
+	if options.RecordLength != 0 {
+		if dataOffset+len(dataBytes) > options.RecordLength {
+			return fmt.Errorf("data does not fit in a %d-byte record", options.RecordLength)
+		}
+		recordLength = math.Log2(float64(options.RecordLength))
+	}
 
 	// Set blockette section
 	bs := BlocketteSection{
@@ -82,22 +288,23 @@ func (m *MiniSeedData) Append(data []int32, options *AppendOptions) error {
 
 	// Set fixed section
 	fs := FixedSection{
-		DataQuality:      "D",
+		DataQuality:      dataQuality,
 		SequenceNumber:   options.SequenceNumber,
 		StationCode:      options.StationCode,
 		LocationCode:     options.LocationCode,
 		ChannelCode:      options.ChannelCode,
 		NetworkCode:      options.NetworkCode,
 		StartTime:        options.StartTime,
+		StartBTime:       NewBTime(options.StartTime, options.LeapSecond),
 		SampleFactor:     sampleFactor,
 		SampleMultiplier: SampleMultiplier,
 		SamplesNumber:    int32(len(data)),
-		ActivityFlags:    0,
-		IOClockFlags:     0,
-		DataQualityFlags: 0,
-		BlockettesFollow: 1,
+		ActivityFlags:    options.ActivityFlags,
+		IOClockFlags:     options.IOClockFlags,
+		DataQualityFlags: options.DataQualityFlags,
+		BlockettesFollow: int32(1 + len(options.Blockettes)),
 		TimeCorrection:   0,
-		DataStartOffset:  64,
+		DataStartOffset:  int32(dataOffset),
 		SectionEndOffset: 48,
 	}
 
@@ -115,6 +322,7 @@ func (m *MiniSeedData) Append(data []int32, options *AppendOptions) error {
 		FixedSection:     fs,
 		BlocketteSection: bs,
 		DataSection:      ds,
+		Blockettes:       options.Blockettes,
 	})
 
 	// Updating counters
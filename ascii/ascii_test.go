@@ -0,0 +1,209 @@
+package ascii
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+var testStart = time.Date(2024, 3, 1, 12, 30, 15, 123400000, time.UTC)
+
+// getTestData returns a record of 8 samples at 40 Hz in an encoding.
+func getTestData(t *testing.T, encoding int) *mseedio.MiniSeedData {
+	var m mseedio.MiniSeedData
+	if err := m.Init(encoding, mseedio.MSBFIRST); err != nil {
+		t.Fatal(err)
+	}
+	data := []int32{-100, 0, 7, 2147483, 15, -3, 42, 1}
+	if encoding == mseedio.ASCII {
+		data = []int32{'G', 'P', 'S', ' ', 'o', 'k', '\n', '!'}
+	}
+	if err := m.Append(data, &mseedio.AppendOptions{
+		NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+		SampleRate: 40, StartTime: testStart, SequenceNumber: "000001",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoded as read from a file
+	encoded, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read mseedio.MiniSeedData
+	if err := read.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+		t.Fatal(err)
+	}
+	return &read
+}
+
+func TestWrite(t *testing.T) {
+	m := getTestData(t, mseedio.STEIM2)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, m, TSPAIR); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if want := "TIMESERIES IU_ANMO_00_BHZ_D, 8 samples, 40 sps, 2024-03-01T12:30:15.123400, TSPAIR, INTEGER, Counts"; lines[0] != want {
+		t.Errorf("want header %q, got %q", want, lines[0])
+	}
+	if lines[1] != "2024-03-01T12:30:15.123400  -100" || lines[4] != "2024-03-01T12:30:15.198400  2147483" {
+		t.Errorf("unexpected pairs\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, m, SLIST); err != nil {
+		t.Fatal(err)
+	}
+	want := "TIMESERIES IU_ANMO_00_BHZ_D, 8 samples, 40 sps, 2024-03-01T12:30:15.123400, SLIST, INTEGER, Counts\n" +
+		"-100        0           7           2147483     15          -3        \n" +
+		"42          1         \n"
+	if buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := Write(&buf, getTestData(t, mseedio.ASCII), TSPAIR); err != nil {
+		t.Fatal(err)
+	}
+	if want := "TIMESERIES IU_ANMO_00_BHZ_D, 8 samples, 40 sps, 2024-03-01T12:30:15.123400, SLIST, ASCII, Characters\nGPS ok\n!\n"; buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+
+	if err := Write(&buf, m, "CSV"); err == nil {
+		t.Error("want error for an unknown format")
+	}
+}
+
+func TestRead(t *testing.T) {
+	for _, encoding := range []int{mseedio.INT32, mseedio.FLOAT32, mseedio.FLOAT64, mseedio.ASCII} {
+		for _, format := range []string{TSPAIR, SLIST} {
+			m := getTestData(t, encoding)
+			var buf bytes.Buffer
+			if err := Write(&buf, m, format); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := Read(&buf)
+			if err != nil {
+				t.Fatalf("encoding %d, %s: %v", encoding, format, err)
+			}
+			if len(got.Series) != 1 || got.Samples != 8 {
+				t.Fatalf("encoding %d, %s: unexpected records %+v", encoding, format, got)
+			}
+			s := &got.Series[0]
+			if s.FixedSection.StationCode != "ANMO" || s.FixedSection.SampleRate() != 40 || !s.FixedSection.StartTime.Equal(testStart) {
+				t.Errorf("encoding %d, %s: unexpected fixed section %+v", encoding, format, s.FixedSection)
+			}
+
+			// The same samples once encoded and read again
+			want := m.Traces()[0].Samples
+			encoded, err := got.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var read mseedio.MiniSeedData
+			if err := read.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+				t.Fatal(err)
+			}
+			if samples := read.Traces()[0].Samples; len(samples) != len(want) || samples[len(want)-1] != want[len(want)-1] {
+				t.Errorf("encoding %d, %s: want %v, got %v", encoding, format, want, samples)
+			}
+			if got := s.DataSection.Decoded; len(got) != len(read.Series[0].DataSection.Decoded) {
+				t.Errorf("encoding %d, %s: decoded %v, read %v", encoding, format, got, read.Series[0].DataSection.Decoded)
+			}
+		}
+	}
+}
+
+func TestReadSplit(t *testing.T) {
+	var text strings.Builder
+	text.WriteString("TIMESERIES XX_TEST__HHZ_R, 2500 samples, 100 sps, 2024-03-01T00:00:00.000000, SLIST, FLOAT, Counts\n")
+	for i := 0; i < 2500; i++ {
+		text.WriteString("0.1 ")
+	}
+	text.WriteString("\nTIMESERIES XX_TEST__HHN_R, 1 samples, 100 sps, 2024-03-01T00:00:00.000000, TSPAIR, INTEGER, Counts\n")
+	text.WriteString("2024-03-01T00:00:00.000000  5\n")
+
+	m, err := Read(strings.NewReader(text.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Series) != 6 || m.Samples != 2501 || m.Series[0].BlocketteSection.EncodingFormat != mseedio.FLOAT64 {
+		t.Fatalf("want 5 FLOAT64 records and 1 more, got %d records of %d samples", len(m.Series), m.Samples)
+	}
+	if n := m.Series[0].FixedSection.SamplesNumber; n != 504 || m.Series[0].BlocketteSection.RecordLength != 12 {
+		t.Errorf("want 504 samples in a 4096-byte record, got %d in 2^%d", n, m.Series[0].BlocketteSection.RecordLength)
+	}
+	if start := m.Series[1].FixedSection.StartTime; !start.Equal(time.Date(2024, 3, 1, 0, 0, 5, 40000000, time.UTC)) {
+		t.Errorf("unexpected start of second record %s", start)
+	}
+	if m.Series[5].FixedSection.LocationCode != "" || m.Series[5].FixedSection.DataQuality != "R" {
+		t.Errorf("unexpected fixed section %+v", m.Series[5].FixedSection)
+	}
+
+	traces := m.Traces()
+	if len(traces) != 2 || len(traces[1].Samples) != 2500 {
+		t.Errorf("want 1 and 2500 samples, got %d traces", len(traces))
+	}
+}
+
+func TestReadSampleRates(t *testing.T) {
+	for _, rate := range []string{"40.5", "0.1", "0.3333333333", "1000.25", "100000", "0.00001"} {
+		input := "TIMESERIES IU_ANMO_00_BHZ_D, 2 samples, " + rate + " sps, 2024-03-01T12:30:15, SLIST, INTEGER, Counts\n1 2\n"
+		m, err := Read(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("%s sps: %v", rate, err)
+		}
+
+		// Through 16-bit header fields
+		encoded, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var read mseedio.MiniSeedData
+		if err := read.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := strconv.ParseFloat(rate, 64)
+		if got := read.Series[0].FixedSection.SampleRate(); math.Abs(got-want) > want*1e-9 {
+			f := &read.Series[0].FixedSection
+			t.Errorf("want %v sps, got %v from factor %d and multiplier %d", want, got, f.SampleFactor, f.SampleMultiplier)
+		}
+	}
+
+	input := "TIMESERIES IU_ANMO_00_BHZ_D, 2 samples, 12345.678901 sps, 2024-03-01T12:30:15, SLIST, INTEGER, Counts\n1 2\n"
+	if _, err := Read(strings.NewReader(input)); err == nil {
+		t.Error("want error for a rate not fitting the factor and multiplier")
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	const header = "TIMESERIES IU_ANMO_00_BHZ_D, 2 samples, 40 sps, 2024-03-01T12:30:15.123400, "
+	for name, input := range map[string]string{
+		"not a header": "hello\n",
+		"identifier":   "TIMESERIES IU_ANMO, 2 samples, 40 sps, 2024-03-01T12:30:15, SLIST, INTEGER, Counts\n1 2\n",
+		"time":         "TIMESERIES IU_ANMO_00_BHZ_D, 2 samples, 40 sps, yesterday, SLIST, INTEGER, Counts\n1 2\n",
+		"format":       header + "CSV, INTEGER, Counts\n1 2\n",
+		"type":         header + "SLIST, COMPLEX, Counts\n1 2\n",
+		"missing":      header + "SLIST, INTEGER, Counts\n1\n",
+		"too many":     header + "SLIST, INTEGER, Counts\n1 2 3\n",
+		"integer":      header + "SLIST, INTEGER, Counts\n1 2.5\n",
+		"pair":         header + "TSPAIR, INTEGER, Counts\n2024-03-01T12:30:15.123400 1 2\n",
+		"characters":   "TIMESERIES IU_ANMO_00_LOG_D, 20 samples, 0 sps, 2024-03-01T12:30:15, SLIST, ASCII, Characters\nshort\n",
+		"huge count":   "TIMESERIES IU_ANMO_00_BHZ_D, 900000000000 samples, 40 sps, 2024-03-01T12:30:15, SLIST, INTEGER, Counts\n1 2\n",
+		// Within MAX_SAMPLES, but not to be allocated up front
+		"lying count":      "TIMESERIES IU_ANMO_00_BHZ_D, 2000000000 samples, 40 sps, 2024-03-01T12:30:15, SLIST, INTEGER, Counts\n1 2\n",
+		"lying characters": "TIMESERIES IU_ANMO_00_LOG_D, 2000000000 samples, 0 sps, 2024-03-01T12:30:15, SLIST, ASCII, Characters\nshort\n",
+	} {
+		if _, err := Read(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Package ascii writes miniSEED records as the TSPAIR and SLIST text formats
+// of mseed2ascii, and reads such text back into records.
+//
+//	var ms mseedio.MiniSeedData
+//	if err := ms.Read("record.mseed"); err != nil {
+//		// handle error
+//	}
+//	err := ascii.Write(os.Stdout, &ms, ascii.SLIST)
+//
+// Each record becomes a block opened by a header line giving its source
+// identifier, sample count, rate, start time, format, sample type and units:
+//
+//	TIMESERIES IU_ANMO_00_BHZ_D, 12 samples, 40 sps, 2024-03-01T12:30:15.123400, SLIST, INTEGER, Counts
+//
+// TSPAIR then lists one "time  sample" pair per line, and SLIST the samples
+// in columns of six. Integer encodings are written as INTEGER, FLOAT32 and
+// FLOAT64 as FLOAT, and ASCII records as their text, with no sample times.
+//
+// Read builds big-endian records from the blocks, which MiniSeedData.Encode
+// turns into miniSEED again.
+package ascii
@@ -0,0 +1,335 @@
+package ascii
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	// MAX_RECORD_LENGTH is the length of the longest record built by Read,
+	// longer blocks being split across records.
+	MAX_RECORD_LENGTH = 4096
+	// MAX_SAMPLES is the largest sample count of a block header. Samples are
+	// not allocated from the count, which only bounds how much is read.
+	MAX_SAMPLES = math.MaxInt32
+)
+
+// Read reads the blocks of TSPAIR and SLIST text into big-endian records
+// ready for MiniSeedData.Encode, decoded as MiniSeedData.Read would decode
+// them. INTEGER samples are encoded as INT32, FLOAT samples as FLOAT32 when
+// they all fit, FLOAT64 otherwise, and ASCII text as ASCII.
+func Read(r io.Reader) (*mseedio.MiniSeedData, error) {
+	var (
+		m      = &mseedio.MiniSeedData{Order: mseedio.MSBFIRST}
+		br     = bufio.NewReader(r)
+		number int
+	)
+	for {
+		line, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && line == "" {
+			break
+		} else if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		number++
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		h, err := parseHeader(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		samples, lines, err := h.readSamples(br)
+		if err != nil {
+			return nil, fmt.Errorf("block at line %d: %w", number, err)
+		}
+		number += lines
+		if err := h.appendRecords(m, samples); err != nil {
+			return nil, fmt.Errorf("block at line %d: %w", number, err)
+		}
+	}
+
+	return m, nil
+}
+
+// header is the header line of a block.
+type header struct {
+	fixed      mseedio.FixedSection
+	count      int
+	rate       float64
+	format     string
+	sampleType string
+}
+
+// parseHeader parses a header line such as
+// "TIMESERIES IU_ANMO_00_BHZ_D, 12 samples, 40 sps, 2024-03-01T12:30:15.123400, SLIST, INTEGER, Counts".
+func parseHeader(line string) (*header, error) {
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+	id, found := strings.CutPrefix(fields[0], "TIMESERIES ")
+	if !found || len(fields) < 6 {
+		return nil, fmt.Errorf("not a TIMESERIES header line")
+	}
+
+	codes := strings.Split(id, "_")
+	if len(codes) != 5 {
+		return nil, fmt.Errorf("invalid source identifier %q", id)
+	}
+	h := &header{
+		fixed: mseedio.FixedSection{
+			NetworkCode: codes[0], StationCode: codes[1], LocationCode: codes[2], ChannelCode: codes[3], DataQuality: codes[4],
+		},
+		format:     fields[4],
+		sampleType: fields[5],
+	}
+
+	var err error
+	if h.count, err = strconv.Atoi(strings.TrimSuffix(fields[1], " samples")); err != nil || h.count < 0 || h.count > MAX_SAMPLES {
+		return nil, fmt.Errorf("invalid sample count %q", fields[1])
+	}
+	if h.rate, err = strconv.ParseFloat(strings.TrimSuffix(fields[2], " sps"), 64); err != nil || h.rate < 0 {
+		return nil, fmt.Errorf("invalid sample rate %q", fields[2])
+	}
+	if h.fixed.StartTime, err = parseTime(fields[3]); err != nil {
+		return nil, err
+	}
+	switch {
+	case h.format != TSPAIR && h.format != SLIST:
+		return nil, fmt.Errorf("unknown format %q", h.format)
+	case h.sampleType != INTEGER && h.sampleType != FLOAT && h.sampleType != ASCII:
+		return nil, fmt.Errorf("unknown sample type %q", h.sampleType)
+	}
+
+	return h, nil
+}
+
+// readSamples reads the samples of a block, returning them with the number
+// of lines read.
+func (h *header) readSamples(br *bufio.Reader) ([]any, int, error) {
+	if h.sampleType == ASCII {
+		// Read up to the count rather than allocate it, the header may lie
+		text, err := io.ReadAll(io.LimitReader(br, int64(h.count)))
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(text) < h.count {
+			return nil, 0, fmt.Errorf("%d characters in header, got %d: %w", h.count, len(text), io.ErrUnexpectedEOF)
+		}
+		lines := strings.Count(string(text), "\n")
+		if b, err := br.Peek(1); err == nil && b[0] == '\n' && (h.count == 0 || text[h.count-1] != '\n') {
+			br.ReadByte()
+			lines++
+		}
+		return []any{string(text)}, lines, nil
+	}
+
+	var samples []any
+	lines := 0
+	for len(samples) < h.count {
+		line, err := br.ReadString('\n')
+		if line == "" && err != nil {
+			return nil, lines, fmt.Errorf("%d samples in header, got %d: %w", h.count, len(samples), io.ErrUnexpectedEOF)
+		}
+		lines++
+
+		fields := strings.Fields(line)
+		if h.format == TSPAIR && len(fields) > 0 {
+			if len(fields) != 2 {
+				return nil, lines, fmt.Errorf("invalid time and sample pair %q", strings.TrimSpace(line))
+			}
+			if _, err := parseTime(fields[0]); err != nil {
+				return nil, lines, err
+			}
+			fields = fields[1:]
+		}
+		for _, field := range fields {
+			v, err := h.parseSample(field)
+			if err != nil {
+				return nil, lines, err
+			}
+			samples = append(samples, v)
+		}
+	}
+	if len(samples) != h.count {
+		return nil, lines, fmt.Errorf("%d samples in header, got %d", h.count, len(samples))
+	}
+
+	return samples, lines, nil
+}
+
+// parseSample parses a sample of the type of the block, an int32 or a
+// float64.
+func (h *header) parseSample(s string) (any, error) {
+	if h.sampleType == INTEGER {
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer sample %q", s)
+		}
+		return int32(v), nil
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid float sample %q", s)
+	}
+	return v, nil
+}
+
+// appendRecords appends the samples of a block to m, as many records as they
+// take.
+func (h *header) appendRecords(m *mseedio.MiniSeedData, samples []any) error {
+	encoding, width := mseedio.INT32, 4
+	switch h.sampleType {
+	case ASCII:
+		encoding, width = mseedio.ASCII, 1
+		text := samples[0].(string)
+		samples = samples[:0]
+		for i := range text {
+			samples = append(samples, text[i:i+1])
+		}
+	case FLOAT:
+		encoding = mseedio.FLOAT32
+		for _, v := range samples {
+			if f := v.(float64); float64(float32(f)) != f {
+				encoding, width = mseedio.FLOAT64, 8
+				break
+			}
+		}
+	}
+	factor, multiplier, err := getSampleFactors(h.rate)
+	if err != nil {
+		return err
+	}
+
+	const dataOffset = mseedio.FIXED_SECTION_LENGTH + 16
+	perRecord := (MAX_RECORD_LENGTH - dataOffset) / width
+	for first := 0; first == 0 || first < len(samples); first += perRecord {
+		last := first + perRecord
+		if last > len(samples) {
+			last = len(samples)
+		}
+		chunk := samples[first:last]
+
+		raw := make([]byte, 0, len(chunk)*width)
+		decoded := make([]any, 0, len(chunk))
+		var text string
+		for _, v := range chunk {
+			switch encoding {
+			case mseedio.ASCII:
+				text += v.(string)
+			case mseedio.INT32:
+				raw = binary.BigEndian.AppendUint32(raw, uint32(v.(int32)))
+				decoded = append(decoded, v)
+			case mseedio.FLOAT32:
+				raw = binary.BigEndian.AppendUint32(raw, math.Float32bits(float32(v.(float64))))
+				decoded = append(decoded, float64(float32(v.(float64))))
+			case mseedio.FLOAT64:
+				raw = binary.BigEndian.AppendUint64(raw, math.Float64bits(v.(float64)))
+				decoded = append(decoded, v)
+			}
+		}
+		if encoding == mseedio.ASCII {
+			raw, decoded = []byte(text), []any{text}
+		}
+
+		fixed := h.fixed
+		fixed.SequenceNumber = fmt.Sprintf("%06d", len(m.Series)%999999+1)
+		if first > 0 && h.rate > 0 {
+			fixed.StartTime = fixed.StartTime.Add(time.Duration(math.Round(float64(first) / h.rate * float64(time.Second))))
+		}
+		fixed.SamplesNumber = int32(len(chunk))
+		fixed.SampleFactor, fixed.SampleMultiplier = factor, multiplier
+		fixed.BlockettesFollow = 1
+		fixed.DataStartOffset = dataOffset
+		fixed.SectionEndOffset = mseedio.FIXED_SECTION_LENGTH
+
+		exponent := bits.Len(uint(dataOffset + len(raw) - 1))
+		if exponent < 8 {
+			exponent = 8
+		}
+		m.Series = append(m.Series, mseedio.DataSeries{
+			FixedSection: fixed,
+			BlocketteSection: mseedio.BlocketteSection{
+				BlocketteCode:  1000,
+				EncodingFormat: int32(encoding),
+				BitOrder:       mseedio.MSBFIRST,
+				RecordLength:   int32(exponent),
+			},
+			DataSection: mseedio.DataSection{Decoded: decoded, RawData: raw},
+		})
+
+		if m.Records == 0 {
+			m.Type, m.StartTime = encoding, fixed.StartTime
+		}
+		m.EndTime = fixed.StartTime
+		m.Records++
+		m.Samples += len(chunk)
+	}
+
+	return nil
+}
+
+// getSampleFactors returns the sample rate factor and multiplier of the
+// fixed section for a rate, both 16-bit fields: an integer rate or period,
+// split into two factors beyond 16 bits, or else a fraction of 16-bit terms.
+// Either must be within a part per billion of the rate.
+func getSampleFactors(rate float64) (int32, int32, error) {
+	if rate == 0 {
+		return 0, 0, nil
+	}
+
+	// rate is factor * multiplier, or the period is -factor * -multiplier
+	for _, c := range []struct {
+		value float64
+		sign  int32
+	}{{rate, 1}, {1 / rate, -1}} {
+		rounded := math.Round(c.value)
+		if math.Abs(rounded-c.value) > c.value*1e-9 || rounded < 1 || rounded > math.MaxInt16*math.MaxInt16 {
+			continue
+		}
+		v := int32(rounded)
+		if v <= math.MaxInt16 {
+			return c.sign * v, 1, nil
+		}
+		for m := int32(2); m <= math.MaxInt16; m++ {
+			if v%m == 0 && v/m <= math.MaxInt16 {
+				return c.sign * v / m, c.sign * m, nil
+			}
+		}
+	}
+
+	// rate is factor / -multiplier
+	for q := 2; q <= math.MaxInt16; q++ {
+		p := math.Round(rate * float64(q))
+		if p > math.MaxInt16 {
+			break
+		}
+		if p >= 1 && math.Abs(p/float64(q)-rate) <= rate*1e-9 {
+			return int32(p), int32(-q), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("sample rate %v Hz does not fit the 16-bit factor and multiplier", rate)
+}
+
+// parseTime parses the time of a header line or a pair, with or without
+// fractional seconds.
+func parseTime(s string) (time.Time, error) {
+	t, err := time.Parse("2006-01-02T15:04:05.999999999", strings.TrimSuffix(s, "Z"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,154 @@
+package ascii
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	TSPAIR = "TSPAIR" // One "time  sample" pair per line
+	SLIST  = "SLIST"  // Samples in columns after the header line
+
+	SLIST_COLUMNS = 6
+	TIME_LAYOUT   = "2006-01-02T15:04:05.000000"
+)
+
+// Sample types of the header line
+const (
+	INTEGER = "INTEGER"
+	FLOAT   = "FLOAT"
+	ASCII   = "ASCII"
+)
+
+// Write writes every record of m as a block of a format, TSPAIR or SLIST,
+// the way mseed2ascii does.
+func Write(w io.Writer, m *mseedio.MiniSeedData, format string) error {
+	bw := bufio.NewWriter(w)
+	for i := range m.Series {
+		if err := writeSeries(bw, &m.Series[i], format); err != nil {
+			return fmt.Errorf("record %d: %w", i, err)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteSeries writes a record as a block of a format, TSPAIR or SLIST. ASCII
+// records have no sample times, their text following the header line in
+// either format.
+func WriteSeries(w io.Writer, s *mseedio.DataSeries, format string) error {
+	bw := bufio.NewWriter(w)
+	if err := writeSeries(bw, s, format); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeSeries writes the header line and samples of a record.
+func writeSeries(w *bufio.Writer, s *mseedio.DataSeries, format string) error {
+	if format != TSPAIR && format != SLIST {
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	// Decoded here if read lazily, leaving s untouched
+	ds := s.DataSection
+	if err := ds.Decode(); err != nil {
+		return err
+	}
+	samples := getSamples(ds.Decoded)
+
+	f := &s.FixedSection
+	rate := f.SampleRate()
+	encoding := int(s.BlocketteSection.EncodingFormat)
+	sampleType, units, layout := INTEGER, "Counts", "%d"
+	switch encoding {
+	case mseedio.ASCII:
+		sampleType, units, format = ASCII, "Characters", SLIST
+	case mseedio.FLOAT32:
+		sampleType, layout = FLOAT, "%.8g"
+	case mseedio.FLOAT64:
+		sampleType, layout = FLOAT, "%.10g"
+	}
+
+	count := len(samples)
+	if sampleType == ASCII {
+		count = 0
+		for _, v := range samples {
+			count += len(fmt.Sprint(v))
+		}
+	}
+	fmt.Fprintf(w, "TIMESERIES %s, %d samples, %.10g sps, %s, %s, %s, %s\n",
+		getSourceID(f), count, rate, f.StartTime.UTC().Format(TIME_LAYOUT), format, sampleType, units)
+
+	switch {
+	case sampleType == ASCII:
+		var text string
+		for _, v := range samples {
+			text += fmt.Sprint(v)
+		}
+		w.WriteString(text)
+		if !strings.HasSuffix(text, "\n") {
+			w.WriteByte('\n')
+		}
+
+	case format == TSPAIR:
+		if rate <= 0 && len(samples) > 1 {
+			return fmt.Errorf("no sample time without a sample rate")
+		}
+		for i, v := range samples {
+			t := f.StartTime
+			if i > 0 {
+				t = t.Add(time.Duration(float64(i) * float64(time.Second) / rate).Round(time.Microsecond))
+			}
+			fmt.Fprintf(w, "%s  "+layout+"\n", t.UTC().Format(TIME_LAYOUT), v)
+		}
+
+	default:
+		for i, v := range samples {
+			fmt.Fprintf(w, "%-10s", fmt.Sprintf(layout, v))
+			if i%SLIST_COLUMNS == SLIST_COLUMNS-1 || i == len(samples)-1 {
+				w.WriteByte('\n')
+			} else {
+				w.WriteString("  ")
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSourceID returns the NET_STA_LOC_CHAN_QUAL identifier of a record.
+func getSourceID(f *mseedio.FixedSection) string {
+	return strings.Join([]string{
+		strings.TrimSpace(f.NetworkCode),
+		strings.TrimSpace(f.StationCode),
+		strings.TrimSpace(f.LocationCode),
+		strings.TrimSpace(f.ChannelCode),
+		strings.TrimSpace(f.DataQuality),
+	}, "_")
+}
+
+// getSamples flattens decoded samples, records built by Append holding them
+// as a single []int32 element.
+func getSamples(decoded []any) []any {
+	var samples []any
+	for _, v := range decoded {
+		switch s := v.(type) {
+		case []int32:
+			for _, sample := range s {
+				samples = append(samples, sample)
+			}
+		case []float64:
+			for _, sample := range s {
+				samples = append(samples, sample)
+			}
+		default:
+			samples = append(samples, v)
+		}
+	}
+	return samples
+}
@@ -52,19 +52,22 @@ func assembleInt(data []byte, n, bitOrder int) int32 {
 
 // assembleTime assembles a time.Time from a 10-byte BTIME structure.
 func assembleTime(data []byte, bitOrder int) time.Time {
+	return assembleBTime(data, bitOrder).Time()
+}
+
+// assembleBTime assembles a BTime from a 10-byte BTIME structure, keeping a
+// leap second (second 60) as is.
+func assembleBTime(data []byte, bitOrder int) BTime {
 	order := byteOrder(bitOrder)
-	var (
-		year = int(order.Uint16(data[0:2]))
-		days = int(order.Uint16(data[2:4]))
-		hour = int(data[4])
-		min  = int(data[5])
-		sec  = int(data[6])
+	return BTime{
+		Year:   int(order.Uint16(data[0:2])),
+		Day:    int(order.Uint16(data[2:4])),
+		Hour:   int(data[4]),
+		Minute: int(data[5]),
+		Second: int(data[6]),
 		// 0.0001-second ticks stored little-/big-endian across bytes 7..9.
-		ticks = int(assembleUint(data[7:10], 3, bitOrder))
-	)
-
-	md := getMonthByDays(year, days)
-	return time.Date(year, md.Month(), md.Day(), hour, min, sec, ticks*100000, time.UTC)
+		Ticks: int(assembleUint(data[7:10], 3, bitOrder)),
+	}
 }
 
 // assembleFloat32 assembles a float32 from 4 bytes.
@@ -126,15 +129,19 @@ func disassembleString(data string, length int, padding byte) []byte {
 
 // disassembleTime disassembles a time.Time into a 10-byte BTIME structure.
 func disassembleTime(t time.Time, bitOrder int) []byte {
+	return disassembleBTime(NewBTime(t, false), bitOrder)
+}
+
+// disassembleBTime disassembles a BTime into a 10-byte BTIME structure.
+func disassembleBTime(b BTime, bitOrder int) []byte {
 	order := byteOrder(bitOrder)
-	ticks := t.Nanosecond() / 100000 // 0.0001-second units
 
 	out := make([]byte, 10)
-	order.PutUint16(out[0:2], uint16(t.Year()))
-	order.PutUint16(out[2:4], uint16(getDaysByDate(t)))
-	out[4] = byte(t.Hour())
-	out[5] = byte(t.Minute())
-	out[6] = byte(t.Second())
-	copy(out[7:10], disassembleInt(int32(ticks), 3, bitOrder))
+	order.PutUint16(out[0:2], uint16(b.Year))
+	order.PutUint16(out[2:4], uint16(b.Day))
+	out[4] = byte(b.Hour)
+	out[5] = byte(b.Minute)
+	out[6] = byte(b.Second)
+	copy(out[7:10], disassembleInt(int32(b.Ticks), 3, bitOrder))
 	return out
 }
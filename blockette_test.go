@@ -0,0 +1,169 @@
+package mseedio
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestBlockette500And2000RoundTrip chains a timing and an opaque blockette after
+// blockette 1000 and verifies both survive Encode -> ReadFromReader.
+func TestBlockette500And2000RoundTrip(t *testing.T) {
+	timing := BlocketteSection{
+		BlocketteCode:    500,
+		VCOCorrection:    51.25,
+		ExceptionTime:    time.Date(2016, 12, 31, 23, 59, 59, 123400000, time.UTC),
+		Microseconds:     -12,
+		ReceptionQuality: 100,
+		ExceptionCount:   3,
+		ExceptionType:    "VALID",
+		ClockModel:       "Q330 GPS",
+		ClockStatus:      "Locked, 9 satellites",
+	}
+	opaque := BlocketteSection{
+		BlocketteCode: 2000,
+		RecordNumber:  7,
+		OpaqueOrder:   MSBFIRST,
+		OpaqueFlags:   0x01,
+		OpaqueTags:    []string{"SOH", "application/json"},
+		OpaqueData:    []byte(`{"temperature":23.5,"battery":12.7}`),
+	}
+
+	samples := make([]int32, 200)
+	for i := range samples {
+		samples[i] = int32(i*i) - 5000
+	}
+
+	for _, data := range [][]int32{samples, nil} {
+		var m MiniSeedData
+		if err := m.Init(STEIM2, MSBFIRST); err != nil {
+			t.Fatal(err)
+		}
+		err := m.Append(data, &AppendOptions{
+			SampleRate:     100,
+			StartTime:      time.Date(2016, 12, 31, 23, 59, 0, 0, time.UTC),
+			SequenceNumber: "000001",
+			StationCode:    "AAAAA",
+			LocationCode:   "BB",
+			ChannelCode:    "EHZ",
+			NetworkCode:    "CC",
+			Blockettes:     []BlocketteSection{timing, opaque},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dataBytes, err := m.Encode(OVERWRITE, MSBFIRST)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got MiniSeedData
+		if err := got.ReadFromReader(bytes.NewReader(dataBytes)); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Series) != 1 {
+			t.Fatalf("want 1 series, got %d", len(got.Series))
+		}
+		s := got.Series[0]
+		if s.FixedSection.BlockettesFollow != 3 || s.FixedSection.DataStartOffset%64 != 0 {
+			t.Fatalf("unexpected layout: follow=%d offset=%d",
+				s.FixedSection.BlockettesFollow, s.FixedSection.DataStartOffset)
+		}
+		if len(s.Blockettes) != 2 {
+			t.Fatalf("want 2 chained blockettes, got %d", len(s.Blockettes))
+		}
+		if len(s.DataSection.Decoded) != len(data) {
+			t.Fatalf("want %d samples, got %d", len(data), len(s.DataSection.Decoded))
+		}
+
+		b500 := s.Blockettes[0]
+		b500.NextBlockette, b500.ReaderOffset = 0, SectionOffset{}
+		if !reflect.DeepEqual(b500, timing) {
+			t.Errorf("blockette 500 mismatch:\nwant %+v\ngot  %+v", timing, b500)
+		}
+
+		b2000 := s.Blockettes[1]
+		if b2000.NextBlockette != 0 || b2000.RecordNumber != 7 || b2000.OpaqueFlags != 1 ||
+			!reflect.DeepEqual(b2000.OpaqueTags, opaque.OpaqueTags) ||
+			!bytes.Equal(b2000.OpaqueData, opaque.OpaqueData) {
+			t.Errorf("blockette 2000 mismatch: %+v", b2000)
+		}
+	}
+}
+
+// TestPackSteimEmpty verifies that records without samples, such as those
+// carrying only opaque data, pack to an empty data section.
+func TestPackSteimEmpty(t *testing.T) {
+	for _, pack := range []func([]int32, int) ([]byte, error){packSteim1, packSteim2} {
+		data, err := pack(nil, MSBFIRST)
+		if err != nil || len(data) != 0 {
+			t.Errorf("want no data, got %d bytes, %v", len(data), err)
+		}
+	}
+}
+
+// TestCustomBlockettes verifies that unregistered blockettes survive a round
+// trip as raw payloads and that registered ones go through their codec.
+func TestCustomBlockettes(t *testing.T) {
+	const vendorCode = 60001
+	payload := []byte("vendor-specific state block")
+
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	err := m.Append([]int32{1, 2, 3}, &AppendOptions{
+		SampleRate: 1, StartTime: time.Unix(0, 0).UTC(), SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		Blockettes: []BlocketteSection{
+			{BlocketteCode: vendorCode, Payload: payload},
+			{BlocketteCode: 2000, OpaqueData: []byte("x")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataBytes, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw MiniSeedData
+	if err := raw.ReadFromReader(bytes.NewReader(dataBytes)); err != nil {
+		t.Fatal(err)
+	}
+	if got := raw.Series[0].Blockettes; len(got) != 2 || !bytes.Equal(got[0].Payload, payload) {
+		t.Fatalf("raw blockette not preserved: %+v", got)
+	}
+	reencoded, err := raw.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reencoded, dataBytes) {
+		t.Fatal("re-encoded record differs from the original")
+	}
+
+	err = RegisterBlockette(vendorCode,
+		func(b *BlocketteSection, body []byte, bitOrder int) error {
+			b.Value = string(body[:len(payload)])
+			return nil
+		},
+		func(b *BlocketteSection, bitOrder int) ([]byte, error) {
+			return []byte(b.Value.(string)), nil
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded MiniSeedData
+	if err := decoded.ReadFromReader(bytes.NewReader(dataBytes)); err != nil {
+		t.Fatal(err)
+	}
+	if v := decoded.Series[0].Blockettes[0].Value; v != string(payload) {
+		t.Fatalf("custom decoder not used, got %v", v)
+	}
+
+	if err := RegisterBlockette(0, nil, nil); err == nil {
+		t.Fatal("expected an error for an invalid registration")
+	}
+}
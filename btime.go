@@ -0,0 +1,57 @@
+package mseedio
+
+import "time"
+
+// BTime is the 10-byte SEED BTIME structure. Unlike time.Time it can hold
+// second 60, the positive leap second inserted at the end of a UTC day.
+type BTime struct {
+	Year   int
+	Day    int // Day of year, 1-366
+	Hour   int
+	Minute int
+	Second int // 0-60
+	Ticks  int // 0.0001-second units
+}
+
+// NewBTime converts t into a BTIME. If leap is set and t falls within the
+// first second of a UTC day, it is expressed as second 60 of the previous
+// day, i.e. as an instant inside a positive leap second. Leap seconds only
+// end a UTC day, so leap is ignored at any other time.
+func NewBTime(t time.Time, leap bool) BTime {
+	t = t.UTC()
+	second := t.Second()
+	if leap && inLeapSecond(t) {
+		t = t.Add(-time.Second)
+		second = 60
+	}
+
+	return BTime{
+		Year:   t.Year(),
+		Day:    getDaysByDate(t),
+		Hour:   t.Hour(),
+		Minute: t.Minute(),
+		Second: second,
+		Ticks:  t.Nanosecond() / 100000,
+	}
+}
+
+// Time converts b into a time.Time. time.Time has no leap seconds, so second
+// 60 is mapped onto second 0 of the following minute, the same instant POSIX
+// time assigns to it.
+func (b BTime) Time() time.Time {
+	md := getMonthByDays(b.Year, b.Day)
+	return time.Date(b.Year, md.Month(), md.Day(), b.Hour, b.Minute, b.Second, b.Ticks*100000, time.UTC)
+}
+
+// inLeapSecond reports whether t, as time.Time maps a positive leap second
+// onto the second after it, may lie inside one: within the first second of a
+// UTC day.
+func inLeapSecond(t time.Time) bool {
+	t = t.UTC()
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+}
+
+// IsLeapSecond reports whether b falls inside a positive leap second.
+func (b BTime) IsLeapSecond() bool {
+	return b.Second == 60
+}
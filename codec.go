@@ -1,41 +1,95 @@
 package mseedio
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // byteReader is a sequential cursor over a byte buffer that decodes the
-// fixed-width fields of a miniSEED record header.
+// fixed-width fields of a miniSEED record header. Reading past the end of the
+// buffer yields zero values and records an error in err, so that hostile
+// headers cannot cause a panic.
 type byteReader struct {
 	buf   []byte
 	pos   int
 	order int
+	err   error
 }
 
 // remaining reports how many bytes are still unread.
-func (r *byteReader) remaining() int { return len(r.buf) - r.pos }
+func (r *byteReader) remaining() int {
+	if r.pos > len(r.buf) {
+		return 0
+	}
+	return len(r.buf) - r.pos
+}
+
+// next returns the next n bytes, or nil once the buffer runs out.
+func (r *byteReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > r.remaining() {
+		r.err = fmt.Errorf("field of %d bytes at offset %d exceeds %d-byte buffer", n, r.pos, len(r.buf))
+		return nil
+	}
+
+	b := r.buf[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
 
 // string reads n bytes as a string.
 func (r *byteReader) string(n int) string {
-	s := assembleString(r.buf[r.pos : r.pos+n])
-	r.pos += n
-	return s
+	return assembleString(r.next(n))
 }
 
 // int reads a signed integer from the next n bytes.
 func (r *byteReader) int(n int) int32 {
-	v := assembleInt(r.buf[r.pos:r.pos+n], n, r.order)
-	r.pos += n
-	return v
+	b := r.next(n)
+	if b == nil {
+		return 0
+	}
+	return assembleInt(b, n, r.order)
+}
+
+// text reads n bytes as a string, dropping the NUL or space padding that
+// fills the fixed-width text fields of blockettes such as 500.
+func (r *byteReader) text(n int) string {
+	return strings.TrimRight(r.string(n), "\x00 ")
+}
+
+// float32 reads a 4-byte IEEE-754 value.
+func (r *byteReader) float32() float32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return assembleFloat32(b, r.order)
+}
+
+// bytes reads the next n bytes without copying them.
+func (r *byteReader) bytes(n int) []byte {
+	return r.next(n)
 }
 
 // time reads a 10-byte BTIME value.
 func (r *byteReader) time() time.Time {
-	t := assembleTime(r.buf[r.pos:r.pos+10], r.order)
-	r.pos += 10
-	return t
+	return r.btime().Time()
+}
+
+// btime reads a 10-byte BTIME value, keeping a leap second as is.
+func (r *byteReader) btime() BTime {
+	b := r.next(10)
+	if b == nil {
+		return BTime{}
+	}
+	return assembleBTime(b, r.order)
 }
 
 // skip advances the cursor past n bytes (e.g. reserved fields).
-func (r *byteReader) skip(n int) { r.pos += n }
+func (r *byteReader) skip(n int) { r.next(n) }
 
 // byteWriter is a sequential cursor that encodes fixed-width header fields.
 type byteWriter struct {
@@ -53,11 +107,26 @@ func (w *byteWriter) int(v int32, n int) {
 	w.buf = append(w.buf, disassembleInt(v, n, w.order)...)
 }
 
+// float32 writes v as a 4-byte IEEE-754 value.
+func (w *byteWriter) float32(v float32) {
+	w.buf = append(w.buf, disassembleFloat(v, w.order)...)
+}
+
+// bytes writes b verbatim.
+func (w *byteWriter) bytes(b []byte) {
+	w.buf = append(w.buf, b...)
+}
+
 // time writes t as a 10-byte BTIME value.
 func (w *byteWriter) time(t time.Time) {
 	w.buf = append(w.buf, disassembleTime(t, w.order)...)
 }
 
+// btime writes b as a 10-byte BTIME value.
+func (w *byteWriter) btime(b BTime) {
+	w.buf = append(w.buf, disassembleBTime(b, w.order)...)
+}
+
 // pad writes n bytes of value b (e.g. reserved fields).
 func (w *byteWriter) pad(n int, b byte) {
 	for i := 0; i < n; i++ {
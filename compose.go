@@ -1,8 +1,14 @@
 package mseedio
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Compose serializes the fixed section into its 48-byte on-disk form. String
 // fields are space-padded and the reserved byte is written as a space, matching
-// the SEED fixed-header convention.
+// the SEED fixed-header convention. StartBTime is written in place of
+// StartTime when both denote the same instant, so a leap second survives.
 func (f *FixedSection) Compose(bitOrder int) ([]byte, error) {
 	w := &byteWriter{order: bitOrder}
 	w.string(f.SequenceNumber, 6, ' ')
@@ -12,13 +18,17 @@ func (f *FixedSection) Compose(bitOrder int) ([]byte, error) {
 	w.string(f.LocationCode, 2, ' ')
 	w.string(f.ChannelCode, 3, ' ')
 	w.string(f.NetworkCode, 2, ' ')
-	w.time(f.StartTime)
+	if f.StartBTime != (BTime{}) && f.StartBTime.Time().Equal(f.StartTime) {
+		w.btime(f.StartBTime)
+	} else {
+		w.time(f.StartTime)
+	}
 	w.int(f.SamplesNumber, 2)
 	w.int(f.SampleFactor, 2)
 	w.int(f.SampleMultiplier, 2)
-	w.int(f.ActivityFlags, 1)
-	w.int(f.IOClockFlags, 1)
-	w.int(f.DataQualityFlags, 1)
+	w.int(int32(f.ActivityFlags), 1)
+	w.int(int32(f.IOClockFlags), 1)
+	w.int(int32(f.DataQualityFlags), 1)
 	w.int(f.BlockettesFollow, 1)
 	w.int(f.TimeCorrection, 4)
 	w.int(f.DataStartOffset, 2)
@@ -26,16 +36,97 @@ func (f *FixedSection) Compose(bitOrder int) ([]byte, error) {
 	return w.buf, nil
 }
 
-// Compose serializes a blockette 1000 (Data Only SEED) into the fixed
-// BLOCKETTE100X_SECTION_LENGTH-byte area, zero-padding the reserved tail.
+// Compose serializes a blockette: its type and next-blockette fields followed
+// by the body produced by the encoder registered for the type, or by Payload
+// verbatim for unregistered types. Blockette 1000 (Data Only SEED) fills the
+// fixed BLOCKETTE100X_SECTION_LENGTH-byte area, zero-padding the reserved
+// tail; other blockettes are returned at their exact length so they can be
+// chained after it.
 func (b *BlocketteSection) Compose(bitOrder int) ([]byte, error) {
 	w := &byteWriter{order: bitOrder}
 	w.int(b.BlocketteCode, 2)
 	w.int(b.NextBlockette, 2)
+
+	codec, ok := getBlocketteCodec(b.BlocketteCode)
+	switch {
+	case ok:
+		body, err := codec.encoder(b, bitOrder)
+		if err != nil {
+			return nil, err
+		}
+		w.bytes(body)
+	case b.Payload != nil:
+		w.bytes(b.Payload)
+	default:
+		return nil, fmt.Errorf("blockette type %d is not supported for writing", b.BlocketteCode)
+	}
+
+	return w.buf, nil
+}
+
+// composeBlockette1000 encodes the body of a blockette 1000 (Data Only SEED),
+// padded so the whole blockette fills BLOCKETTE100X_SECTION_LENGTH bytes.
+func composeBlockette1000(b *BlocketteSection, bitOrder int) ([]byte, error) {
+	w := &byteWriter{order: bitOrder}
 	w.int(b.EncodingFormat, 1)
 	w.int(b.BitOrder, 1)
 	w.int(b.RecordLength, 1)
 	w.pad(1, 0) // reserved
-	w.pad(BLOCKETTE100X_SECTION_LENGTH-len(w.buf), 0)
+	w.pad(BLOCKETTE100X_SECTION_LENGTH-4-len(w.buf), 0)
+	return w.buf, nil
+}
+
+// composeBlockette1001 encodes the body of a blockette 1001 (Data Extension).
+func composeBlockette1001(b *BlocketteSection, bitOrder int) ([]byte, error) {
+	w := &byteWriter{order: bitOrder}
+	w.int(b.TimingQuality, 1)
+	w.int(b.Microseconds, 1)
+	w.pad(1, 0) // reserved
+	w.int(b.FrameCount, 1)
+	return w.buf, nil
+}
+
+// composeBlockette500 encodes the body of a blockette 500 (Timing).
+func composeBlockette500(b *BlocketteSection, bitOrder int) ([]byte, error) {
+	w := &byteWriter{order: bitOrder}
+	w.float32(b.VCOCorrection)
+	w.time(b.ExceptionTime)
+	w.int(b.Microseconds, 1)
+	w.int(b.ReceptionQuality, 1)
+	w.int(b.ExceptionCount, 4)
+	w.string(b.ExceptionType, 16, 0)
+	w.string(b.ClockModel, 32, 0)
+	w.string(b.ClockStatus, 128, 0)
+	return w.buf, nil
+}
+
+// composeBlockette2000 encodes the body of a blockette 2000 (Opaque Data).
+func composeBlockette2000(b *BlocketteSection, bitOrder int) ([]byte, error) {
+	var header string
+	for _, tag := range b.OpaqueTags {
+		if strings.Contains(tag, "~") {
+			return nil, fmt.Errorf("opaque header tag %q contains '~'", tag)
+		}
+		header += tag + "~"
+	}
+	if len(b.OpaqueTags) > 255 {
+		return nil, fmt.Errorf("blockette 2000 supports at most 255 header tags")
+	}
+
+	dataOffset := BLOCKETTE2000_HEADER_LENGTH + len(header)
+	length := dataOffset + len(b.OpaqueData)
+	if length > 0xffff {
+		return nil, fmt.Errorf("blockette 2000 length %d exceeds 65535 bytes", length)
+	}
+
+	w := &byteWriter{order: bitOrder}
+	w.int(int32(length), 2)
+	w.int(int32(dataOffset), 2)
+	w.int(b.RecordNumber, 4)
+	w.int(b.OpaqueOrder, 1)
+	w.int(b.OpaqueFlags, 1)
+	w.int(int32(len(b.OpaqueTags)), 1)
+	w.bytes([]byte(header))
+	w.bytes(b.OpaqueData)
 	return w.buf, nil
 }
@@ -0,0 +1,98 @@
+package mseedio
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestConcurrentRead checks that decoding on several workers keeps records in
+// order and gives the same result as a sequential read.
+func TestConcurrentRead(t *testing.T) {
+	files, _ := filepath.Glob("example/reader/testdata/*.mseed")
+	for _, f := range files {
+		var want, got MiniSeedData
+		if err := want.Read(f); err != nil {
+			t.Fatal(err)
+		}
+		if err := got.Read(f, WithConcurrency(4)); err != nil {
+			t.Fatal(err)
+		}
+		if dumpRecord(&want) != dumpRecord(&got) {
+			t.Errorf("%s: concurrent read disagrees with sequential read", f)
+		}
+	}
+}
+
+// TestAppendBatch checks that packing records concurrently encodes the same
+// bytes as appending them one by one.
+func TestAppendBatch(t *testing.T) {
+	var (
+		data    [][]int32
+		options []*AppendOptions
+	)
+	for i := 0; i < 64; i++ {
+		samples := make([]int32, 300)
+		for j := range samples {
+			samples[j] = int32((i*300 + j) % 977 * (j%7 - 3))
+		}
+		data = append(data, samples)
+		options = append(options, &AppendOptions{
+			SampleRate: 100, StartTime: time.Unix(int64(i*3), 0).UTC(),
+			SequenceNumber: fmt.Sprintf("%06d", i+1),
+			StationCode:    "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		})
+	}
+
+	var sequential, batch MiniSeedData
+	_ = sequential.Init(STEIM2, MSBFIRST)
+	_ = batch.Init(STEIM2, MSBFIRST)
+	for i := range data {
+		if err := sequential.Append(data[i], options[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := batch.AppendBatch(data, options, 8); err != nil {
+		t.Fatal(err)
+	}
+
+	want, _ := sequential.Encode(OVERWRITE, MSBFIRST)
+	got, err := batch.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Fatal("batch encoding differs from sequential encoding")
+	}
+	if err := batch.AppendBatch(data[:1], options[:1], 2); err == nil {
+		t.Fatal("expected a duplicate sequence number error")
+	}
+
+	// Runs split into fixed-length records, sequence numbers counting on
+	var splitSequential, splitBatch MiniSeedData
+	_ = splitSequential.Init(STEIM2, MSBFIRST)
+	_ = splitBatch.Init(STEIM2, MSBFIRST)
+	for i := range options {
+		split := *options[i]
+		split.RecordLength, split.SequenceNumber = 256, fmt.Sprintf("%06d", i*10+1)
+		options[i] = &split
+		if err := splitSequential.Append(data[i], options[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := splitBatch.AppendBatch(data, options, 8); err != nil {
+		t.Fatal(err)
+	}
+	if splitBatch.Records <= len(data) || splitBatch.Records != splitSequential.Records {
+		t.Fatalf("want as many split records as appended, got %d and %d", splitBatch.Records, splitSequential.Records)
+	}
+	want, _ = splitSequential.Encode(OVERWRITE, MSBFIRST)
+	if got, err = splitBatch.Encode(OVERWRITE, MSBFIRST); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Fatal("batch encoding of split records differs from sequential encoding")
+	}
+}
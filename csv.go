@@ -0,0 +1,50 @@
+package mseedio
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// WriteCSV writes the samples of traces as CSV, one row per sample under a
+// header row: time, network, station, location, channel and sample. Times
+// are ISO 8601, see JSON_TIME_LAYOUT. ASCII traces, having no sample times,
+// are left out.
+func WriteCSV(w io.Writer, traces []Trace) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"time", "network", "station", "location", "channel", "sample"}); err != nil {
+		return err
+	}
+
+	for i := range traces {
+		t := &traces[i]
+		period := t.period()
+		for j, v := range t.Samples {
+			var sample string
+			switch s := v.(type) {
+			case int32:
+				sample = strconv.FormatInt(int64(s), 10)
+			case float32:
+				sample = strconv.FormatFloat(float64(s), 'g', -1, 32)
+			case float64:
+				sample = strconv.FormatFloat(s, 'g', -1, 64)
+			case string:
+				continue
+			default:
+				return fmt.Errorf("trace %s: unsupported sample type %T", t.key(), v)
+			}
+
+			at := t.StartTime.Add(time.Duration(j) * period)
+			if err := cw.Write([]string{
+				at.UTC().Format(JSON_TIME_LAYOUT), t.NetworkCode, t.StationCode, t.LocationCode, t.ChannelCode, sample,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,36 @@
+package mseedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriteCSV(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 30, 15, 123400000, time.UTC)
+	traces := []Trace{
+		{NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ", SampleRate: 40, StartTime: start,
+			Samples: []any{int32(-3), int32(7)}},
+		{NetworkCode: "IU", StationCode: "ANMO", ChannelCode: "LOG", StartTime: start, Samples: []any{"GPS ok"}},
+		{NetworkCode: "IU", StationCode: "ANMO", LocationCode: "10", ChannelCode: "LHZ", SampleRate: 1, StartTime: start,
+			Samples: []any{0.5, float32(1.25)}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, traces); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,network,station,location,channel,sample\n" +
+		"2024-03-01T12:30:15.123400Z,IU,ANMO,00,BHZ,-3\n" +
+		"2024-03-01T12:30:15.148400Z,IU,ANMO,00,BHZ,7\n" +
+		"2024-03-01T12:30:15.123400Z,IU,ANMO,10,LHZ,0.5\n" +
+		"2024-03-01T12:30:16.123400Z,IU,ANMO,10,LHZ,1.25\n"
+	if buf.String() != want {
+		t.Errorf("want\n%s\ngot\n%s", want, buf.String())
+	}
+
+	traces[0].Samples = append(traces[0].Samples, []int32{1})
+	if err := WriteCSV(&buf, traces); err == nil {
+		t.Error("want error for an unsupported sample type")
+	}
+}
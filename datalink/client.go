@@ -0,0 +1,404 @@
+package datalink
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// Client is a connection to a DataLink server such as ringserver.
+type Client struct {
+	ServerID     string   // ID reply of the server, e.g. "DataLink 2020.075"
+	Capabilities []string // Flags after "::" in the ID reply, e.g. "DLPROTO:1.0", "PACKETSIZE:512", "WRITE"
+
+	conn      net.Conn
+	reader    *bufio.Reader
+	opts      *clientOptions
+	streaming bool
+	lastRead  time.Time
+}
+
+// Dial connects to a DataLink server at address (host:port) and exchanges
+// IDs, see NewClient.
+func Dial(ctx context.Context, address string, options ...Option) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(conn, options...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewClient sends the client ID over an established connection and records
+// the ID and capabilities sent back by the server.
+func NewClient(conn net.Conn, options ...Option) (*Client, error) {
+	c := &Client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		opts:   getOptions(options),
+	}
+
+	if err := c.write("ID "+c.opts.clientID, nil); err != nil {
+		return nil, err
+	}
+	f, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if f.kind() != "ID" {
+		return nil, fmt.Errorf("expected ID reply, got %q", strings.Join(f.fields, " "))
+	}
+
+	id, capabilities, _ := strings.Cut(strings.Join(f.fields[1:], " "), "::")
+	c.ServerID = strings.TrimSpace(id)
+	c.Capabilities = strings.Fields(capabilities)
+	return c, nil
+}
+
+// Capability returns the value of a capability flag such as PACKETSIZE, and
+// whether the server announced it at all.
+func (c *Client) Capability(name string) (string, bool) {
+	for _, flag := range c.Capabilities {
+		key, value, _ := strings.Cut(flag, ":")
+		if key == name {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// WriteRecord sends one miniSEED record under its NET_STA_LOC_CHA/MSEED
+// stream ID with the times of its first and last samples. With ack, it waits
+// for the server and returns the packet ID assigned; otherwise it returns 0.
+func (c *Client) WriteRecord(record []byte, ack bool) (int64, error) {
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(record), mseedio.WithLazyDecode()); err != nil {
+		return 0, err
+	}
+	if len(m.Series) != 1 {
+		return 0, fmt.Errorf("expected one record, got %d", len(m.Series))
+	}
+
+	return c.writeRecord(record, &m.Series[0], ack)
+}
+
+// Write sends the records in p, as returned by MiniSeedData.Encode, one
+// WRITE each, waiting for every acknowledgement. Each record is cut from p
+// at the length given by its own blockette 1000. It returns the number of
+// bytes of the records acknowledged before an error.
+func (c *Client) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		length, err := getRecordLength(p[n:])
+		if err != nil {
+			return n, fmt.Errorf("record at byte %d: %w", n, err)
+		}
+		if n+length > len(p) {
+			return n, fmt.Errorf("record at byte %d overruns the buffer", n)
+		}
+		if _, err := c.WriteRecord(p[n:n+length], true); err != nil {
+			return n, fmt.Errorf("record at byte %d: %w", n, err)
+		}
+		n += length
+	}
+
+	return n, nil
+}
+
+// writeRecord sends WRITE for a parsed record.
+func (c *Client) writeRecord(record []byte, s *mseedio.DataSeries, ack bool) (int64, error) {
+	if c.streaming {
+		return 0, fmt.Errorf("cannot write while streaming")
+	}
+
+	flag := "N"
+	if ack {
+		flag = "A"
+	}
+	header := fmt.Sprintf("WRITE %s %d %d %s %d",
+		getStreamID(&s.FixedSection), getHPTime(s.FixedSection.StartTime), getHPTime(s.EndTime()), flag, len(record))
+	if err := c.write(header, record); err != nil {
+		return 0, err
+	}
+	if !ack {
+		return 0, nil
+	}
+
+	value, _, err := c.reply("WRITE")
+	return value, err
+}
+
+// Match limits the packets read or streamed to stream IDs matching pattern,
+// a regular expression evaluated by the server, e.g. "^IU_ANMO_.*/MSEED$".
+// It returns the number of streams currently matching.
+func (c *Client) Match(pattern string) (int64, error) {
+	return c.command("MATCH "+strconv.Itoa(len(pattern)), []byte(pattern))
+}
+
+// Reject excludes stream IDs matching pattern, see Match.
+func (c *Client) Reject(pattern string) (int64, error) {
+	return c.command("REJECT "+strconv.Itoa(len(pattern)), []byte(pattern))
+}
+
+// PositionSet moves the read position to packet id, which must have been
+// received at packetTime unless packetTime is zero, or to POSITION_EARLIEST
+// or POSITION_LATEST. It returns the ID of the packet positioned at.
+func (c *Client) PositionSet(id int64, packetTime time.Time) (int64, error) {
+	switch {
+	case id == POSITION_EARLIEST:
+		return c.command("POSITION SET EARLIEST", nil)
+	case id == POSITION_LATEST:
+		return c.command("POSITION SET LATEST", nil)
+	case packetTime.IsZero():
+		return c.command(fmt.Sprintf("POSITION SET %d", id), nil)
+	}
+
+	return c.command(fmt.Sprintf("POSITION SET %d %d", id, getHPTime(packetTime)), nil)
+}
+
+// PositionAfter moves the read position to the first packet with data after
+// t. It returns the ID of the packet positioned at.
+func (c *Client) PositionAfter(t time.Time) (int64, error) {
+	return c.command(fmt.Sprintf("POSITION AFTER %d", getHPTime(t)), nil)
+}
+
+// Read returns the packet with the given ID. If only its record cannot be
+// parsed, the packet is returned along with the error.
+func (c *Client) Read(id int64) (*Packet, error) {
+	if c.streaming {
+		return nil, fmt.Errorf("cannot read while streaming")
+	}
+	if err := c.write(fmt.Sprintf("READ %d", id), nil); err != nil {
+		return nil, err
+	}
+
+	f, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	if f.kind() == "ERROR" {
+		return nil, fmt.Errorf("READ: server replied ERROR: %s", f.data)
+	}
+	return getPacket(f)
+}
+
+// Info returns the XML document of an INFO request, e.g. STATUS, STREAMS or
+// CONNECTIONS.
+func (c *Client) Info(kind string) (string, error) {
+	if c.streaming {
+		return "", fmt.Errorf("cannot request INFO while streaming")
+	}
+	if err := c.write("INFO "+kind, nil); err != nil {
+		return "", err
+	}
+
+	f, err := c.read()
+	if err != nil {
+		return "", err
+	}
+	switch f.kind() {
+	case "INFO":
+		return string(f.data), nil
+	case "ERROR":
+		return "", fmt.Errorf("INFO: server replied ERROR: %s", f.data)
+	}
+	return "", fmt.Errorf("INFO: unexpected reply %q", strings.Join(f.fields, " "))
+}
+
+// Stream asks the server to send packets from the read position on as they
+// come, to be read with Next.
+func (c *Client) Stream() error {
+	if c.streaming {
+		return fmt.Errorf("already streaming")
+	}
+	if err := c.write("STREAM", nil); err != nil {
+		return err
+	}
+
+	c.streaming = true
+	c.lastRead = time.Now()
+	return nil
+}
+
+// EndStream asks the server to stop streaming. Next returns the packets
+// already on their way, then io.EOF once the server confirms.
+func (c *Client) EndStream() error {
+	if !c.streaming {
+		return fmt.Errorf("not streaming")
+	}
+
+	return c.write("ENDSTREAM", nil)
+}
+
+// Next returns the next streamed packet, skipping ID replies to keepalives.
+// If only the record of a packet cannot be parsed, the packet is returned
+// along with the error and the connection remains usable.
+func (c *Client) Next() (*Packet, error) {
+	if !c.streaming {
+		return nil, fmt.Errorf("not streaming")
+	}
+
+	for {
+		if err := c.wait(); err != nil {
+			return nil, err
+		}
+		f, err := c.read()
+		if err != nil {
+			return nil, err
+		}
+		c.lastRead = time.Now()
+
+		switch f.kind() {
+		case "ID":
+			continue
+		case "ENDSTREAM":
+			c.streaming = false
+			return nil, io.EOF
+		case "ERROR":
+			return nil, fmt.Errorf("server error: %s", f.data)
+		}
+		return getPacket(f)
+	}
+}
+
+// wait blocks until a frame starts arriving, sending ID every keepalive
+// interval meanwhile and failing after the timeout.
+func (c *Client) wait() error {
+	for {
+		var deadline time.Time
+		if c.opts.keepalive > 0 {
+			deadline = time.Now().Add(c.opts.keepalive)
+		}
+		if c.opts.timeout > 0 {
+			if t := c.lastRead.Add(c.opts.timeout); deadline.IsZero() || t.Before(deadline) {
+				deadline = t
+			}
+		}
+		if err := c.conn.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+
+		_, err := c.reader.Peek(1)
+		var netErr net.Error
+		if err == nil || !errors.As(err, &netErr) || !netErr.Timeout() {
+			return err
+		}
+		if c.opts.timeout > 0 && time.Since(c.lastRead) >= c.opts.timeout {
+			return fmt.Errorf("no data received for %s", c.opts.timeout)
+		}
+		if err := c.write("ID "+c.opts.clientID, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the connection, DataLink having no goodbye.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// command sends a header and payload and returns the value of the OK reply.
+func (c *Client) command(header string, data []byte) (int64, error) {
+	if c.streaming {
+		return 0, fmt.Errorf("cannot send %s while streaming", strings.Fields(header)[0])
+	}
+	if err := c.write(header, data); err != nil {
+		return 0, err
+	}
+
+	value, _, err := c.reply(strings.Fields(header)[0])
+	return value, err
+}
+
+// reply reads an OK or ERROR reply to cmd, returning its value and message.
+func (c *Client) reply(cmd string) (int64, string, error) {
+	f, err := c.read()
+	if err != nil {
+		return 0, "", err
+	}
+
+	kind := f.kind()
+	if (kind != "OK" && kind != "ERROR") || len(f.fields) != 3 {
+		return 0, "", fmt.Errorf("%s: unexpected reply %q", cmd, strings.Join(f.fields, " "))
+	}
+	value, err := strconv.ParseInt(f.fields[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: invalid reply value %q", cmd, f.fields[1])
+	}
+	if kind == "ERROR" {
+		return value, string(f.data), fmt.Errorf("%s: server replied ERROR: %s", cmd, f.data)
+	}
+	return value, string(f.data), nil
+}
+
+// write sends a frame.
+func (c *Client) write(header string, data []byte) error {
+	if c.opts.timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.opts.timeout)); err != nil {
+			return err
+		}
+	}
+
+	return writeFrame(c.conn, header, data)
+}
+
+// read reads a frame, bounded by the timeout if any.
+func (c *Client) read() (*frame, error) {
+	var deadline time.Time
+	if c.opts.timeout > 0 {
+		deadline = time.Now().Add(c.opts.timeout)
+	}
+	_ = c.conn.SetReadDeadline(deadline)
+
+	return readFrame(c.reader)
+}
+
+// getRecordLength walks the blockettes of the record starting p up to
+// blockette 1000, and returns the record length it gives.
+func getRecordLength(p []byte) (int, error) {
+	if len(p) < mseedio.FIXED_SECTION_LENGTH {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	// The year of the start time tells the byte order apart
+	var order binary.ByteOrder = binary.BigEndian
+	if year := order.Uint16(p[20:]); year < 1900 || year > 2500 {
+		order = binary.LittleEndian
+	}
+
+	offset := int(order.Uint16(p[46:]))
+	for offset >= mseedio.FIXED_SECTION_LENGTH && offset+7 <= len(p) {
+		typ, next := order.Uint16(p[offset:]), int(order.Uint16(p[offset+2:]))
+		if typ == 1000 {
+			exponent := p[offset+6]
+			if exponent < 7 || exponent > 16 {
+				return 0, fmt.Errorf("record length 2^%d out of range", exponent)
+			}
+			return 1 << exponent, nil
+		}
+		if next <= offset {
+			break
+		}
+		offset = next
+	}
+
+	return 0, fmt.Errorf("record without blockette 1000")
+}
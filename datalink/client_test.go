@@ -0,0 +1,328 @@
+package datalink
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// fakePacket is a packet held by fakeServer.
+type fakePacket struct {
+	streamID   string
+	start, end int64
+	data       []byte
+}
+
+// fakeServer is a minimal DataLink server holding written packets in memory.
+type fakeServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	packets  []fakePacket
+	commands []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &fakeServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) getCommands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...)
+}
+
+// readCommand reads a client frame, whose payload size is the last field of
+// WRITE, MATCH and REJECT headers.
+func readCommand(r *bufio.Reader) ([]string, []byte, error) {
+	preheader := make([]byte, 3)
+	if _, err := io.ReadFull(r, preheader); err != nil {
+		return nil, nil, err
+	}
+	header := make([]byte, preheader[2])
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+
+	fields := strings.Fields(string(header))
+	var data []byte
+	switch fields[0] {
+	case "WRITE", "MATCH", "REJECT":
+		size, _ := strconv.Atoi(fields[len(fields)-1])
+		data = make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	return fields, data, nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	position := 0
+	for {
+		fields, data, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, strings.Join(fields, " "))
+		s.mu.Unlock()
+
+		switch fields[0] {
+		case "ID":
+			writeFrame(conn, "ID DataLink 2020.075 :: DLPROTO:1.0 PACKETSIZE:512 WRITE", nil)
+		case "WRITE":
+			start, _ := strconv.ParseInt(fields[2], 10, 64)
+			end, _ := strconv.ParseInt(fields[3], 10, 64)
+			s.mu.Lock()
+			s.packets = append(s.packets, fakePacket{fields[1], start, end, data})
+			id := len(s.packets) - 1
+			s.mu.Unlock()
+			if fields[4] == "A" {
+				writeFrame(conn, fmt.Sprintf("OK %d 0", id), nil)
+			}
+		case "MATCH":
+			writeFrame(conn, "OK 1 0", nil)
+		case "REJECT":
+			message := "REJECT not supported"
+			writeFrame(conn, fmt.Sprintf("ERROR 0 %d", len(message)), []byte(message))
+		case "POSITION":
+			position, _ = strconv.Atoi(fields[2])
+			if fields[2] == "EARLIEST" {
+				position = 0
+			}
+			writeFrame(conn, fmt.Sprintf("OK %d 0", position), nil)
+		case "READ":
+			id, _ := strconv.Atoi(fields[1])
+			s.writePacket(conn, id)
+		case "INFO":
+			info := "<DataLink/>"
+			writeFrame(conn, fmt.Sprintf("INFO %s %d", fields[1], len(info)), []byte(info))
+		case "STREAM":
+			s.mu.Lock()
+			n := len(s.packets)
+			s.mu.Unlock()
+			for ; position < n; position++ {
+				s.writePacket(conn, position)
+			}
+		case "ENDSTREAM":
+			writeFrame(conn, "ENDSTREAM", nil)
+		}
+	}
+}
+
+func (s *fakeServer) writePacket(conn net.Conn, id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 0 || id >= len(s.packets) {
+		message := "Packet not found"
+		writeFrame(conn, fmt.Sprintf("ERROR 0 %d", len(message)), []byte(message))
+		return
+	}
+	p := s.packets[id]
+	header := fmt.Sprintf("PACKET %s %d %d %d %d %d", p.streamID, id, p.end, p.start, p.end, len(p.data))
+	writeFrame(conn, header, p.data)
+}
+
+// getTestRecords encodes n 512-byte Steim-2 records of 100 samples at 100 Hz.
+func getTestRecords(t *testing.T, n int) []byte {
+	samples := make([]int32, 100*n)
+	for i := range samples {
+		samples[i] = int32(i)
+	}
+
+	var m mseedio.MiniSeedData
+	_ = m.Init(mseedio.STEIM2, mseedio.MSBFIRST)
+	for i := 0; i < n; i++ {
+		err := m.Append(samples[i*100:(i+1)*100], &mseedio.AppendOptions{
+			SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+			RecordLength: 512, SequenceNumber: fmt.Sprintf("%06d", i+1),
+			NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func dialFakeServer(t *testing.T, s *fakeServer, options ...Option) *Client {
+	c, err := Dial(context.Background(), s.listener.Addr().String(), append([]Option{WithTimeout(5 * time.Second)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestClientID(t *testing.T) {
+	s := newFakeServer(t)
+	c := dialFakeServer(t, s, WithClientID("test:me"))
+
+	if c.ServerID != "DataLink 2020.075" {
+		t.Errorf("unexpected server ID %q", c.ServerID)
+	}
+	if size, ok := c.Capability("PACKETSIZE"); !ok || size != "512" {
+		t.Errorf("want PACKETSIZE 512, got %q", size)
+	}
+	if _, ok := c.Capability("WRITE"); !ok {
+		t.Error("missing WRITE capability")
+	}
+	if got := s.getCommands(); got[0] != "ID test:me" {
+		t.Errorf("unexpected ID command %q", got[0])
+	}
+}
+
+func TestClientWrite(t *testing.T) {
+	s := newFakeServer(t)
+	c := dialFakeServer(t, s)
+
+	records := getTestRecords(t, 3)
+	if n, err := c.Write(records); err != nil || n != len(records) {
+		t.Fatalf("wrote %d of %d bytes: %v", n, len(records), err)
+	}
+	id, err := c.WriteRecord(records[:512], false)
+	if err != nil || id != 0 {
+		t.Fatalf("unexpected packet ID %d: %v", id, err)
+	}
+	if id, err = c.WriteRecord(records[1024:], true); err != nil || id != 4 {
+		t.Fatalf("want packet ID 4, got %d: %v", id, err)
+	}
+
+	got := s.getCommands()
+	want := []string{
+		"WRITE IU_ANMO_00_BHZ/MSEED 1704067200000000 1704067200990000 A 512",
+		"WRITE IU_ANMO_00_BHZ/MSEED 1704067201000000 1704067201990000 A 512",
+		"WRITE IU_ANMO_00_BHZ/MSEED 1704067202000000 1704067202990000 A 512",
+		"WRITE IU_ANMO_00_BHZ/MSEED 1704067200000000 1704067200990000 N 512",
+		"WRITE IU_ANMO_00_BHZ/MSEED 1704067202000000 1704067202990000 A 512",
+	}
+	if strings.Join(got[1:], "\n") != strings.Join(want, "\n") {
+		t.Fatalf("want commands\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got[1:], "\n"))
+	}
+
+	if _, err := c.Write(records[:700]); err == nil {
+		t.Fatal("want error for a truncated record")
+	}
+
+	// A record the reader would skip stops the write, instead of the next
+	// record going out under its header
+	bad := append([]byte{}, records...)
+	bad[512+6] = 'X'
+	before := len(s.getCommands())
+	if n, err := c.Write(bad); err == nil || n != 512 {
+		t.Fatalf("want error after 512 bytes for a record of quality X, got %d: %v", n, err)
+	}
+	if got := s.getCommands()[before:]; len(got) != 1 || got[0] != want[0] {
+		t.Errorf("want only the first record written, got %q", got)
+	}
+}
+
+func TestClientRead(t *testing.T) {
+	s := newFakeServer(t)
+	c := dialFakeServer(t, s)
+	if _, err := c.Write(getTestRecords(t, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Read(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 1 || p.StreamID != "IU_ANMO_00_BHZ/MSEED" || !p.StartTime.Equal(time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC)) {
+		t.Fatalf("unexpected packet %d of %q starting %s", p.ID, p.StreamID, p.StartTime)
+	}
+	if decoded := p.Series.DataSection.Decoded; len(decoded) != 100 || decoded[0] != int32(100) {
+		t.Fatalf("unexpected samples %v", decoded)
+	}
+
+	if _, err := c.Read(5); err == nil || !strings.Contains(err.Error(), "Packet not found") {
+		t.Fatalf("want ERROR reply, got %v", err)
+	}
+	if info, err := c.Info("STATUS"); err != nil || info != "<DataLink/>" {
+		t.Fatalf("unexpected INFO reply %q: %v", info, err)
+	}
+	if n, err := c.Match("^IU_ANMO_.*/MSEED$"); err != nil || n != 1 {
+		t.Fatalf("want 1 stream matched, got %d: %v", n, err)
+	}
+	if _, err := c.Reject("^XX_"); err == nil || !strings.Contains(err.Error(), "REJECT not supported") {
+		t.Fatalf("want ERROR reply, got %v", err)
+	}
+}
+
+func TestClientStream(t *testing.T) {
+	s := newFakeServer(t)
+	c := dialFakeServer(t, s, WithKeepalive(10*time.Millisecond))
+	if _, err := c.Write(getTestRecords(t, 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := c.PositionSet(1, time.Time{}); err != nil || id != 1 {
+		t.Fatalf("want position 1, got %d: %v", id, err)
+	}
+	if err := c.Stream(); err != nil {
+		t.Fatal(err)
+	}
+	for want := int64(1); want < 3; want++ {
+		p, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.ID != want || strings.TrimSpace(p.Series.FixedSection.StationCode) != "ANMO" {
+			t.Fatalf("want packet %d, got %d", want, p.ID)
+		}
+	}
+	if _, err := c.Read(0); err == nil {
+		t.Fatal("want error for READ while streaming")
+	}
+
+	// Idle until a keepalive has gone out
+	time.Sleep(30 * time.Millisecond)
+	if err := c.EndStream(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Next(); err != io.EOF {
+		t.Fatalf("want io.EOF, got %v", err)
+	}
+
+	got := strings.Join(s.getCommands(), "|")
+	for _, want := range []string{"POSITION SET 1", "STREAM", "ENDSTREAM"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing command %q in %q", want, got)
+		}
+	}
+}
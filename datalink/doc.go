@@ -0,0 +1,28 @@
+// Package datalink implements a client of DataLink, the TCP protocol used to
+// push packets into and pull them from a ringserver.
+//
+// # Writing records
+//
+//	c, err := datalink.Dial(ctx, "localhost:16000")
+//	if err != nil {
+//		// handle error
+//	}
+//	defer c.Close()
+//
+//	records, _ := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+//	if _, err := c.Write(records); err != nil {
+//		// handle error
+//	}
+//
+// Write sends every record of an Encode output under its NET_STA_LOC_CHA/MSEED
+// stream ID, with the times of its first and last samples, and waits for each
+// acknowledgement. WriteRecord sends one record, acknowledged or not.
+//
+// # Reading records
+//
+// Match and Reject filter stream IDs, PositionSet and PositionAfter move the
+// read position, Read returns one packet and Stream has the server send them
+// as they come, to be read with Next until EndStream. Packets of MSEED streams
+// come with their record parsed by mseedio. With WithKeepalive, the client
+// sends ID while streaming whenever no packet has arrived for the interval.
+package datalink
@@ -0,0 +1,47 @@
+package datalink
+
+import "time"
+
+// Option configures Dial and NewClient.
+type Option func(*clientOptions)
+
+// clientOptions holds the settings applied by Option values.
+type clientOptions struct {
+	clientID  string        // Sent with ID, program:user:pid:arch in libdali
+	timeout   time.Duration // Network timeout, 0 for none
+	keepalive time.Duration // Idle time before sending ID while streaming, 0 for none
+}
+
+// WithClientID sets the client ID sent to the server, "mseedio" by default.
+func WithClientID(id string) Option {
+	return func(o *clientOptions) {
+		o.clientID = id
+	}
+}
+
+// WithTimeout fails commands that get no reply, and Next when nothing at all
+// arrives, within d. Keepalive replies count as traffic.
+func WithTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithKeepalive sends ID after d without packets while streaming.
+func WithKeepalive(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.keepalive = d
+	}
+}
+
+// getOptions applies options over the defaults.
+func getOptions(options []Option) *clientOptions {
+	o := &clientOptions{
+		clientID: "mseedio",
+	}
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
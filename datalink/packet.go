@@ -0,0 +1,170 @@
+package datalink
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	MAX_HEADER_LENGTH       = 255     // Header length is carried in one byte
+	MAX_DATA_LENGTH         = 1 << 20 // Largest payload accepted from a server
+	MSEED_SUFFIX            = "/MSEED"
+	POSITION_EARLIEST int64 = -2 // PositionSet to the oldest packet in the ring
+	POSITION_LATEST   int64 = -3 // PositionSet to the newest packet in the ring
+)
+
+// Packet is one packet read from the ring of a DataLink server.
+type Packet struct {
+	StreamID   string             // e.g. "IU_ANMO_00_BHZ/MSEED"
+	ID         int64              // Packet ID in the server ring
+	PacketTime time.Time          // When the server received the packet
+	StartTime  time.Time          // Time of the first sample
+	EndTime    time.Time          // Time of the last sample
+	Data       []byte             // Payload as received
+	Series     mseedio.DataSeries // The record parsed, for MSEED streams
+}
+
+// frame is one message: "DL", the header length, the ASCII header and, for
+// headers announcing a size, the payload.
+type frame struct {
+	fields []string
+	data   []byte
+}
+
+// kind returns the first word of the header, e.g. "PACKET" or "OK".
+func (f *frame) kind() string {
+	if len(f.fields) == 0 {
+		return ""
+	}
+
+	return f.fields[0]
+}
+
+// readFrame reads the next frame sent by the server.
+func readFrame(r *bufio.Reader) (*frame, error) {
+	preheader := make([]byte, 3)
+	if _, err := io.ReadFull(r, preheader); err != nil {
+		return nil, err
+	}
+	if string(preheader[:2]) != "DL" {
+		return nil, fmt.Errorf("unexpected frame signature %q", preheader[:2])
+	}
+
+	header := make([]byte, preheader[2])
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	f := &frame{fields: strings.Fields(string(header))}
+	size, err := getDataSize(f.fields)
+	if err != nil {
+		return nil, err
+	}
+	f.data = make([]byte, size)
+	if _, err := io.ReadFull(r, f.data); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// getDataSize returns the payload size announced by a header, 0 for headers
+// without payload such as ID and ENDSTREAM.
+func getDataSize(fields []string) (int, error) {
+	var field string
+	switch {
+	case len(fields) == 0:
+		return 0, fmt.Errorf("empty header")
+	case fields[0] == "PACKET" && len(fields) == 7:
+		field = fields[6]
+	case (fields[0] == "OK" || fields[0] == "ERROR" || fields[0] == "INFO") && len(fields) == 3:
+		field = fields[2]
+	case fields[0] == "PACKET" || fields[0] == "OK" || fields[0] == "ERROR" || fields[0] == "INFO":
+		return 0, fmt.Errorf("malformed %s header %q", fields[0], strings.Join(fields, " "))
+	default:
+		return 0, nil
+	}
+
+	size, err := strconv.Atoi(field)
+	if err != nil || size < 0 || size > MAX_DATA_LENGTH {
+		return 0, fmt.Errorf("invalid payload size %q", field)
+	}
+	return size, nil
+}
+
+// writeFrame sends a header and its payload, if any.
+func writeFrame(w io.Writer, header string, data []byte) error {
+	if len(header) > MAX_HEADER_LENGTH {
+		return fmt.Errorf("header of %d bytes exceeds %d", len(header), MAX_HEADER_LENGTH)
+	}
+
+	buffer := make([]byte, 0, 3+len(header)+len(data))
+	buffer = append(buffer, 'D', 'L', byte(len(header)))
+	buffer = append(append(buffer, header...), data...)
+	_, err := w.Write(buffer)
+	return err
+}
+
+// getPacket converts a PACKET frame, parsing the record of MSEED streams.
+// If only the record cannot be parsed, the packet is returned with the error.
+func getPacket(f *frame) (*Packet, error) {
+	if f.kind() != "PACKET" {
+		return nil, fmt.Errorf("expected PACKET, got %q", strings.Join(f.fields, " "))
+	}
+
+	var values [4]int64
+	for i := range values {
+		v, err := strconv.ParseInt(f.fields[i+2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PACKET field %q", f.fields[i+2])
+		}
+		values[i] = v
+	}
+
+	p := &Packet{
+		StreamID:   f.fields[1],
+		ID:         values[0],
+		PacketTime: getTime(values[1]),
+		StartTime:  getTime(values[2]),
+		EndTime:    getTime(values[3]),
+		Data:       f.data,
+	}
+	if !strings.HasSuffix(p.StreamID, MSEED_SUFFIX) {
+		return p, nil
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(p.Data), mseedio.WithIntegrity(mseedio.INTEGRITY_WARN)); err != nil {
+		return p, err
+	}
+	p.Series = m.Series[0]
+	return p, nil
+}
+
+// getStreamID returns the NET_STA_LOC_CHA/MSEED stream ID of a record.
+func getStreamID(f *mseedio.FixedSection) string {
+	codes := []string{f.NetworkCode, f.StationCode, f.LocationCode, f.ChannelCode}
+	for i := range codes {
+		codes[i] = strings.TrimSpace(codes[i])
+	}
+
+	return strings.Join(codes, "_") + MSEED_SUFFIX
+}
+
+// getHPTime returns t in the high-precision ticks of DataLink headers,
+// microseconds since the Unix epoch.
+func getHPTime(t time.Time) int64 {
+	return t.UnixMicro()
+}
+
+// getTime converts high-precision ticks back to a UTC time.
+func getTime(ticks int64) time.Time {
+	return time.UnixMicro(ticks).UTC()
+}
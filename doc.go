@@ -5,8 +5,11 @@
 // A miniSEED stream is a sequence of fixed-length data records. Each record
 // begins with a 48-byte fixed header (FixedSection), followed by one or more
 // blockettes (BlocketteSection) — this package fully supports blockette 1000
-// (Data Only SEED) and 1001 (Data Extension) — and then the encoded samples
-// (DataSection).
+// (Data Only SEED), 1001 (Data Extension), 500 (Timing) and 2000 (Opaque Data)
+// — and then the encoded samples (DataSection). Blockettes chained after the
+// first one are kept in DataSeries.Blockettes; other types are kept verbatim
+// in BlocketteSection.Payload unless a codec is installed with
+// RegisterBlockette.
 //
 // # Reading
 //
@@ -20,7 +23,15 @@
 //
 // Read (or ReadFromReader for an arbitrary io.Reader) auto-detects the byte
 // order and decodes every supported sample encoding: ASCII, INT16, INT24,
-// INT32, FLOAT32, FLOAT64, and the Steim-1/Steim-2 compressions.
+// INT32, FLOAT32, FLOAT64, and the Steim-1/Steim-2 compressions. ReadAt reads
+// from an io.ReaderAt; given a MappedFile from OpenMapped it parses records in
+// place, and with WithLazyDecode samples are only decoded by
+// DataSection.Decode. Steim records whose last sample does not match Xn, or
+// that hold fewer samples than their header claims, are dropped, the read
+// returning their errors along with the other records, unless
+// WithIntegrity(INTEGRITY_WARN) keeps them with DataSeries.Warnings set, or
+// INTEGRITY_IGNORE keeps them silently. Malformed or truncated headers make
+// the read functions skip the record or return an error, never panic.
 //
 // # Writing
 //
@@ -32,5 +43,30 @@
 //
 // Init sets the encoding and byte order, Append adds one blockette-1000 record
 // per call, Encode serializes the records to bytes, and Write persists them.
+// Setting AppendOptions.RecordLength (e.g. 512) instead splits the samples
+// across as many fixed-size records as needed; EncodeSteim1 and EncodeSteim2
+// greedily pack a given number of frames and report how many samples fit.
 // Note that the Steim compressions require MSBFIRST (big-endian) byte order.
+//
+// # Traces
+//
+// Traces merges contiguous records of a channel into continuous Trace values
+// and Gaps reports the breaks between them. Record start times are kept as
+// BTime in FixedSection.StartBTime, which, unlike time.Time, can represent a
+// leap second; DataSeries.EndTime uses the leap-second activity flags so that
+// records spanning one merge without a bogus gap or overlap. Samples inside a
+// positive leap second are timed at the midnight ending it, keeping sample
+// times in order. Overlapping
+// records of different data quality are resolved in favor of M, then Q, R
+// and D data; WithQualities restricts reading to some qualities altogether.
+//
+// # Exporting
+//
+// FixedSection, BlocketteSection, DataSeries and Trace implement
+// json.Marshaler with snake_case keys, ISO 8601 times, the sample rate in Hz
+// and the sample type next to the samples. WriteCSV writes traces one sample
+// per row, for spreadsheets:
+//
+//	out, _ := json.MarshalIndent(ms.Traces(), "", "  ")
+//	mseedio.WriteCSV(os.Stdout, ms.Traces())
 package mseedio
@@ -19,66 +19,99 @@ func (m *MiniSeedData) Encode(encodeMode, bitOrder int) ([]byte, error) {
 
 	// Append mode only encode last record
 	if encodeMode == APPEND {
-		lastRecord := m.Series[len(m.Series)-1]
-		if lastRecord.BlocketteSection.BlocketteCode != 1000 {
-			return nil, fmt.Errorf("only 1000-blockette is supported")
-		}
-
-		// Create data bytes with fixed length
-		dataLength := int(math.Pow(2, float64(lastRecord.BlocketteSection.RecordLength)))
-		dataBytes := make([]byte, dataLength)
-
-		// Compose fixed section data bytes
-		fs, err := lastRecord.FixedSection.Compose(bitOrder)
-		if err != nil {
-			return nil, err
-		}
+		return composeRecord(m.Series[len(m.Series)-1], bitOrder)
+	}
 
-		// Compose blockette section data bytes
-		bs, err := lastRecord.BlocketteSection.Compose(bitOrder)
+	// Go through all record and encode
+	var dataBytes []byte
+	for _, v := range m.Series {
+		dataSlice, err := composeRecord(v, bitOrder)
 		if err != nil {
 			return nil, err
 		}
 
-		// Copy raw data to data bytes
-		copy(dataBytes, fs)
-		copy(dataBytes[FIXED_SECTION_LENGTH:], bs)
-		copy(dataBytes[FIXED_SECTION_LENGTH+BLOCKETTE100X_SECTION_LENGTH:], lastRecord.DataSection.RawData)
-
-		return dataBytes, nil
+		// Append slice to data bytes
+		dataBytes = append(dataBytes, dataSlice...)
 	}
 
-	// Go through all record and encode
-	var dataBytes []byte
-	for _, v := range m.Series {
-		if v.BlocketteSection.BlocketteCode != 1000 {
-			return nil, fmt.Errorf("only 1000-blockette is supported")
-		}
+	return dataBytes, nil
+}
 
-		// Create data slice with fixed length
-		dataLength := int(math.Pow(2, float64(v.BlocketteSection.RecordLength)))
-		dataSlice := make([]byte, dataLength)
+// composeRecord serializes a data series into a record of 2^RecordLength bytes:
+// the fixed section, blockette 1000, any chained blockettes, then the data.
+func composeRecord(v DataSeries, bitOrder int) ([]byte, error) {
+	if v.BlocketteSection.BlocketteCode != 1000 {
+		return nil, fmt.Errorf("only 1000-blockette is supported")
+	}
 
-		// Compose fixed section data bytes
-		fs, err := v.FixedSection.Compose(bitOrder)
+	// Compose chained blockettes, linking each one to the next
+	var (
+		chained []byte
+		offset  = FIXED_SECTION_LENGTH + 8 // Blockette 1000 is 8 bytes long
+		bs      = v.BlocketteSection
+	)
+	bs.NextBlockette = 0
+	if len(v.Blockettes) > 0 {
+		bs.NextBlockette = int32(offset)
+	}
+	for i, b := range v.Blockettes {
+		b.NextBlockette = 0
+		data, err := b.Compose(bitOrder)
 		if err != nil {
 			return nil, err
 		}
 
-		// Compose blockette section data bytes
-		bs, err := v.BlocketteSection.Compose(bitOrder)
-		if err != nil {
-			return nil, err
+		offset += len(data)
+		if i < len(v.Blockettes)-1 {
+			copy(data[2:4], disassembleInt(int32(offset), 2, bitOrder))
 		}
+		chained = append(chained, data...)
+	}
 
-		// Copy raw data to data bytes
-		copy(dataSlice, fs)
-		copy(dataSlice[FIXED_SECTION_LENGTH:], bs)
-		copy(dataSlice[FIXED_SECTION_LENGTH+BLOCKETTE100X_SECTION_LENGTH:], v.DataSection.RawData)
+	// Data begins after the blockettes, keeping the original offset if it fits
+	fixed := v.FixedSection
+	dataOffset := int(fixed.DataStartOffset)
+	if dataOffset < offset {
+		dataOffset = getDataOffset(offset)
+	}
+	fixed.BlockettesFollow = int32(1 + len(v.Blockettes))
+	fixed.DataStartOffset = int32(dataOffset)
 
-		// Append slice to data bytes
-		dataBytes = append(dataBytes, dataSlice...)
+	// Create data bytes with fixed length
+	dataLength := int(math.Pow(2, float64(bs.RecordLength)))
+	if dataOffset+len(v.DataSection.RawData) > dataLength {
+		return nil, fmt.Errorf("record length %d is too short for %d bytes of data at offset %d",
+			dataLength, len(v.DataSection.RawData), dataOffset)
 	}
+	dataBytes := make([]byte, dataLength)
+
+	// Compose fixed section data bytes
+	fs, err := fixed.Compose(bitOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compose blockette section data bytes
+	b1000, err := bs.Compose(bitOrder)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy raw data to data bytes
+	copy(dataBytes, fs)
+	copy(dataBytes[FIXED_SECTION_LENGTH:], b1000)
+	copy(dataBytes[FIXED_SECTION_LENGTH+8:], chained)
+	copy(dataBytes[dataOffset:], v.DataSection.RawData)
 
 	return dataBytes, nil
 }
+
+// getDataOffset rounds the end of the blockettes up to the next 64-byte
+// boundary, where Steim frames (and by convention all data) must begin.
+func getDataOffset(blocketteEnd int) int {
+	if blocketteEnd <= FIXED_SECTION_LENGTH+BLOCKETTE100X_SECTION_LENGTH {
+		return FIXED_SECTION_LENGTH + BLOCKETTE100X_SECTION_LENGTH
+	}
+
+	return (blocketteEnd + 63) / 64 * 64
+}
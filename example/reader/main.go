@@ -1,8 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
-	"reflect"
+	"os"
 
 	"github.com/bclswl0827/mseedio"
 )
@@ -16,28 +17,18 @@ func main() {
 		return
 	}
 
-	// Print structured data
+	// Print records as JSON
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
 	for _, v := range miniseed.Series {
-		printFields(v.FixedSection)
-		printFields(v.BlocketteSection)
-		fmt.Println("DataSeries:", v.DataSection.Decoded)
-		fmt.Println()
+		if err := encoder.Encode(v); err != nil {
+			fmt.Println(err)
+			return
+		}
 	}
-}
-
-func printFields(obj any) {
-	value := reflect.ValueOf(obj)
-	typ := reflect.TypeOf(obj)
 
-	if typ.Kind() != reflect.Struct {
-		fmt.Println("Object is not a struct")
-		return
-	}
-
-	for i := 0; i < value.NumField(); i++ {
-		fieldValue := value.Field(i)
-		fieldType := typ.Field(i)
-
-		fmt.Printf("%s: %v\n", fieldType.Name, fieldValue.Interface())
+	// Print traces as CSV
+	if err := mseedio.WriteCSV(os.Stdout, miniseed.Traces()); err != nil {
+		fmt.Println(err)
 	}
 }
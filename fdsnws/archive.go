@@ -0,0 +1,118 @@
+package fdsnws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// Archive finds the records served by Handler.
+type Archive interface {
+	// Records calls fn with the records of the channels matching s that
+	// overlap its time window, every record of a channel in a row, stopping
+	// at the first error of fn. Codes of s may hold the wildcards * and ?,
+	// are never empty, and "--" stands for the empty location code.
+	Records(ctx context.Context, s Selection, fn func(*mseedio.DataSeries) error) error
+}
+
+// SDSArchive is an Archive over a SeisComP Data Structure directory tree:
+// Root/YEAR/NET/STA/CHA.D/NET.STA.LOC.CHA.D.YEAR.DAY, one file per channel
+// and day.
+type SDSArchive struct {
+	Root string
+}
+
+// Records globs the day files of the window, the day before included as its
+// last records may run past midnight, and reads them one at a time, channel
+// after channel, so that a single day file is held in memory. Windows
+// longer than MAX_WINDOW are refused.
+func (a *SDSArchive) Records(ctx context.Context, s Selection, fn func(*mseedio.DataSeries) error) error {
+	if s.StartTime.IsZero() || s.EndTime.IsZero() || s.EndTime.Before(s.StartTime) {
+		return fmt.Errorf("invalid time window %s to %s", s.StartTime, s.EndTime)
+	}
+	if s.EndTime.Sub(s.StartTime) > MAX_WINDOW {
+		return fmt.Errorf("%w: time window longer than %s", errTooLarge, MAX_WINDOW)
+	}
+
+	location := s.Location
+	if location == "--" {
+		location = ""
+	}
+
+	// Day files by channel, NET.STA.LOC.CHA.D, in day order
+	files := map[string][]string{}
+	day := s.StartTime.UTC().Truncate(24*time.Hour).AddDate(0, 0, -1)
+	for ; !day.After(s.EndTime); day = day.AddDate(0, 0, 1) {
+		file := fmt.Sprintf("%s.%s.%s.%s.D.%04d.%03d", s.Network, s.Station, location, s.Channel, day.Year(), day.YearDay())
+		pattern := filepath.Join(a.Root, fmt.Sprintf("%04d", day.Year()), s.Network, s.Station, s.Channel+".D", file)
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		for _, match := range matches {
+			channel := strings.TrimSuffix(filepath.Base(match), filepath.Ext(filepath.Base(match)))
+			channel = strings.TrimSuffix(channel, filepath.Ext(channel))
+			files[channel] = append(files[channel], match)
+		}
+	}
+	channels := make([]string, 0, len(files))
+	for channel := range files {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	for _, channel := range channels {
+		for _, file := range files[channel] {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := readSDSFile(file, &s, location, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readSDSFile reads a day file, calling fn with the records that overlap the
+// window and match the location and channel of the selection.
+func readSDSFile(file string, s *Selection, location string, fn func(*mseedio.DataSeries) error) error {
+	if info, err := os.Stat(file); err != nil || info.Size() == 0 {
+		return err
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.Read(file, mseedio.WithLazyDecode()); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	for i := range m.Series {
+		series := &m.Series[i]
+		f := &series.FixedSection
+		if series.EndTime().Before(s.StartTime) || f.StartTime.After(s.EndTime) {
+			continue
+		}
+		if !matchCode(location, f.LocationCode) || !matchCode(s.Channel, f.ChannelCode) {
+			continue
+		}
+		if err := fn(series); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matchCode matches a padded header code against a pattern with wildcards.
+func matchCode(pattern, code string) bool {
+	matched, _ := path.Match(pattern, strings.TrimSpace(code))
+	return matched
+}
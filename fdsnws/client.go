@@ -0,0 +1,199 @@
+package fdsnws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	DATASELECT_PATH = "/fdsnws/dataselect/1/query"
+	TIME_FORMAT     = "2006-01-02T15:04:05.000000" // Times of requests, always UTC
+	MAX_ERROR_BODY  = 4096                         // Bytes of an error document kept in errors
+)
+
+// ErrNoData is returned when the data center has no data for the request,
+// which it replies with HTTP 204 or 404.
+var ErrNoData = errors.New("fdsnws: no data")
+
+// Selection is one network/station/location/channel and time window. Codes
+// may hold the wildcards * and ?, empty ones match everything and "--" is
+// the empty location code.
+type Selection struct {
+	Network   string
+	Station   string
+	Location  string
+	Channel   string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Query is a dataselect request. A single selection is sent as GET
+// parameters, several as a POST bulk request, which needs start and end
+// times on every selection.
+type Query struct {
+	Selections    []Selection
+	Quality       string  // D, R, Q, M or B (best), server default if empty
+	MinimumLength float64 // Fraction of the window a trace must cover, 0 to 1, 0 for no limit
+	LongestOnly   bool    // Only the longest continuous trace of each channel
+}
+
+// Client requests data from an FDSN web service.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+}
+
+// NewClient returns a client of the data center at baseURL, e.g.
+// "https://service.iris.edu".
+func NewClient(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		userAgent:  "mseedio",
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	return c
+}
+
+// Dataselect requests miniSEED data and returns a Reader streaming the
+// records of the response, parsed with options. It returns ErrNoData if the
+// data center has none. The Reader must be closed.
+func (c *Client) Dataselect(ctx context.Context, q *Query, options ...mseedio.ReadOption) (*Reader, error) {
+	req, err := c.getDataselectRequest(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return newReader(resp.Body, options), nil
+	case http.StatusNoContent, http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNoData
+	}
+
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MAX_ERROR_BODY))
+	return nil, fmt.Errorf("fdsnws: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+}
+
+// getDataselectRequest builds the GET or POST request of a query.
+func (c *Client) getDataselectRequest(ctx context.Context, q *Query) (*http.Request, error) {
+	if len(q.Selections) == 0 {
+		return nil, fmt.Errorf("no selection in query")
+	}
+	if q.MinimumLength < 0 || q.MinimumLength > 1 {
+		return nil, fmt.Errorf("minimum length %g out of 0 to 1", q.MinimumLength)
+	}
+
+	endpoint := c.baseURL + DATASELECT_PATH
+	var (
+		req *http.Request
+		err error
+	)
+	if len(q.Selections) == 1 {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+q.getParameters().Encode(), nil)
+	} else {
+		var body string
+		body, err = q.getBulkBody()
+		if err != nil {
+			return nil, err
+		}
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(body))
+		if req != nil {
+			req.Header.Set("Content-Type", "text/plain")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
+	return req, nil
+}
+
+// getOptions returns the parameters other than selections, in the order of
+// the specification.
+func (q *Query) getOptions() [][2]string {
+	var options [][2]string
+	if q.Quality != "" {
+		options = append(options, [2]string{"quality", q.Quality})
+	}
+	if q.MinimumLength > 0 {
+		options = append(options, [2]string{"minimumlength", strconv.FormatFloat(q.MinimumLength, 'f', -1, 64)})
+	}
+	if q.LongestOnly {
+		options = append(options, [2]string{"longestonly", "true"})
+	}
+
+	return options
+}
+
+// getParameters returns the GET parameters of a single-selection query.
+func (q *Query) getParameters() url.Values {
+	s := &q.Selections[0]
+	values := url.Values{}
+	for _, p := range [][2]string{
+		{"network", s.Network}, {"station", s.Station}, {"location", s.Location}, {"channel", s.Channel},
+	} {
+		if p[1] != "" {
+			values.Set(p[0], p[1])
+		}
+	}
+	if !s.StartTime.IsZero() {
+		values.Set("starttime", s.StartTime.UTC().Format(TIME_FORMAT))
+	}
+	if !s.EndTime.IsZero() {
+		values.Set("endtime", s.EndTime.UTC().Format(TIME_FORMAT))
+	}
+	for _, option := range q.getOptions() {
+		values.Set(option[0], option[1])
+	}
+
+	return values
+}
+
+// getBulkBody returns the POST body of a query: key=value options, then one
+// "NET STA LOC CHA START END" line per selection.
+func (q *Query) getBulkBody() (string, error) {
+	var body strings.Builder
+	for _, option := range q.getOptions() {
+		fmt.Fprintf(&body, "%s=%s\n", option[0], option[1])
+	}
+
+	for i := range q.Selections {
+		s := &q.Selections[i]
+		if s.StartTime.IsZero() || s.EndTime.IsZero() {
+			return "", fmt.Errorf("selection %d: bulk requests need start and end times", i)
+		}
+
+		codes := []string{s.Network, s.Station, s.Location, s.Channel}
+		for j := range codes {
+			if codes[j] == "" {
+				codes[j] = "*"
+			}
+		}
+		fmt.Fprintf(&body, "%s %s %s\n", strings.Join(codes, " "),
+			s.StartTime.UTC().Format(TIME_FORMAT), s.EndTime.UTC().Format(TIME_FORMAT))
+	}
+
+	return body.String(), nil
+}
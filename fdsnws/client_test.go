@@ -0,0 +1,191 @@
+package fdsnws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// getTestRecords encodes one 512-byte record per channel, little-endian ones
+// with 4096 bytes, 100 samples at 100 Hz each.
+func getTestRecords(t *testing.T, channels ...string) []byte {
+	var records []byte
+	for i, channel := range channels {
+		samples := make([]int32, 100)
+		for j := range samples {
+			samples[j] = int32(i*100 + j)
+		}
+
+		order, length := mseedio.MSBFIRST, 512
+		if i%2 == 1 {
+			order, length = mseedio.LSBFIRST, 4096
+		}
+		var m mseedio.MiniSeedData
+		_ = m.Init(mseedio.INT32, order)
+		err := m.Append(samples, &mseedio.AppendOptions{
+			SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			RecordLength: length, SequenceNumber: fmt.Sprintf("%06d", i+1),
+			NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: channel,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := m.Encode(mseedio.OVERWRITE, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record...)
+	}
+	return records
+}
+
+// newTestServer serves body on the dataselect path and records the last
+// request and its body.
+func newTestServer(t *testing.T, status int, body []byte) (*httptest.Server, *http.Request, *string) {
+	var (
+		last     http.Request
+		lastBody string
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != DATASELECT_PATH {
+			http.NotFound(w, r)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		last, lastBody = *r, string(b)
+
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s, &last, &lastBody
+}
+
+func TestDataselectGet(t *testing.T) {
+	s, last, _ := newTestServer(t, http.StatusOK, getTestRecords(t, "BHZ", "BHN", "BHE"))
+	c := NewClient(s.URL+"/", WithUserAgent("test/1.0"))
+
+	q := &Query{
+		Selections: []Selection{{
+			Network: "IU", Station: "ANMO", Location: "00", Channel: "BH?",
+			StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndTime:   time.Date(2024, 1, 1, 1, 0, 0, 500000000, time.UTC),
+		}},
+		Quality: "B", MinimumLength: 0.5,
+	}
+	r, err := c.Dataselect(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var got []string
+	for {
+		series, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s:%d", series.FixedSection.ChannelCode, len(series.DataSection.Decoded)))
+	}
+	if want := "BHZ:100 BHN:100 BHE:100"; strings.Join(got, " ") != want {
+		t.Fatalf("want %q, got %q", want, strings.Join(got, " "))
+	}
+
+	if last.Method != http.MethodGet || last.Header.Get("User-Agent") != "test/1.0" {
+		t.Errorf("unexpected %s request with User-Agent %q", last.Method, last.Header.Get("User-Agent"))
+	}
+	want := "channel=BH%3F&endtime=2024-01-01T01%3A00%3A00.500000&location=00&minimumlength=0.5" +
+		"&network=IU&quality=B&starttime=2024-01-01T00%3A00%3A00.000000&station=ANMO"
+	if last.URL.RawQuery != want {
+		t.Errorf("want query %q, got %q", want, last.URL.RawQuery)
+	}
+}
+
+func TestDataselectBulk(t *testing.T) {
+	s, last, lastBody := newTestServer(t, http.StatusOK, getTestRecords(t, "BHZ", "LHZ"))
+	c := NewClient(s.URL)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := &Query{
+		Selections: []Selection{
+			{Network: "IU", Station: "ANMO", Location: "00", Channel: "BHZ", StartTime: start, EndTime: start.Add(time.Hour)},
+			{Network: "IU", Station: "ANMO", Location: "--", StartTime: start, EndTime: start.Add(time.Minute)},
+		},
+		LongestOnly: true,
+	}
+	r, err := c.Dataselect(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	m, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Records != 2 || m.Samples != 200 || m.Series[1].FixedSection.ChannelCode != "LHZ" {
+		t.Fatalf("want 2 records of 200 samples ending with LHZ, got %d of %d", m.Records, m.Samples)
+	}
+
+	want := "longestonly=true\n" +
+		"IU ANMO 00 BHZ 2024-01-01T00:00:00.000000 2024-01-01T01:00:00.000000\n" +
+		"IU ANMO -- * 2024-01-01T00:00:00.000000 2024-01-01T00:01:00.000000\n"
+	if last.Method != http.MethodPost || *lastBody != want {
+		t.Fatalf("want POST body %q, got %s %q", want, last.Method, *lastBody)
+	}
+
+	q.Selections[1].EndTime = time.Time{}
+	if _, err := c.Dataselect(context.Background(), q); err == nil {
+		t.Fatal("want error for a bulk selection without end time")
+	}
+}
+
+func TestDataselectErrors(t *testing.T) {
+	q := &Query{Selections: []Selection{{Network: "XX"}}}
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		s, _, _ := newTestServer(t, status, nil)
+		if _, err := NewClient(s.URL).Dataselect(context.Background(), q); !errors.Is(err, ErrNoData) {
+			t.Errorf("status %d: want ErrNoData, got %v", status, err)
+		}
+	}
+
+	s, _, _ := newTestServer(t, http.StatusBadRequest, []byte("Error 400: Bad Request\n\nUnrecognized parameter\n"))
+	_, err := NewClient(s.URL).Dataselect(context.Background(), q)
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request: Error 400") {
+		t.Fatalf("want 400 error with the server message, got %v", err)
+	}
+
+	if _, err := NewClient(s.URL).Dataselect(context.Background(), &Query{}); err == nil {
+		t.Fatal("want error for a query without selection")
+	}
+	bad := &Query{Selections: q.Selections, MinimumLength: 2}
+	if _, err := NewClient(s.URL).Dataselect(context.Background(), bad); err == nil {
+		t.Fatal("want error for a minimum length above 1")
+	}
+
+	// A truncated body fails the record being read, not the ones before
+	records := getTestRecords(t, "BHZ", "BHN")
+	s, _, _ = newTestServer(t, http.StatusOK, records[:1000])
+	r, err := NewClient(s.URL).Dataselect(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := r.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("want io.ErrUnexpectedEOF, got %v", err)
+	}
+}
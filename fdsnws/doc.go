@@ -0,0 +1,42 @@
+// Package fdsnws implements a client and a server of the FDSN dataselect web
+// service, which serves miniSEED from data centers over HTTP.
+//
+//	c := fdsnws.NewClient("https://service.iris.edu")
+//	r, err := c.Dataselect(ctx, &fdsnws.Query{
+//		Selections: []fdsnws.Selection{{
+//			Network: "IU", Station: "ANMO", Location: "00", Channel: "BHZ",
+//			StartTime: start, EndTime: start.Add(time.Hour),
+//		}},
+//	})
+//	if errors.Is(err, fdsnws.ErrNoData) {
+//		// nothing in the window
+//	}
+//	defer r.Close()
+//	for {
+//		series, err := r.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		// use series
+//	}
+//
+// A query with one selection is sent as GET parameters, one with several as
+// a POST bulk request. The response is read one record at a time by Next, or
+// at once by ReadAll.
+//
+// # Serving data
+//
+// Handler serves the query, version and application.wadl resources of
+// dataselect 1 from an Archive, such as an SDS directory tree:
+//
+//	h := fdsnws.NewHandler(&fdsnws.SDSArchive{Root: "/data/sds"})
+//	http.ListenAndServe(":8080", h)
+//
+// Records overlapping the ends of the window are trimmed to it, and the
+// quality, minimumlength, longestonly and nodata options are honored, quality
+// B (the default) keeping the records of the best quality where records of a
+// channel overlap.
+// Records are sent channel by channel as the archive reads them. Requests
+// of more than MAX_SELECTIONS selections, or of a window longer than
+// MAX_WINDOW, are refused with 413.
+package fdsnws
@@ -0,0 +1,638 @@
+package fdsnws
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	VERSION            = "1.1.0" // Version of the dataselect specification implemented
+	MSEED_CONTENT_TYPE = "application/vnd.fdsn.mseed"
+	MAX_POST_BODY      = 1 << 20 // Bytes of a POST bulk request accepted
+	MAX_SELECTIONS     = 1000    // Selections of a request accepted, GET code lists combined
+
+	// MAX_WINDOW is the longest time window of a selection accepted. The
+	// trimmed records of a channel over the window are held in memory.
+	MAX_WINDOW = 7 * 24 * time.Hour
+)
+
+// errTooLarge marks requests refused with 413 Request Entity Too Large.
+var errTooLarge = errors.New("request too large")
+
+// Handler serves the FDSN dataselect web service from an Archive:
+// query (GET and POST), version and application.wadl under
+// /fdsnws/dataselect/1/.
+type Handler struct {
+	archive Archive
+}
+
+// NewHandler returns a dataselect service over archive.
+func NewHandler(archive Archive) *Handler {
+	return &Handler{archive: archive}
+}
+
+// request is a parsed query and the status to reply when there is no data.
+type request struct {
+	Query
+	nodata int
+}
+
+// ServeHTTP routes the dataselect methods.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case DATASELECT_PATH:
+		h.serveQuery(w, r)
+	case path.Dir(DATASELECT_PATH) + "/version":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, VERSION)
+	case path.Dir(DATASELECT_PATH) + "/application.wadl":
+		w.Header().Set("Content-Type", "application/xml")
+		io.WriteString(w, DATASELECT_WADL)
+	default:
+		writeError(w, r, http.StatusNotFound, "Unknown method "+r.URL.Path)
+	}
+}
+
+// serveQuery parses a query, then streams the records of one selection
+// after the other. Once a record is out, errors can only cut the response.
+func (h *Handler) serveQuery(w http.ResponseWriter, r *http.Request) {
+	var (
+		req *request
+		err error
+	)
+	switch r.Method {
+	case http.MethodGet:
+		req, err = parseParameters(r.URL.Query())
+	case http.MethodPost:
+		req, err = parseBulk(http.MaxBytesReader(w, r.Body, MAX_POST_BODY))
+	default:
+		writeError(w, r, http.StatusMethodNotAllowed, "Use GET or POST")
+		return
+	}
+	var maxBytesError *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesError) || errors.Is(err, errTooLarge):
+		writeError(w, r, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Records go out channel by channel, once the archive moves on to the
+	// next channel or is done with the selection
+	var (
+		written bool
+		channel []*trimmed
+	)
+	flush := func(s *Selection) error {
+		runs := getRuns(channel, s, &req.Query)
+		channel = channel[:0]
+		for _, run := range runs {
+			for _, t := range run {
+				if !written {
+					w.Header().Set("Content-Type", MSEED_CONTENT_TYPE)
+					w.WriteHeader(http.StatusOK)
+					written = true
+				}
+				if _, err := w.Write(t.record); err != nil {
+					return err
+				}
+			}
+		}
+		if f, ok := w.(http.Flusher); ok && written {
+			f.Flush()
+		}
+		return nil
+	}
+	for _, s := range req.Selections {
+		err := h.archive.Records(r.Context(), s, func(series *mseedio.DataSeries) error {
+			if q := req.Quality; q != "" && q != "B" && series.FixedSection.DataQuality != q {
+				return nil
+			}
+			t, err := trimRecord(series, s.StartTime, s.EndTime)
+			if err != nil || t == nil {
+				return err
+			}
+			if len(channel) > 0 && channel[0].key != t.key {
+				if err := flush(&s); err != nil {
+					return err
+				}
+			}
+			channel = append(channel, t)
+			return nil
+		})
+		if err == nil {
+			err = flush(&s)
+		}
+		if err != nil {
+			if !written {
+				writeError(w, r, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+	}
+
+	switch {
+	case written:
+	case req.nodata == http.StatusNotFound:
+		writeError(w, r, http.StatusNotFound, "No data matched the request")
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// trimmed is a record cut to a time window and encoded again.
+type trimmed struct {
+	key       string // NET.STA.LOC.CHA
+	quality   string
+	rate      float64
+	startTime time.Time
+	endTime   time.Time
+	record    []byte
+}
+
+// getRuns groups the trimmed records of a selection into continuous runs per
+// channel, applying the best quality (B, the default), minimum length and
+// longest-only options of the query.
+func getRuns(cut []*trimmed, s *Selection, q *Query) [][]*trimmed {
+	if q.Quality == "" || q.Quality == "B" {
+		cut = getBestQuality(cut)
+	}
+	sort.SliceStable(cut, func(i, j int) bool {
+		if cut[i].key != cut[j].key {
+			return cut[i].key < cut[j].key
+		}
+		return cut[i].startTime.Before(cut[j].startTime)
+	})
+
+	var runs [][]*trimmed
+	for _, t := range cut {
+		if n := len(runs); n > 0 && follows(runs[n-1][len(runs[n-1])-1], t) {
+			runs[n-1] = append(runs[n-1], t)
+			continue
+		}
+		runs = append(runs, []*trimmed{t})
+	}
+
+	window := s.EndTime.Sub(s.StartTime)
+	longest := map[string]int{}
+	kept := runs[:0]
+	for _, run := range runs {
+		length := getRunLength(run)
+		if q.MinimumLength > 0 && length < time.Duration(q.MinimumLength*float64(window)) {
+			continue
+		}
+		if i, ok := longest[run[0].key]; ok && q.LongestOnly {
+			if length > getRunLength(kept[i]) {
+				kept[i] = run
+			}
+			continue
+		}
+		longest[run[0].key] = len(kept)
+		kept = append(kept, run)
+	}
+
+	return kept
+}
+
+// getBestQuality drops the records overlapped by a record of the same
+// channel of better quality, M beating Q beating R beating D as in
+// MiniSeedData.Traces.
+func getBestQuality(cut []*trimmed) []*trimmed {
+	ranked := append([]*trimmed(nil), cut...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].key != ranked[j].key {
+			return ranked[i].key < ranked[j].key
+		}
+		return mseedio.QualityRank(ranked[i].quality) > mseedio.QualityRank(ranked[j].quality)
+	})
+
+	var (
+		kept    []*trimmed
+		covered []*trimmed // Disjoint spans of the better qualities, in time order
+		level   int        // Start in kept of the current channel and quality
+	)
+	for i, t := range ranked {
+		if i > 0 && ranked[i-1].key != t.key {
+			covered, level = covered[:0], len(kept)
+		} else if i > 0 && ranked[i-1].quality != t.quality {
+			covered, level = getCoverage(covered, kept[level:]), len(kept)
+		}
+
+		j := sort.Search(len(covered), func(j int) bool { return !covered[j].endTime.Before(t.startTime) })
+		if j < len(covered) && !covered[j].startTime.After(t.endTime) {
+			continue
+		}
+		kept = append(kept, t)
+	}
+
+	return kept
+}
+
+// getCoverage merges the spans of records into disjoint spans in time order.
+func getCoverage(covered []*trimmed, records []*trimmed) []*trimmed {
+	spans := append([]*trimmed(nil), covered...)
+	spans = append(spans, records...)
+	sort.Slice(spans, func(i, j int) bool { return spans[i].startTime.Before(spans[j].startTime) })
+
+	var merged []*trimmed
+	for _, t := range spans {
+		if n := len(merged); n > 0 && !t.startTime.After(merged[n-1].endTime) {
+			if t.endTime.After(merged[n-1].endTime) {
+				merged[n-1].endTime = t.endTime
+			}
+			continue
+		}
+		merged = append(merged, &trimmed{startTime: t.startTime, endTime: t.endTime})
+	}
+
+	return merged
+}
+
+// follows tells whether next continues prev within half a sample period.
+func follows(prev, next *trimmed) bool {
+	if prev.key != next.key || prev.rate <= 0 || math.Abs(prev.rate-next.rate) > prev.rate*1e-6 {
+		return false
+	}
+
+	period := time.Duration(float64(time.Second) / prev.rate)
+	offset := next.startTime.Sub(prev.endTime.Add(period))
+	return offset >= -period/2 && offset <= period/2
+}
+
+// getRunLength returns the time covered by a run, last sample period included.
+func getRunLength(run []*trimmed) time.Duration {
+	first, last := run[0], run[len(run)-1]
+	length := last.endTime.Sub(first.startTime)
+	if last.rate > 0 {
+		length += time.Duration(float64(time.Second) / last.rate)
+	}
+
+	return length
+}
+
+// trimRecord encodes the samples of a record within [start, end], nil if
+// there are none. Records wholly inside are encoded as they are, as are
+// those whose encoding Append cannot write (ASCII and floats).
+func trimRecord(s *mseedio.DataSeries, start, end time.Time) (*trimmed, error) {
+	f, b := &s.FixedSection, &s.BlocketteSection
+	t := &trimmed{
+		key:       getSelectionKey(f),
+		quality:   f.DataQuality,
+		rate:      f.SampleRate(),
+		startTime: f.StartTime,
+		endTime:   s.EndTime(),
+	}
+	if t.endTime.Before(start) || t.startTime.After(end) {
+		return nil, nil
+	}
+
+	n := int(f.SamplesNumber)
+	first, last := 0, n-1
+	if t.rate > 0 && f.StartTime.Before(start) {
+		first = int(math.Ceil(start.Sub(f.StartTime).Seconds()*t.rate - 1e-6))
+	}
+	if t.rate > 0 && t.endTime.After(end) {
+		last = int(math.Floor(end.Sub(f.StartTime).Seconds()*t.rate + 1e-6))
+	}
+	if first > last {
+		return nil, nil
+	}
+
+	var (
+		whole = first == 0 && last == n-1
+		m     mseedio.MiniSeedData
+		err   error
+	)
+	switch b.EncodingFormat {
+	case mseedio.INT16, mseedio.INT24, mseedio.INT32, mseedio.STEIM1, mseedio.STEIM2:
+	default:
+		whole = true
+	}
+	if !whole {
+		whole, err = appendTrimmed(&m, s, first, last)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if whole {
+		m = mseedio.MiniSeedData{Series: []mseedio.DataSeries{*s}}
+	} else {
+		t.startTime = f.StartTime.Add(time.Duration(float64(first) / t.rate * float64(time.Second)))
+		t.endTime = f.StartTime.Add(time.Duration(float64(last) / t.rate * float64(time.Second)))
+	}
+
+	t.record, err = m.Encode(mseedio.OVERWRITE, int(b.BitOrder))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// appendTrimmed appends samples first to last of a record to m, with the
+// header of the record. It returns true instead if the samples cannot be
+// decoded, for the record to be sent whole.
+func appendTrimmed(m *mseedio.MiniSeedData, s *mseedio.DataSeries, first, last int) (bool, error) {
+	ds := s.DataSection
+	if err := ds.Decode(); err != nil || len(ds.Decoded) <= last {
+		return true, nil
+	}
+
+	samples := make([]int32, 0, last-first+1)
+	for _, v := range ds.Decoded[first : last+1] {
+		sample, ok := v.(int32)
+		if !ok {
+			return true, nil
+		}
+		samples = append(samples, sample)
+	}
+
+	f, b := &s.FixedSection, &s.BlocketteSection
+	rate := f.SampleRate()
+	if err := m.Init(int(b.EncodingFormat), int(b.BitOrder)); err != nil {
+		return false, err
+	}
+	err := m.Append(samples, &mseedio.AppendOptions{
+		SampleRate:       rate,
+		DataQuality:      f.DataQuality,
+		SequenceNumber:   f.SequenceNumber,
+		NetworkCode:      strings.TrimSpace(f.NetworkCode),
+		StationCode:      strings.TrimSpace(f.StationCode),
+		LocationCode:     strings.TrimSpace(f.LocationCode),
+		ChannelCode:      strings.TrimSpace(f.ChannelCode),
+		StartTime:        f.StartTime.Add(time.Duration(float64(first) / rate * float64(time.Second))),
+		ActivityFlags:    f.ActivityFlags,
+		IOClockFlags:     f.IOClockFlags,
+		DataQualityFlags: f.DataQualityFlags,
+		RecordLength:     1 << b.RecordLength,
+	})
+	return false, err
+}
+
+// getSelectionKey returns the NET.STA.LOC.CHA key of a record.
+func getSelectionKey(f *mseedio.FixedSection) string {
+	return strings.Join([]string{
+		strings.TrimSpace(f.NetworkCode),
+		strings.TrimSpace(f.StationCode),
+		strings.TrimSpace(f.LocationCode),
+		strings.TrimSpace(f.ChannelCode),
+	}, ".")
+}
+
+// parseParameters parses the GET parameters of a query. Codes may be
+// comma-separated lists, each combination making one selection, up to
+// MAX_SELECTIONS of them.
+func parseParameters(values url.Values) (*request, error) {
+	req := &request{nodata: http.StatusNoContent}
+	codes := map[string][]string{}
+	var start, end time.Time
+	for key, vals := range values {
+		value := vals[len(vals)-1]
+		var err error
+		switch key {
+		case "network", "net", "station", "sta", "location", "loc", "channel", "cha":
+			codes[key[:3]] = append(codes[key[:3]], strings.Split(value, ",")...)
+		case "starttime", "start":
+			start, err = parseTime(value)
+		case "endtime", "end":
+			end, err = parseTime(value)
+		default:
+			err = req.setOption(key, value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if start.IsZero() || end.IsZero() {
+		return nil, fmt.Errorf("starttime and endtime are required")
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("starttime must be before endtime")
+	}
+
+	count := 1
+	for _, key := range []string{"net", "sta", "loc", "cha"} {
+		if len(codes[key]) == 0 {
+			codes[key] = []string{"*"}
+		}
+		if count *= len(codes[key]); count > MAX_SELECTIONS {
+			return nil, fmt.Errorf("%w: code lists combine into more than %d selections", errTooLarge, MAX_SELECTIONS)
+		}
+	}
+	for _, network := range codes["net"] {
+		for _, station := range codes["sta"] {
+			for _, location := range codes["loc"] {
+				for _, channel := range codes["cha"] {
+					s, err := getSelection([]string{network, station, location, channel}, start, end)
+					if err != nil {
+						return nil, err
+					}
+					req.Selections = append(req.Selections, s)
+				}
+			}
+		}
+	}
+	return req, nil
+}
+
+// parseBulk parses a POST body: key=value options, then one
+// "NET STA LOC CHA START END" line per selection.
+func parseBulk(body io.Reader) (*request, error) {
+	req := &request{nodata: http.StatusNoContent}
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			if len(req.Selections) > 0 {
+				return nil, fmt.Errorf("option %q after selections", key)
+			}
+			if err := req.setOption(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if len(req.Selections) == MAX_SELECTIONS {
+			return nil, fmt.Errorf("%w: more than %d selections", errTooLarge, MAX_SELECTIONS)
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("expected NET STA LOC CHA START END, got %q", line)
+		}
+		start, err := parseTime(fields[4])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTime(fields[5])
+		if err != nil {
+			return nil, err
+		}
+		if !start.Before(end) {
+			return nil, fmt.Errorf("start must be before end in %q", line)
+		}
+		s, err := getSelection(fields[:4], start, end)
+		if err != nil {
+			return nil, err
+		}
+		req.Selections = append(req.Selections, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(req.Selections) == 0 {
+		return nil, fmt.Errorf("no selection in request")
+	}
+
+	return req, nil
+}
+
+// setOption sets a parameter other than codes and times.
+func (req *request) setOption(key, value string) error {
+	switch key {
+	case "quality":
+		if !strings.Contains("DRQMB", value) || len(value) != 1 {
+			return fmt.Errorf("invalid quality %q", value)
+		}
+		req.Quality = value
+	case "minimumlength":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil || v < 0 || v > 1 {
+			return fmt.Errorf("invalid minimumlength %q", value)
+		}
+		req.MinimumLength = v
+	case "longestonly":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid longestonly %q", value)
+		}
+		req.LongestOnly = v
+	case "nodata":
+		if value != "204" && value != "404" {
+			return fmt.Errorf("invalid nodata %q", value)
+		}
+		req.nodata, _ = strconv.Atoi(value)
+	case "format":
+		if value != "miniseed" {
+			return fmt.Errorf("unsupported format %q", value)
+		}
+	default:
+		return fmt.Errorf("unknown parameter %q", key)
+	}
+
+	return nil
+}
+
+// getSelection checks the codes of a selection, defaulting empty ones to *,
+// and its window, up to MAX_WINDOW long. Codes hold letters, digits and
+// wildcards only, so that they are safe in archive paths.
+func getSelection(codes []string, start, end time.Time) (Selection, error) {
+	if end.Sub(start) > MAX_WINDOW {
+		return Selection{}, fmt.Errorf("%w: time window longer than %s", errTooLarge, MAX_WINDOW)
+	}
+	for i := range codes {
+		if codes[i] == "" {
+			codes[i] = "*"
+		}
+		for _, c := range codes[i] {
+			if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.ContainsRune("*?-", c)) {
+				return Selection{}, fmt.Errorf("invalid code %q", codes[i])
+			}
+		}
+	}
+
+	return Selection{
+		Network: codes[0], Station: codes[1], Location: codes[2], Channel: codes[3],
+		StartTime: start, EndTime: end,
+	}, nil
+}
+
+// parseTime parses a time of a request, UTC with or without fractional
+// seconds or a trailing Z, or a date alone.
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSuffix(value, "Z")
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// writeError sends an error document in the format of the specification.
+func writeError(w http.ResponseWriter, r *http.Request, status int, details string) {
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "Error %d: %s\n\n%s\n\nUsage details are available from %s\n\n"+
+		"Request:\n%s\n\nRequest Submitted:\n%s\n\nService version:\n%s\n",
+		status, http.StatusText(status), details, path.Dir(DATASELECT_PATH)+"/application.wadl",
+		r.URL.String(), time.Now().UTC().Format(time.RFC3339), VERSION)
+}
+
+// DATASELECT_WADL describes the query method and its parameters.
+const DATASELECT_WADL = `<?xml version="1.0" encoding="UTF-8"?>
+<application xmlns="http://wadl.dev.java.net/2009/02" xmlns:xsd="http://www.w3.org/2001/XMLSchema">
+  <resources base="/fdsnws/dataselect/1/">
+    <resource path="query">
+      <method name="GET">
+        <request>
+          <param name="starttime" style="query" type="xsd:dateTime" required="true"/>
+          <param name="endtime" style="query" type="xsd:dateTime" required="true"/>
+          <param name="network" style="query" type="xsd:string"/>
+          <param name="station" style="query" type="xsd:string"/>
+          <param name="location" style="query" type="xsd:string"/>
+          <param name="channel" style="query" type="xsd:string"/>
+          <param name="quality" style="query" type="xsd:string" default="B"/>
+          <param name="minimumlength" style="query" type="xsd:double" default="0.0"/>
+          <param name="longestonly" style="query" type="xsd:boolean" default="false"/>
+          <param name="format" style="query" type="xsd:string" default="miniseed"/>
+          <param name="nodata" style="query" type="xsd:int" default="204"/>
+        </request>
+        <response status="200">
+          <representation mediaType="application/vnd.fdsn.mseed"/>
+        </response>
+        <response status="204 400 404 413 500"/>
+      </method>
+      <method name="POST">
+        <request>
+          <representation mediaType="text/plain"/>
+        </request>
+        <response status="200">
+          <representation mediaType="application/vnd.fdsn.mseed"/>
+        </response>
+        <response status="204 400 404 413 500"/>
+      </method>
+    </resource>
+    <resource path="version">
+      <method name="GET">
+        <response>
+          <representation mediaType="text/plain"/>
+        </response>
+      </method>
+    </resource>
+    <resource path="application.wadl">
+      <method name="GET">
+        <response>
+          <representation mediaType="application/xml"/>
+        </response>
+      </method>
+    </resource>
+  </resources>
+</application>
+`
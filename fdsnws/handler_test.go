@@ -0,0 +1,274 @@
+package fdsnws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// writeSDSDay writes the given 10-second records (100 samples at 10 Hz, the
+// samples counting up from 100 times the record index) of a channel on
+// 2024-01-01 to an SDS tree.
+func writeSDSDay(t *testing.T, root, channel string, encoding int, indices ...int) {
+	var m mseedio.MiniSeedData
+	_ = m.Init(encoding, mseedio.MSBFIRST)
+	for _, i := range indices {
+		samples := make([]int32, 100)
+		for j := range samples {
+			samples[j] = int32(i*100 + j)
+		}
+		err := m.Append(samples, &mseedio.AppendOptions{
+			SampleRate: 10, StartTime: time.Date(2024, 1, 1, 0, 0, 10*i, 0, time.UTC),
+			RecordLength: 512, SequenceNumber: fmt.Sprintf("%06d", i+1),
+			NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: channel,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(root, "2024", "IU", "ANMO", channel+".D")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "IU.ANMO.00."+channel+".D.2024.001"), records, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestHandler serves an SDS archive holding BHZ records 0 to 9, and BHN
+// records 0 to 2 and 5 to 9, the latter a 50-second run after a gap.
+func newTestHandler(t *testing.T) *httptest.Server {
+	root := t.TempDir()
+	writeSDSDay(t, root, "BHZ", mseedio.STEIM2, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	writeSDSDay(t, root, "BHN", mseedio.INT32, 0, 1, 2, 5, 6, 7, 8, 9)
+
+	s := httptest.NewServer(NewHandler(&SDSArchive{Root: root}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// getTraceSummaries describes the traces of a response as
+// "CHA:first-last/count" each.
+func getTraceSummaries(t *testing.T, r *Reader) string {
+	defer r.Close()
+	m, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var summaries []string
+	for _, trace := range m.Traces() {
+		summaries = append(summaries, fmt.Sprintf("%s:%v-%v/%d", trace.ChannelCode,
+			trace.Samples[0], trace.Samples[len(trace.Samples)-1], len(trace.Samples)))
+	}
+	return strings.Join(summaries, " ")
+}
+
+func TestHandlerQuery(t *testing.T) {
+	s := newTestHandler(t)
+	c := NewClient(s.URL)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		q    Query
+		want string
+	}{
+		{"trimmed Steim-2", Query{Selections: []Selection{{
+			Network: "IU", Station: "ANMO", Location: "00", Channel: "BHZ",
+			StartTime: day.Add(15 * time.Second), EndTime: day.Add(35 * time.Second),
+		}}}, "BHZ:150-350/201"},
+		{"wildcards", Query{Selections: []Selection{{
+			Network: "IU", Station: "AN*", Channel: "BH?",
+			StartTime: day.Add(25 * time.Second), EndTime: day.Add(55 * time.Second),
+		}}}, "BHN:250-299/50 BHN:500-550/51 BHZ:250-550/301"},
+		{"bulk", Query{Selections: []Selection{
+			{Network: "IU", Station: "ANMO", Location: "00", Channel: "BHZ", StartTime: day, EndTime: day.Add(5 * time.Second)},
+			{Network: "IU", Station: "ANMO", Location: "00", Channel: "BHN", StartTime: day.Add(90 * time.Second), EndTime: day.Add(time.Hour)},
+		}}, "BHN:900-999/100 BHZ:0-50/51"},
+		{"longestonly", Query{Selections: []Selection{{
+			Channel: "BHN", StartTime: day, EndTime: day.Add(time.Hour),
+		}}, LongestOnly: true}, "BHN:500-999/500"},
+		{"minimumlength", Query{Selections: []Selection{{
+			Channel: "BH?", StartTime: day, EndTime: day.Add(100 * time.Second),
+		}}, MinimumLength: 0.45}, "BHN:500-999/500 BHZ:0-999/1000"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := c.Dataselect(context.Background(), &test.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := getTraceSummaries(t, r); got != test.want {
+				t.Fatalf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestHandlerNoData(t *testing.T) {
+	s := newTestHandler(t)
+	c := NewClient(s.URL)
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, q := range []Query{
+		{Selections: []Selection{{Network: "XX", StartTime: day, EndTime: day.Add(time.Hour)}}},
+		{Selections: []Selection{{Channel: "BHZ", StartTime: day.Add(2 * time.Hour), EndTime: day.Add(3 * time.Hour)}}},
+		{Selections: []Selection{{Channel: "BHZ", StartTime: day, EndTime: day.Add(time.Hour)}}, Quality: "M"},
+	} {
+		if _, err := c.Dataselect(context.Background(), &q); !errors.Is(err, ErrNoData) {
+			t.Errorf("want ErrNoData for %+v, got %v", q, err)
+		}
+	}
+
+	resp, err := http.Get(s.URL + DATASELECT_PATH + "?net=XX&start=2024-01-01&end=2024-01-02&nodata=404")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("want 404 with nodata=404, got %d", resp.StatusCode)
+	}
+}
+
+// getCodeList returns a comma-separated list of n codes.
+func getCodeList(n int) string {
+	codes := make([]string, n)
+	for i := range codes {
+		codes[i] = fmt.Sprintf("S%d", i)
+	}
+	return strings.Join(codes, ",")
+}
+
+func TestHandlerMethods(t *testing.T) {
+	s := newTestHandler(t)
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		want               string
+	}{
+		{"GET", "/fdsnws/dataselect/1/version", "", 200, VERSION},
+		{"GET", "/fdsnws/dataselect/1/application.wadl", "", 200, `<resource path="query">`},
+		{"GET", "/fdsnws/dataselect/1/catalog", "", 404, "Error 404: Not Found"},
+		{"PUT", DATASELECT_PATH, "", 405, "Error 405"},
+		{"GET", DATASELECT_PATH + "?net=IU", "", 400, "starttime and endtime are required"},
+		{"GET", DATASELECT_PATH + "?net=IU&start=2024-01-02&end=2024-01-01", "", 400, "starttime must be before endtime"},
+		{"GET", DATASELECT_PATH + "?net=IU&start=2024-01-01&end=2024-01-02&quality=X", "", 400, `invalid quality "X"`},
+		{"GET", DATASELECT_PATH + "?net=..&start=2024-01-01&end=2024-01-02", "", 400, `invalid code ".."`},
+		{"GET", DATASELECT_PATH + "?net=IU&start=2024-01-01&end=2024-01-02&format=sac", "", 400, `unsupported format "sac"`},
+		{"GET", DATASELECT_PATH + "?network=IU&start=2024-01-01&end=2024-01-02&bogus=1", "", 400, `unknown parameter "bogus"`},
+		{"POST", DATASELECT_PATH, "IU ANMO 00 BHZ 2024-01-01T00:00:00\n", 400, "expected NET STA LOC CHA START END"},
+		{"POST", DATASELECT_PATH, "IU ANMO 00 BHZ 2024-01-01 2024-01-02\nquality=M\n", 400, `option "quality" after selections`},
+		{"POST", DATASELECT_PATH, "quality=D\n", 400, "no selection in request"},
+		{"POST", DATASELECT_PATH, strings.Repeat("\n", MAX_POST_BODY+1), 413, "Error 413"},
+		{"POST", DATASELECT_PATH, strings.Repeat("IU ANMO 00 BHZ 2024-01-01 2024-01-02\n", MAX_SELECTIONS+1), 413, "more than 1000 selections"},
+		{"GET", DATASELECT_PATH + "?sta=" + getCodeList(40) + "&cha=" + getCodeList(40) + "&start=2024-01-01&end=2024-01-02", "", 413, "more than 1000 selections"},
+		{"GET", DATASELECT_PATH + "?net=IU&start=2024-01-01&end=2024-02-01", "", 413, "time window longer than 168h0m0s"},
+		{"POST", DATASELECT_PATH, "IU ANMO 00 BHZ 2024-01-01 2024-01-09\n", 413, "time window longer than"},
+		{"GET", DATASELECT_PATH + "?net=" + getCodeList(500) + "&sta=" + getCodeList(500) + "&loc=" + getCodeList(500) + "&cha=" + getCodeList(500) + "&start=2024-01-01&end=2024-01-02", "", 413, "Error 413"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(test.method, s.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status || !strings.Contains(string(body), test.want) {
+			t.Errorf("%s %s: want %d with %q, got %d with %q", test.method, test.path, test.status, test.want, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestSDSArchive(t *testing.T) {
+	root := t.TempDir()
+	writeSDSDay(t, root, "BHZ", mseedio.STEIM2, 0, 1, 2)
+	writeSDSDay(t, root, "BHN", mseedio.INT32, 0, 1)
+	writeSDSDay(t, root, "BHE", mseedio.INT32, 2)
+	a := &SDSArchive{Root: root}
+
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := Selection{Network: "IU", Station: "*", Location: "00", Channel: "BH?", StartTime: day, EndTime: day.Add(time.Hour)}
+	var got []string
+	err := a.Records(context.Background(), s, func(series *mseedio.DataSeries) error {
+		got = append(got, series.FixedSection.ChannelCode+series.FixedSection.SequenceNumber)
+		return nil
+	})
+	if want := "BHE000003 BHN000001 BHN000002 BHZ000001 BHZ000002 BHZ000003"; err != nil || strings.Join(got, " ") != want {
+		t.Fatalf("want channel after channel %q, got %q: %v", want, got, err)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	if err := a.Records(context.Background(), s, func(*mseedio.DataSeries) error { calls++; return stop }); err != stop || calls != 1 {
+		t.Fatalf("want the error of fn after 1 call, got %v after %d", err, calls)
+	}
+
+	s.EndTime = day.Add(MAX_WINDOW + time.Second)
+	if err := a.Records(context.Background(), s, func(*mseedio.DataSeries) error { return nil }); !errors.Is(err, errTooLarge) {
+		t.Fatalf("want errTooLarge for a long window, got %v", err)
+	}
+}
+
+func TestBestQuality(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(key, quality string, from, to int) *trimmed {
+		return &trimmed{
+			key: key, quality: quality, rate: 10,
+			startTime: day.Add(time.Duration(from) * time.Second),
+			endTime:   day.Add(time.Duration(to)*time.Second - 100*time.Millisecond),
+		}
+	}
+
+	// D records every 10 s, a Q record overlapped by an M one, and another
+	// channel left alone
+	var cut []*trimmed
+	for i := 0; i < 6; i++ {
+		cut = append(cut, record("IU.ANMO.00.BHZ", "D", 10*i, 10*i+10))
+	}
+	cut = append(cut,
+		record("IU.ANMO.00.BHZ", "Q", 20, 35),
+		record("IU.ANMO.00.BHZ", "M", 30, 40),
+		record("IU.ANMO.10.BHZ", "D", 30, 40),
+	)
+
+	var got []string
+	for _, t := range getBestQuality(cut) {
+		got = append(got, fmt.Sprintf("%s:%s@%d", t.key[len(t.key)-6:], t.quality, t.startTime.Second()))
+	}
+	if want := "00.BHZ:M@30 00.BHZ:D@0 00.BHZ:D@10 00.BHZ:D@20 00.BHZ:D@40 00.BHZ:D@50 10.BHZ:D@30"; strings.Join(got, " ") != want {
+		t.Fatalf("want %q, got %q", want, strings.Join(got, " "))
+	}
+
+	// Other qualities keep overlapped records
+	for quality, want := range map[string]int{"": 7, "B": 7, "D": 9} {
+		n := 0
+		for _, run := range getRuns(cut, &Selection{StartTime: day, EndTime: day.Add(time.Minute)}, &Query{Quality: quality}) {
+			n += len(run)
+		}
+		if n != want {
+			t.Errorf("quality %q: want %d records, got %d", quality, want, n)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package fdsnws
+
+import "net/http"
+
+// Option configures NewClient.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through h rather than http.DefaultClient,
+// e.g. for timeouts or proxies.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = h
+	}
+}
+
+// WithUserAgent sets the User-Agent header of requests.
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
@@ -0,0 +1,146 @@
+package fdsnws
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	MIN_RECORD_LENGTH = 128   // 2^7, smallest record length of blockette 1000
+	MAX_RECORD_LENGTH = 65536 // 2^16, largest record length accepted
+)
+
+// Reader streams the miniSEED records of a response one at a time, so that
+// large requests need not be held in memory.
+type Reader struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	options []mseedio.ReadOption
+}
+
+// newReader wraps a response body.
+func newReader(body io.ReadCloser, options []mseedio.ReadOption) *Reader {
+	return &Reader{
+		body:    body,
+		reader:  bufio.NewReaderSize(body, MAX_RECORD_LENGTH),
+		options: options,
+	}
+}
+
+// Next returns the next record, or io.EOF after the last one. Record lengths
+// are taken from blockette 1000, which data centers always send.
+func (r *Reader) Next() (*mseedio.DataSeries, error) {
+	record, err := r.NextRecord()
+	if err != nil {
+		return nil, err
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(record), r.options...); err != nil {
+		return nil, err
+	}
+	if len(m.Series) != 1 {
+		return nil, fmt.Errorf("expected one record, got %d", len(m.Series))
+	}
+	return &m.Series[0], nil
+}
+
+// NextRecord returns the next record as sent, or io.EOF after the last one.
+func (r *Reader) NextRecord() ([]byte, error) {
+	if _, err := r.reader.Peek(1); err != nil {
+		return nil, err
+	}
+
+	length, err := r.getRecordLength()
+	if err != nil {
+		return nil, err
+	}
+	record := make([]byte, length)
+	if _, err := io.ReadFull(r.reader, record); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ReadAll reads the remaining records into a MiniSeedData, filling in its
+// summary fields as MiniSeedData.ReadFromReader does. Records are read one
+// by one, so they may differ in length and byte order.
+func (r *Reader) ReadAll() (*mseedio.MiniSeedData, error) {
+	var m mseedio.MiniSeedData
+	for {
+		series, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if m.Records == 0 {
+			m.Order = int(series.BlocketteSection.BitOrder)
+			m.Type = int(series.BlocketteSection.BlocketteCode)
+			m.StartTime = series.FixedSection.StartTime
+		}
+		m.Series = append(m.Series, *series)
+		m.Records++
+		m.Samples += int(series.FixedSection.SamplesNumber)
+		m.EndTime = series.FixedSection.StartTime
+	}
+	if m.Records == 0 {
+		return nil, fmt.Errorf("no record in response")
+	}
+
+	return &m, nil
+}
+
+// Close closes the response body.
+func (r *Reader) Close() error {
+	return r.body.Close()
+}
+
+// getRecordLength walks the blockettes of the next record, without consuming
+// it, up to blockette 1000.
+func (r *Reader) getRecordLength() (int, error) {
+	header, err := r.reader.Peek(48)
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	// The year of the start time tells the byte order apart
+	var order binary.ByteOrder = binary.BigEndian
+	if year := order.Uint16(header[20:]); year < 1900 || year > 2500 {
+		order = binary.LittleEndian
+	}
+
+	offset := int(order.Uint16(header[46:]))
+	for offset >= 48 && offset+7 <= MAX_RECORD_LENGTH {
+		blockette, err := r.reader.Peek(offset + 7)
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		typ, next := order.Uint16(blockette[offset:]), int(order.Uint16(blockette[offset+2:]))
+		if typ == 1000 {
+			exponent := blockette[offset+6]
+			if exponent < 7 || exponent > 16 {
+				return 0, fmt.Errorf("record length 2^%d out of range", exponent)
+			}
+			return 1 << exponent, nil
+		}
+		if next <= offset {
+			break
+		}
+		offset = next
+	}
+
+	return 0, fmt.Errorf("record without blockette 1000")
+}
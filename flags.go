@@ -0,0 +1,94 @@
+package mseedio
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ActivityFlags is the activity flags field of the fixed section
+type ActivityFlags uint8
+
+// IOClockFlags is the I/O and clock flags field of the fixed section
+type IOClockFlags uint8
+
+// DataQualityFlags is the data quality flags field of the fixed section
+type DataQualityFlags uint8
+
+// Activity flags
+const (
+	ACTIVITY_CALIBRATION       ActivityFlags = 1 << 0 // Calibration signals present
+	ACTIVITY_TIME_CORRECTED    ActivityFlags = 1 << 1 // Time correction applied
+	ACTIVITY_EVENT_BEGIN       ActivityFlags = 1 << 2 // Beginning of an event, station trigger
+	ACTIVITY_EVENT_END         ActivityFlags = 1 << 3 // End of the event, station detriggers
+	ACTIVITY_LEAP_POSITIVE     ActivityFlags = 1 << 4 // A positive leap second happened during this record
+	ACTIVITY_LEAP_NEGATIVE     ActivityFlags = 1 << 5 // A negative leap second happened during this record
+	ACTIVITY_EVENT_IN_PROGRESS ActivityFlags = 1 << 6 // Event in progress
+)
+
+// I/O and clock flags
+const (
+	IOCLOCK_PARITY_ERROR IOClockFlags = 1 << 0 // Station volume parity error possibly present
+	IOCLOCK_LONG_RECORD  IOClockFlags = 1 << 1 // Long record read (possibly no problem)
+	IOCLOCK_SHORT_RECORD IOClockFlags = 1 << 2 // Short record read (record padded)
+	IOCLOCK_SERIES_START IOClockFlags = 1 << 3 // Start of time series
+	IOCLOCK_SERIES_END   IOClockFlags = 1 << 4 // End of time series
+	IOCLOCK_CLOCK_LOCKED IOClockFlags = 1 << 5 // Clock locked
+)
+
+// Data quality flags
+const (
+	QUALITY_AMPLIFIER_SATURATION DataQualityFlags = 1 << 0 // Amplifier saturation detected
+	QUALITY_DIGITIZER_CLIPPING   DataQualityFlags = 1 << 1 // Digitizer clipping detected
+	QUALITY_SPIKES               DataQualityFlags = 1 << 2 // Spikes detected
+	QUALITY_GLITCHES             DataQualityFlags = 1 << 3 // Glitches detected
+	QUALITY_MISSING_DATA         DataQualityFlags = 1 << 4 // Missing/padded data present
+	QUALITY_TELEMETRY_SYNC_ERROR DataQualityFlags = 1 << 5 // Telemetry synchronization error
+	QUALITY_FILTER_CHARGING      DataQualityFlags = 1 << 6 // A digital filter may be charging
+	QUALITY_QUESTIONABLE_TIME    DataQualityFlags = 1 << 7 // Time tag is questionable
+)
+
+var (
+	activityFlagNames = []string{
+		"CALIBRATION", "TIME_CORRECTED", "EVENT_BEGIN", "EVENT_END",
+		"LEAP_POSITIVE", "LEAP_NEGATIVE", "EVENT_IN_PROGRESS",
+	}
+	ioClockFlagNames = []string{
+		"PARITY_ERROR", "LONG_RECORD", "SHORT_RECORD",
+		"SERIES_START", "SERIES_END", "CLOCK_LOCKED",
+	}
+	dataQualityFlagNames = []string{
+		"AMPLIFIER_SATURATION", "DIGITIZER_CLIPPING", "SPIKES", "GLITCHES",
+		"MISSING_DATA", "TELEMETRY_SYNC_ERROR", "FILTER_CHARGING", "QUESTIONABLE_TIME",
+	}
+)
+
+// String lists the set activity flags, e.g. "CALIBRATION|EVENT_BEGIN".
+func (f ActivityFlags) String() string { return formatFlags(uint8(f), activityFlagNames) }
+
+// String lists the set I/O and clock flags, e.g. "SERIES_START|CLOCK_LOCKED".
+func (f IOClockFlags) String() string { return formatFlags(uint8(f), ioClockFlagNames) }
+
+// String lists the set data quality flags, e.g. "GLITCHES|MISSING_DATA".
+func (f DataQualityFlags) String() string { return formatFlags(uint8(f), dataQualityFlagNames) }
+
+// formatFlags joins the names of the set bits with "|", falling back to a hex
+// value for bits without a name and "NONE" when no bit is set.
+func formatFlags(flags uint8, names []string) string {
+	if flags == 0 {
+		return "NONE"
+	}
+
+	var set []string
+	for i := 0; i < 8; i++ {
+		if flags&(1<<i) == 0 {
+			continue
+		}
+		if i < len(names) {
+			set = append(set, names[i])
+		} else {
+			set = append(set, fmt.Sprintf("0x%02x", 1<<i))
+		}
+	}
+
+	return strings.Join(set, "|")
+}
@@ -0,0 +1,45 @@
+package mseedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestFlagsRoundTrip sets every flag field through AppendOptions, including
+// the high bit that a signed decode would turn negative, and reads them back.
+func TestFlagsRoundTrip(t *testing.T) {
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	err := m.Append([]int32{1, 2, 3}, &AppendOptions{
+		SampleRate: 1, StartTime: time.Unix(0, 0).UTC(), SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		ActivityFlags:    ACTIVITY_CALIBRATION | ACTIVITY_LEAP_POSITIVE,
+		IOClockFlags:     IOCLOCK_CLOCK_LOCKED,
+		DataQualityFlags: QUALITY_GLITCHES | QUALITY_QUESTIONABLE_TIME,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataBytes, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got MiniSeedData
+	if err := got.ReadFromReader(bytes.NewReader(dataBytes)); err != nil {
+		t.Fatal(err)
+	}
+	fs := got.Series[0].FixedSection
+	for _, c := range []struct{ got, want string }{
+		{fs.ActivityFlags.String(), "CALIBRATION|LEAP_POSITIVE"},
+		{fs.IOClockFlags.String(), "CLOCK_LOCKED"},
+		{fs.DataQualityFlags.String(), "GLITCHES|QUESTIONABLE_TIME"},
+		{ActivityFlags(0).String(), "NONE"},
+		{ActivityFlags(0x80).String(), "0x80"},
+	} {
+		if c.got != c.want {
+			t.Errorf("want %q, got %q", c.want, c.got)
+		}
+	}
+}
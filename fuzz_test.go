@@ -0,0 +1,237 @@
+package mseedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// addFuzzFixtures seeds f with every fixture record.
+func addFuzzFixtures(f *testing.F) {
+	files, _ := filepath.Glob("example/reader/testdata/*.mseed")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+// FuzzReadFromReader feeds arbitrary bytes to the record parser, which may
+// reject them but must not panic.
+func FuzzReadFromReader(f *testing.F) {
+	addFuzzFixtures(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var m MiniSeedData
+		if err := m.ReadFromReader(bytes.NewReader(data), WithIntegrity(INTEGRITY_WARN)); err != nil {
+			return
+		}
+		m.Traces()
+		m.Gaps()
+	})
+}
+
+// FuzzDecodeSteim feeds arbitrary frames to both Steim decoders.
+func FuzzDecodeSteim(f *testing.F) {
+	for _, pack := range []func([]int32, int) ([]byte, error){packSteim1, packSteim2} {
+		packed, _ := pack(getSteimTestSamples(100, 1), MSBFIRST)
+		f.Add(packed, 100)
+	}
+	f.Add([]byte{}, 1)
+	f.Add(make([]byte, 8), 3)
+	f.Fuzz(func(t *testing.T, data []byte, samples int) {
+		if samples < -1 || samples > 1<<16 {
+			return
+		}
+		_, _ = unpackSteim1(data, samples, MSBFIRST)
+		_, _ = unpackSteim2(data, samples, MSBFIRST)
+	})
+}
+
+// FuzzEncodeSteim checks that arbitrary samples round trip through both
+// Steim encoders whatever the frame budget.
+func FuzzEncodeSteim(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0x7f, 0xff, 0xff, 0xff, 0x80, 0, 0, 0}, 0)
+	f.Add(make([]byte, 400), 1)
+	f.Fuzz(func(t *testing.T, data []byte, frames int) {
+		samples := make([]int32, len(data)/4)
+		for i := range samples {
+			samples[i] = int32(binary.BigEndian.Uint32(data[i*4:]))
+		}
+		for _, c := range []struct {
+			encode func([]int32, int) ([]byte, int, error)
+			decode func([]int32, []byte) (int, error)
+		}{{EncodeSteim1, DecodeSteim1Into}, {EncodeSteim2, DecodeSteim2Into}} {
+			packed, n, err := c.encode(samples, frames)
+			if err != nil || n == 0 {
+				continue // No samples, or Steim-2 differences beyond 30 bits
+			}
+			dst := make([]int32, n)
+			if got, err := c.decode(dst, packed); err != nil || got != n {
+				t.Fatalf("decoded %d of %d samples: %v", got, n, err)
+			}
+			for i := range dst {
+				if dst[i] != samples[i] {
+					t.Fatalf("sample %d: want %d, got %d", i, samples[i], dst[i])
+				}
+			}
+		}
+	})
+}
+
+// FuzzAssembleBTime decodes arbitrary BTIME structures.
+func FuzzAssembleBTime(f *testing.F) {
+	f.Add([]byte{0x07, 0xe8, 0x00, 0x3c, 23, 59, 60, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) < 10 {
+			return
+		}
+		for _, order := range []int{MSBFIRST, LSBFIRST} {
+			assembleTime(data, order)
+		}
+	})
+}
+
+// getRoundTripSamples returns samples that every encoding represents
+// exactly: printable characters for ASCII and the type range otherwise.
+func getRoundTripSamples(encoding int, n int, r *rand.Rand) []int32 {
+	samples := make([]int32, n)
+	for i := range samples {
+		switch encoding {
+		case ASCII:
+			samples[i] = int32(' ' + r.Intn(95))
+		case INT16:
+			samples[i] = int32(r.Intn(1<<16) - 1<<15)
+		case INT24, FLOAT32:
+			samples[i] = int32(r.Intn(1<<24) - 1<<23)
+		case STEIM2:
+			samples[i] = int32(r.Intn(1<<29) - 1<<28)
+		default:
+			samples[i] = int32(r.Uint32())
+		}
+	}
+	return samples
+}
+
+// TestRoundTripEncodings checks that random samples survive Append, Encode
+// and Read for every encoding and byte order it supports.
+func TestRoundTripEncodings(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, encoding := range []int{ASCII, INT16, INT24, INT32, FLOAT32, FLOAT64, STEIM1, STEIM2} {
+		for _, order := range []int{MSBFIRST, LSBFIRST} {
+			if order == LSBFIRST && (encoding == STEIM1 || encoding == STEIM2) {
+				continue
+			}
+			for i := 0; i < 20; i++ {
+				samples := getRoundTripSamples(encoding, 1+r.Intn(2000), r)
+
+				var m MiniSeedData
+				_ = m.Init(encoding, order)
+				err := m.Append(samples, &AppendOptions{
+					SampleRate: 100, StartTime: time.Unix(r.Int63n(1<<31), 0).UTC(), SequenceNumber: "000001",
+					StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+					RecordLength: 512,
+				})
+				if err != nil {
+					t.Fatalf("encoding %d: %v", encoding, err)
+				}
+				encoded, err := m.Encode(OVERWRITE, order)
+				if err != nil {
+					t.Fatalf("encoding %d: %v", encoding, err)
+				}
+
+				var got MiniSeedData
+				if err := got.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+					t.Fatalf("encoding %d order %d: %v", encoding, order, err)
+				}
+				decoded := getRoundTripDecoded(&got)
+				if len(decoded) != len(samples) {
+					t.Fatalf("encoding %d order %d: want %d samples, got %d", encoding, order, len(samples), len(decoded))
+				}
+				for j := range samples {
+					if decoded[j] != samples[j] {
+						t.Fatalf("encoding %d order %d sample %d: want %d, got %d", encoding, order, j, samples[j], decoded[j])
+					}
+				}
+			}
+		}
+	}
+}
+
+// getRoundTripDecoded flattens the samples of every record to int32.
+func getRoundTripDecoded(m *MiniSeedData) []int32 {
+	var res []int32
+	for _, s := range m.Series {
+		for _, v := range appendSamples(nil, s.DataSection.Decoded) {
+			switch v := v.(type) {
+			case int32:
+				res = append(res, v)
+			case float64:
+				res = append(res, int32(v))
+			case string:
+				for _, c := range []byte(v) {
+					res = append(res, int32(c))
+				}
+			}
+		}
+	}
+	return res
+}
+
+// getBenchmarkRecords encodes 100 512-byte Steim-2 records.
+func getBenchmarkRecords(b *testing.B) []byte {
+	var m MiniSeedData
+	_ = m.Init(STEIM2, MSBFIRST)
+	err := m.Append(getSteimTestSamples(20000, 3), &AppendOptions{
+		SampleRate: 100, StartTime: time.Unix(0, 0).UTC(), SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		RecordLength: 512,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	encoded, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return encoded
+}
+
+func BenchmarkReadFromReader(b *testing.B) {
+	encoded := getBenchmarkRecords(b)
+	b.SetBytes(int64(len(encoded)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m MiniSeedData
+		if err := m.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	var m MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(getBenchmarkRecords(b))); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := m.Encode(OVERWRITE, MSBFIRST); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAssembleTime(b *testing.B) {
+	data := []byte{0x07, 0xe8, 0x00, 0x3c, 12, 30, 15, 0x00, 0x13, 0x88}
+	for i := 0; i < b.N; i++ {
+		assembleTime(data, MSBFIRST)
+	}
+}
@@ -113,6 +113,70 @@ func TestInt16NegativeSignExtension(t *testing.T) {
 	}
 }
 
+// TestUnpackFullDataSection verifies that the last INT32 and FLOAT samples of
+// a data section ending at the end of the record are decoded (previously it
+// was dropped, e.g. 49 of the 50 samples of int32_INT32_bigEndian.mseed).
+func TestUnpackFullDataSection(t *testing.T) {
+	for _, name := range []string{"int32_INT32_bigEndian.mseed", "int32_INT32_littleEndian.mseed"} {
+		var m MiniSeedData
+		if err := m.Read(filepath.Join("example/reader/testdata", name)); err != nil {
+			t.Fatal(err)
+		}
+		s := &m.Series[0]
+		if end := int(s.FixedSection.DataStartOffset) + 4*50; end != 1<<s.BlocketteSection.RecordLength {
+			t.Fatalf("%s: want samples filling the record, got data up to byte %d", name, end)
+		}
+		if d := s.DataSection.Decoded; len(d) != 50 || d[49] != int32(50) {
+			t.Fatalf("%s: want 50 samples ending with 50, got %v", name, d)
+		}
+	}
+
+	// Exact buffers, and a trailing partial sample left out
+	if got := unpackInt([]byte{0, 0, 0, 1, 0, 0, 0, 2}, 2, 32, MSBFIRST); len(got) != 2 || got[1] != 2 {
+		t.Errorf("want [1 2], got %v", got)
+	}
+	if got := unpackInt([]byte{0, 1, 0, 2, 0}, 3, 16, MSBFIRST); len(got) != 2 || got[1] != 2 {
+		t.Errorf("want [1 2], got %v", got)
+	}
+	if got := unpackFloat([]byte{0, 0, 0x80, 0x3f, 0, 0, 0, 0x40}, 2, 32, LSBFIRST); len(got) != 2 || got[1] != 2 {
+		t.Errorf("want [1 2], got %v", got)
+	}
+	if got := unpackFloat(make([]byte, 15), 2, 64, LSBFIRST); len(got) != 1 {
+		t.Errorf("want 1 sample, got %v", got)
+	}
+}
+
+// TestUnpackAsciiSamples verifies that ASCII records decode to as many
+// characters as their sample count, leaving out the padding of the record
+// (previously the 200 NUL bytes after "ABCDEFGH" of smallASCII_*.mseed).
+func TestUnpackAsciiSamples(t *testing.T) {
+	for name, want := range map[string]int{
+		"smallASCII_bigEndian.mseed":    8,
+		"smallASCII_littleEndian.mseed": 8,
+		"fullASCII_bigEndian.mseed":     95,
+	} {
+		var m MiniSeedData
+		if err := m.Read(filepath.Join("example/reader/testdata", name)); err != nil {
+			t.Fatal(err)
+		}
+		s := &m.Series[0]
+		if text := s.DataSection.Decoded[0].(string); len(text) != want || strings.ContainsRune(text, 0) {
+			t.Errorf("%s: want %d characters, got %q", name, want, text)
+		}
+		if padding := len(s.DataSection.RawData) - want; padding <= 0 {
+			t.Errorf("%s: want padding after the text, got %d bytes of data", name, len(s.DataSection.RawData))
+		}
+	}
+
+	// A sample count beyond the data keeps all of it
+	if got := unpackAscii([]byte("GPS ok  "), 6); got != "GPS ok" {
+		t.Errorf("want %q, got %q", "GPS ok", got)
+	}
+	if got := unpackAscii([]byte("GPS"), 6); got != "GPS" {
+		t.Errorf("want %q, got %q", "GPS", got)
+	}
+}
+
 // TestRoundTrip exercises Init -> Append -> Encode -> Read for each encoding and
 // verifies the decoded samples survive a full write/read cycle.
 func TestRoundTrip(t *testing.T) {
@@ -0,0 +1,94 @@
+package mseedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// getCorruptRecord encodes one Steim-2 record of samples and lets corrupt
+// modify the bytes.
+func getCorruptRecord(t *testing.T, samples []int32, corrupt func(record []byte)) []byte {
+	var m MiniSeedData
+	_ = m.Init(STEIM2, MSBFIRST)
+	err := m.Append(samples, &AppendOptions{
+		SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt(record)
+	return record
+}
+
+// TestIntegrityPolicies checks that Xn and sample count mismatches drop the
+// record, or keep the samples with or without a warning.
+func TestIntegrityPolicies(t *testing.T) {
+	samples := getSteimTestSamples(200, 7)
+	for _, c := range []struct {
+		name    string
+		want    error
+		corrupt func(record []byte)
+	}{
+		{"xn", ErrSteimXn, func(record []byte) {
+			binary.BigEndian.PutUint32(record[64+8:], uint32(samples[len(samples)-1]+1))
+		}},
+		{"count", ErrSteimCount, func(record []byte) {
+			binary.BigEndian.PutUint16(record[30:], uint16(len(samples)+50))
+		}},
+	} {
+		record := getCorruptRecord(t, samples, c.corrupt)
+
+		var strict MiniSeedData
+		if err := strict.ReadFromReader(bytes.NewReader(record)); !errors.Is(err, c.want) || len(strict.Series) != 0 {
+			t.Errorf("%s: strict read: want %v and no record, got %v and %d records", c.name, c.want, err, len(strict.Series))
+		}
+
+		// Only the failing record is dropped from a longer stream
+		good := getCorruptRecord(t, samples[:100], func([]byte) {})
+		stream := append(append(append([]byte{}, good...), record...), good...)
+		if err := strict.ReadFromReader(bytes.NewReader(stream)); !errors.Is(err, c.want) {
+			t.Errorf("%s: strict read of 3 records: want %v, got %v", c.name, c.want, err)
+		}
+		if len(strict.Series) != 2 || strict.Records != 2 || strict.Samples != 200 ||
+			len(strict.Series[1].DataSection.Decoded) != 100 {
+			t.Errorf("%s: strict read of 3 records: want the 2 good ones, got %d records of %d samples", c.name, strict.Records, strict.Samples)
+		}
+
+		for _, policy := range []IntegrityPolicy{INTEGRITY_WARN, INTEGRITY_IGNORE} {
+			var m MiniSeedData
+			if err := m.ReadFromReader(bytes.NewReader(record), WithIntegrity(policy)); err != nil {
+				t.Fatalf("%s: policy %d: %v", c.name, policy, err)
+			}
+			s := m.Series[0]
+			if len(s.DataSection.Decoded) != len(samples) || s.DataSection.Decoded[0] != samples[0] {
+				t.Errorf("%s: policy %d: samples were not kept", c.name, policy)
+			}
+			warned := len(s.Warnings) == 1 && errors.Is(s.Warnings[0], c.want)
+			if warned != (policy == INTEGRITY_WARN) {
+				t.Errorf("%s: policy %d: got warnings %v", c.name, policy, s.Warnings)
+			}
+		}
+
+		// Lazily decoded records report the error from Decode
+		var lazy MiniSeedData
+		if err := lazy.ReadFromReader(bytes.NewReader(record), WithLazyDecode()); err != nil {
+			t.Fatal(err)
+		}
+		ds := &lazy.Series[0].DataSection
+		if err := ds.Decode(); !errors.Is(err, c.want) || len(ds.Decoded) != len(samples) {
+			t.Errorf("%s: lazy decode: want %v with samples, got %v and %d samples", c.name, c.want, err, len(ds.Decoded))
+		}
+		if traces := lazy.Traces(); len(traces) != 1 {
+			t.Errorf("%s: expected the record to make a trace, got %d traces", c.name, len(traces))
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package mseedio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JSON_TIME_LAYOUT is the ISO 8601 layout of times in JSON and CSV output.
+const JSON_TIME_LAYOUT = "2006-01-02T15:04:05.000000Z"
+
+// fixedSectionJSON is the JSON form of a fixed section.
+type fixedSectionJSON struct {
+	SequenceNumber   string   `json:"sequence_number"`
+	DataQuality      string   `json:"data_quality"`
+	NetworkCode      string   `json:"network"`
+	StationCode      string   `json:"station"`
+	LocationCode     string   `json:"location"`
+	ChannelCode      string   `json:"channel"`
+	StartTime        string   `json:"start_time"`
+	SampleRate       float64  `json:"sample_rate"`
+	SamplesNumber    int32    `json:"samples_number"`
+	ActivityFlags    []string `json:"activity_flags"`
+	IOClockFlags     []string `json:"io_clock_flags"`
+	DataQualityFlags []string `json:"data_quality_flags"`
+	BlockettesFollow int32    `json:"blockettes_follow"`
+	TimeCorrection   int32    `json:"time_correction"`
+	DataStartOffset  int32    `json:"data_start_offset"`
+}
+
+// blocketteJSON is the JSON form of a blockette, with the fields of its type.
+type blocketteJSON struct {
+	Type          int32 `json:"type"`
+	NextBlockette int32 `json:"next_blockette"`
+
+	// Blockette 1000
+	Encoding     string `json:"encoding,omitempty"`
+	ByteOrder    string `json:"byte_order,omitempty"`
+	RecordLength int    `json:"record_length,omitempty"`
+
+	// Blockettes 1001 and 500
+	TimingQuality *int32 `json:"timing_quality,omitempty"`
+	Microseconds  *int32 `json:"microseconds,omitempty"`
+	FrameCount    *int32 `json:"frame_count,omitempty"`
+
+	// Blockette 500
+	VCOCorrection    *float32 `json:"vco_correction,omitempty"`
+	ExceptionTime    string   `json:"exception_time,omitempty"`
+	ReceptionQuality *int32   `json:"reception_quality,omitempty"`
+	ExceptionCount   *int32   `json:"exception_count,omitempty"`
+	ExceptionType    string   `json:"exception_type,omitempty"`
+	ClockModel       string   `json:"clock_model,omitempty"`
+	ClockStatus      string   `json:"clock_status,omitempty"`
+
+	// Blockette 2000
+	RecordNumber *int32   `json:"record_number,omitempty"`
+	OpaqueOrder  *int32   `json:"opaque_order,omitempty"`
+	OpaqueFlags  *int32   `json:"opaque_flags,omitempty"`
+	OpaqueTags   []string `json:"opaque_tags,omitempty"`
+	OpaqueData   []byte   `json:"opaque_data,omitempty"`
+
+	Value   any    `json:"value,omitempty"`
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// traceJSON is the JSON form of a trace.
+type traceJSON struct {
+	NetworkCode  string  `json:"network"`
+	StationCode  string  `json:"station"`
+	LocationCode string  `json:"location"`
+	ChannelCode  string  `json:"channel"`
+	DataQuality  string  `json:"data_quality"`
+	SampleRate   float64 `json:"sample_rate"`
+	StartTime    string  `json:"start_time"`
+	EndTime      string  `json:"end_time"`
+	SampleType   string  `json:"sample_type"`
+	Samples      []any   `json:"samples"`
+}
+
+// dataSeriesJSON is the JSON form of a record.
+type dataSeriesJSON struct {
+	FixedSection FixedSection       `json:"fixed_section"`
+	Blockettes   []BlocketteSection `json:"blockettes"`
+	SampleType   string             `json:"sample_type"`
+	Samples      []any              `json:"samples"`
+}
+
+// MarshalJSON encodes the fixed section with trimmed codes, an ISO 8601
+// start time, the sample rate in Hz and the names of the set flags.
+func (f FixedSection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fixedSectionJSON{
+		SequenceNumber:   strings.TrimSpace(f.SequenceNumber),
+		DataQuality:      f.DataQuality,
+		NetworkCode:      strings.TrimSpace(f.NetworkCode),
+		StationCode:      strings.TrimSpace(f.StationCode),
+		LocationCode:     strings.TrimSpace(f.LocationCode),
+		ChannelCode:      strings.TrimSpace(f.ChannelCode),
+		StartTime:        formatStartTime(&f),
+		SampleRate:       f.SampleRate(),
+		SamplesNumber:    f.SamplesNumber,
+		ActivityFlags:    getFlagNames(f.ActivityFlags.String()),
+		IOClockFlags:     getFlagNames(f.IOClockFlags.String()),
+		DataQualityFlags: getFlagNames(f.DataQualityFlags.String()),
+		BlockettesFollow: f.BlockettesFollow,
+		TimeCorrection:   f.TimeCorrection,
+		DataStartOffset:  f.DataStartOffset,
+	})
+}
+
+// MarshalJSON encodes the blockette with the fields of its type: the
+// encoding name, byte order and record length in bytes of blockette 1000,
+// the timing fields of 1001 and 500, and the opaque data of 2000, base64
+// encoded. Custom blockettes get their Value, unregistered ones their
+// Payload.
+func (b BlocketteSection) MarshalJSON() ([]byte, error) {
+	v := blocketteJSON{Type: b.BlocketteCode, NextBlockette: b.NextBlockette, Value: b.Value, Payload: b.Payload}
+	if b.Value == nil && b.Payload == nil {
+		switch b.BlocketteCode {
+		case 1000:
+			v.Encoding, v.ByteOrder = getEncodingName(int(b.EncodingFormat)), "LSBFIRST"
+			if b.BitOrder == MSBFIRST {
+				v.ByteOrder = "MSBFIRST"
+			}
+			v.RecordLength = 1 << b.RecordLength
+		case 1001:
+			v.TimingQuality, v.Microseconds, v.FrameCount = &b.TimingQuality, &b.Microseconds, &b.FrameCount
+		case 500:
+			v.VCOCorrection, v.Microseconds = &b.VCOCorrection, &b.Microseconds
+			v.ExceptionTime = b.ExceptionTime.UTC().Format(JSON_TIME_LAYOUT)
+			v.ReceptionQuality, v.ExceptionCount = &b.ReceptionQuality, &b.ExceptionCount
+			v.ExceptionType, v.ClockModel, v.ClockStatus = b.ExceptionType, b.ClockModel, b.ClockStatus
+		case 2000:
+			v.RecordNumber, v.OpaqueOrder, v.OpaqueFlags = &b.RecordNumber, &b.OpaqueOrder, &b.OpaqueFlags
+			v.OpaqueTags, v.OpaqueData = b.OpaqueTags, b.OpaqueData
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+// MarshalJSON encodes the trace with ISO 8601 times, the sample type (int32,
+// float32, float64 or ascii) and its samples.
+func (t Trace) MarshalJSON() ([]byte, error) {
+	sampleType, samples := getTypedSamples(t.Samples)
+	return json.Marshal(traceJSON{
+		NetworkCode:  t.NetworkCode,
+		StationCode:  t.StationCode,
+		LocationCode: t.LocationCode,
+		ChannelCode:  t.ChannelCode,
+		DataQuality:  t.DataQuality,
+		SampleRate:   t.SampleRate,
+		StartTime:    t.StartTime.UTC().Format(JSON_TIME_LAYOUT),
+		EndTime:      t.EndTime.UTC().Format(JSON_TIME_LAYOUT),
+		SampleType:   sampleType,
+		Samples:      samples,
+	})
+}
+
+// MarshalJSON encodes the record as its fixed section, its blockettes,
+// blockette 1000 first, and its decoded samples, typed by its encoding. Records read with
+// WithLazyDecode are decoded for the occasion.
+func (d DataSeries) MarshalJSON() ([]byte, error) {
+	ds := d.DataSection
+	if err := ds.Decode(); err != nil && !isSteimIntegrityError(err) {
+		return nil, err
+	}
+
+	sampleType, samples := getTypedSamples(appendSamples(nil, ds.Decoded))
+	if d.BlocketteSection.EncodingFormat == FLOAT32 && sampleType == "float64" {
+		sampleType = "float32" // Decoded as float64
+	}
+	return json.Marshal(dataSeriesJSON{
+		FixedSection: d.FixedSection,
+		Blockettes:   append([]BlocketteSection{d.BlocketteSection}, d.Blockettes...),
+		SampleType:   sampleType,
+		Samples:      samples,
+	})
+}
+
+// formatStartTime formats the start time of a record, keeping second 60 of
+// a leap second.
+func formatStartTime(f *FixedSection) string {
+	if b := f.StartBTime; b.IsLeapSecond() && b.Time().Equal(f.StartTime) {
+		return fmt.Sprintf("%s:60.%06dZ", f.StartTime.UTC().Add(-time.Second).Format("2006-01-02T15:04"), b.Ticks*100)
+	}
+	return f.StartTime.UTC().Format(JSON_TIME_LAYOUT)
+}
+
+// getFlagNames splits the names listed by the String method of flags.
+func getFlagNames(s string) []string {
+	if s == "NONE" {
+		return []string{}
+	}
+	return strings.Split(s, "|")
+}
+
+// getEncodingName returns the name of an encoding format, such as "STEIM2".
+func getEncodingName(encoding int) string {
+	switch encoding {
+	case ASCII:
+		return "ASCII"
+	case INT16:
+		return "INT16"
+	case INT24:
+		return "INT24"
+	case INT32:
+		return "INT32"
+	case FLOAT32:
+		return "FLOAT32"
+	case FLOAT64:
+		return "FLOAT64"
+	case STEIM1:
+		return "STEIM1"
+	case STEIM2:
+		return "STEIM2"
+	}
+	return fmt.Sprintf("UNKNOWN_%d", encoding)
+}
+
+// getTypedSamples returns the type of flattened samples and the samples, the
+// text of ASCII records as a single string.
+func getTypedSamples(samples []any) (string, []any) {
+	if samples == nil {
+		samples = []any{}
+	}
+	if len(samples) == 0 {
+		return "", samples
+	}
+
+	switch samples[0].(type) {
+	case int32:
+		return "int32", samples
+	case float32:
+		return "float32", samples
+	case float64:
+		return "float64", samples
+	case string:
+		var text strings.Builder
+		for _, v := range samples {
+			fmt.Fprint(&text, v)
+		}
+		return "ascii", []any{text.String()}
+	}
+	return fmt.Sprintf("%T", samples[0]), samples
+}
@@ -0,0 +1,99 @@
+package mseedio
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMarshalJSON checks the JSON of a record read back from bytes, and of
+// its trace.
+func TestMarshalJSON(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 30, 15, 123400000, time.UTC)
+
+	var m MiniSeedData
+	_ = m.Init(STEIM2, MSBFIRST)
+	if err := m.Append([]int32{1, -2, 3}, &AppendOptions{
+		SampleRate: 0.1, StartTime: start, SequenceNumber: "000007",
+		StationCode: "ANMO", LocationCode: "00", ChannelCode: "LHZ", NetworkCode: "IU",
+		ActivityFlags: ACTIVITY_EVENT_BEGIN, IOClockFlags: IOCLOCK_CLOCK_LOCKED,
+		Blockettes: []BlocketteSection{{BlocketteCode: 1001, TimingQuality: 90, Microseconds: 12}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var read MiniSeedData
+	if err := read.ReadFromReader(bytes.NewReader(data), WithLazyDecode()); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := json.Marshal(read.Series[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"fixed_section":{"sequence_number":"000007","data_quality":"D","network":"IU","station":"ANMO",` +
+		`"location":"00","channel":"LHZ","start_time":"2024-03-01T12:30:15.123400Z","sample_rate":0.1,` +
+		`"samples_number":3,"activity_flags":["EVENT_BEGIN"],"io_clock_flags":["CLOCK_LOCKED"],"data_quality_flags":[],` +
+		`"blockettes_follow":2,"time_correction":0,"data_start_offset":64},` +
+		`"blockettes":[{"type":1000,"next_blockette":56,"encoding":"STEIM2","byte_order":"MSBFIRST","record_length":256},` +
+		`{"type":1001,"next_blockette":0,"timing_quality":90,"microseconds":12,"frame_count":0}],` +
+		`"sample_type":"int32","samples":[1,-2,3]}`
+	if string(got) != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+
+	traces := read.Traces()
+	if got, err = json.Marshal(traces); err != nil {
+		t.Fatal(err)
+	}
+	want = `[{"network":"IU","station":"ANMO","location":"00","channel":"LHZ","data_quality":"D","sample_rate":0.1,` +
+		`"start_time":"2024-03-01T12:30:15.123400Z","end_time":"2024-03-01T12:30:35.123400Z",` +
+		`"sample_type":"int32","samples":[1,-2,3]}]`
+	if string(got) != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+// TestMarshalJSONTypes checks the sample types of float and ASCII traces,
+// the start time of a record in a leap second and unregistered blockettes.
+func TestMarshalJSONTypes(t *testing.T) {
+	for _, c := range []struct {
+		samples []any
+		want    string
+	}{
+		{[]any{float32(1.5)}, `"sample_type":"float32","samples":[1.5]`},
+		{[]any{0.25, -1.0}, `"sample_type":"float64","samples":[0.25,-1]`},
+		{[]any{"GPS ", "ok"}, `"sample_type":"ascii","samples":["GPS ok"]`},
+		{nil, `"sample_type":"","samples":[]`},
+	} {
+		got, err := json.Marshal(Trace{Samples: c.samples})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(got), c.want) {
+			t.Errorf("want %s in %s", c.want, got)
+		}
+	}
+
+	start := time.Date(2017, 1, 1, 0, 0, 0, 500000000, time.UTC)
+	got, err := json.Marshal(FixedSection{StartTime: start, StartBTime: NewBTime(start, true)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"start_time":"2016-12-31T23:59:60.500000Z"`; !strings.Contains(string(got), want) {
+		t.Errorf("want %s in %s", want, got)
+	}
+
+	got, err = json.Marshal(BlocketteSection{BlocketteCode: 300, Payload: []byte{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"type":300,"next_blockette":0,"payload":"AQI="}`; string(got) != want {
+		t.Errorf("want %s, got %s", want, got)
+	}
+}
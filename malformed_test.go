@@ -0,0 +1,141 @@
+package mseedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// getValidRecord encodes one 512-byte record of 100 samples.
+func getValidRecord(t *testing.T, encoding int) []byte {
+	var m MiniSeedData
+	_ = m.Init(encoding, MSBFIRST)
+	err := m.Append(getSteimTestSamples(100, 5), &AppendOptions{
+		SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		RecordLength: 512,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// TestMalformedHeaders corrupts one header field at a time. Every case must
+// return without panicking, and those leaving no usable record must fail.
+func TestMalformedHeaders(t *testing.T) {
+	putUint16 := func(offset int, v uint16) func([]byte) []byte {
+		return func(record []byte) []byte {
+			binary.BigEndian.PutUint16(record[offset:], v)
+			return record
+		}
+	}
+
+	for _, c := range []struct {
+		name     string
+		encoding int
+		corrupt  func(record []byte) []byte
+		wantErr  bool
+	}{
+		{"truncated fixed section", INT32, func(r []byte) []byte { return r[:40] }, true},
+		{"truncated blockette", INT32, func(r []byte) []byte { return r[:52] }, true},
+		{"data offset zero", INT32, putUint16(44, 0), true},
+		{"data offset inside fixed section", INT32, putUint16(44, 30), true},
+		{"data offset past the record", INT32, putUint16(44, 60000), true},
+		{"first blockette offset", INT32, putUint16(46, 0), true},
+		{"blockette 1001 without encoding byte", INT32, func(r []byte) []byte {
+			binary.BigEndian.PutUint16(r[48:], 1001)
+			binary.BigEndian.PutUint16(r[44:], 56)
+			return r
+		}, false},
+		{"blockette 500 cut by the data", INT32, putUint16(48, 500), true},
+		{"blockette 2000 lengths", INT32, func(r []byte) []byte {
+			binary.BigEndian.PutUint16(r[48:], 2000)
+			binary.BigEndian.PutUint16(r[52:], 0xffff)
+			return r
+		}, true},
+		{"unknown blockette without body", INT32, func(r []byte) []byte {
+			binary.BigEndian.PutUint16(r[48:], 9999)
+			binary.BigEndian.PutUint16(r[44:], 52)
+			return r
+		}, true},
+		{"standard blockette", INT32, putUint16(48, 100), false},
+		{"standard blockette pointing into the data", INT32, func(r []byte) []byte {
+			binary.BigEndian.PutUint16(r[48:], 100)
+			binary.BigEndian.PutUint16(r[50:], 500)
+			return r
+		}, true},
+		{"next blockette backwards", INT32, putUint16(50, 10), false},
+		{"next blockette past the record", INT32, putUint16(50, 65000), false},
+		{"next blockette into the data", INT32, putUint16(50, 500), false},
+		{"negative samples number", INT32, putUint16(30, 0xffff), false},
+		{"steim samples number beyond data", STEIM2, putUint16(30, 0xffff), true},
+		{"steim data shorter than 12 bytes", STEIM2, func(r []byte) []byte { return r[:72] }, true},
+		{"steim data missing", STEIM1, func(r []byte) []byte { return r[:64] }, true},
+		{"unknown encoding", INT32, func(r []byte) []byte { r[52] = 99; return r }, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			record := c.corrupt(getValidRecord(t, c.encoding))
+
+			var m MiniSeedData
+			err := m.ReadFromReader(bytes.NewReader(record))
+			if c.wantErr && err == nil {
+				t.Errorf("expected an error")
+			}
+			if err == nil {
+				m.Traces()
+			}
+		})
+	}
+}
+
+// TestMalformedCalls passes bad arguments to the exported parsing functions.
+func TestMalformedCalls(t *testing.T) {
+	record := getValidRecord(t, STEIM2)
+
+	var m MiniSeedData
+	if err := m.ReadAt(bytes.NewReader(record), -1); err == nil {
+		t.Error("ReadAt: expected an error for a negative size")
+	}
+	if err := m.ReadAt(bytes.NewReader(record), 1<<40); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAt: expected io.ErrUnexpectedEOF for a size past the data, got %v", err)
+	}
+
+	var fs FixedSection
+	if err := fs.Parse(record[:47], MSBFIRST); err == nil {
+		t.Error("FixedSection.Parse: expected an error for a short buffer")
+	}
+
+	for _, buffer := range [][]byte{nil, {0x03}, {0x03, 0xe8, 0x00}, {0x01, 0xf4, 0x00, 0x00, 0x01}} {
+		var bs BlocketteSection
+		if err := bs.Parse(buffer, MSBFIRST); err == nil {
+			t.Errorf("BlocketteSection.Parse(% x): expected an error", buffer)
+		}
+	}
+
+	var ds DataSection
+	if err := ds.Parse(record[64:], -1, 1000, STEIM2, MSBFIRST); err == nil {
+		t.Error("DataSection.Parse: expected an error for a negative samples number")
+	}
+	for _, encoding := range []int{STEIM1, STEIM2} {
+		var ds DataSection
+		if err := ds.Parse(record[64:70], 10, 1000, encoding, MSBFIRST); err == nil {
+			t.Errorf("DataSection.Parse: expected an error for %d bytes of Steim data", 6)
+		}
+	}
+
+	r := &byteReader{buf: []byte{1, 2, 3}, order: MSBFIRST}
+	if v := r.int(4); v != 0 || r.err == nil {
+		t.Errorf("byteReader: reading past the end returned %d, %v", v, r.err)
+	}
+	if b := r.btime(); b != (BTime{}) {
+		t.Errorf("byteReader: expected a zero BTime after an error, got %+v", b)
+	}
+}
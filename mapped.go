@@ -0,0 +1,46 @@
+package mseedio
+
+import (
+	"fmt"
+	"io"
+)
+
+// MappedFile is a read-only view of a file mapped into memory, opened with
+// OpenMapped. Data series read from it through ReadAt reference the mapping
+// directly, so they must not be used once the file is closed.
+type MappedFile struct {
+	data  []byte
+	unmap func([]byte) error
+}
+
+// ReadAt implements io.ReaderAt by copying out of the mapping.
+func (f *MappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, fmt.Errorf("negative offset %d", off)
+	}
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Size returns the length of the mapped file in bytes.
+func (f *MappedFile) Size() int64 {
+	return int64(len(f.data))
+}
+
+// Close releases the mapping.
+func (f *MappedFile) Close() error {
+	data := f.data
+	f.data = nil
+	if f.unmap == nil || data == nil {
+		return nil
+	}
+
+	return f.unmap(data)
+}
@@ -0,0 +1,17 @@
+//go:build !unix
+
+package mseedio
+
+import "os"
+
+// OpenMapped reads the file at filePath into memory. Memory mapping is only
+// available on Unix systems; elsewhere the file is loaded once and ReadAt
+// still parses it without a further copy.
+func OpenMapped(filePath string) (*MappedFile, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MappedFile{data: data}, nil
+}
@@ -0,0 +1,57 @@
+package mseedio
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReadAtMatchesRead checks that the memory-mapped, lazily decoded and the
+// plain io.ReaderAt paths agree with Read on every fixture.
+func TestReadAtMatchesRead(t *testing.T) {
+	files, _ := filepath.Glob("example/reader/testdata/*.mseed")
+	for _, f := range files {
+		var want MiniSeedData
+		if err := want.Read(f); err != nil {
+			t.Fatalf("Read(%s): %v", f, err)
+		}
+
+		mf, err := OpenMapped(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mapped MiniSeedData
+		if err := mapped.ReadAt(mf, mf.Size(), WithLazyDecode()); err != nil {
+			t.Fatalf("ReadAt(%s): %v", f, err)
+		}
+		for i := range mapped.Series {
+			if mapped.Series[i].DataSection.Decoded != nil {
+				t.Fatalf("%s: series %d decoded eagerly", f, i)
+			}
+			if err := mapped.Series[i].DataSection.Decode(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if dumpRecord(&want) != dumpRecord(&mapped) {
+			t.Errorf("%s: mapped read disagrees with Read", f)
+		}
+		if err := mf.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		fh, err := os.Open(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := fh.Stat()
+		var plain MiniSeedData
+		err = plain.ReadAt(fh, info.Size())
+		fh.Close()
+		if err != nil {
+			t.Fatalf("ReadAt(%s): %v", f, err)
+		}
+		if dumpRecord(&want) != dumpRecord(&plain) {
+			t.Errorf("%s: ReadAt disagrees with Read", f)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+//go:build unix
+
+package mseedio
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenMapped maps the file at filePath into memory read-only, so that ReadAt
+// can parse its records without copying them.
+func OpenMapped(filePath string) (*MappedFile, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return &MappedFile{}, nil
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MappedFile{data: data, unmap: syscall.Munmap}, nil
+}
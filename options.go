@@ -0,0 +1,83 @@
+package mseedio
+
+// IntegrityPolicy decides what reading does with a record failing a Steim
+// integrity check, see ErrSteimXn and ErrSteimCount.
+type IntegrityPolicy int
+
+// Steim integrity policies
+const (
+	INTEGRITY_STRICT IntegrityPolicy = iota // Drop the record, the read returning its error after the others
+	INTEGRITY_WARN                          // Keep the samples, add the error to DataSeries.Warnings
+	INTEGRITY_IGNORE                        // Keep the samples silently
+)
+
+// ReadOption configures Read and ReadFromReader.
+type ReadOption func(*readOptions)
+
+// readOptions holds the settings applied by ReadOption values.
+type readOptions struct {
+	qualities   []string // Accepted data quality indicators
+	lazyDecode  bool     // Leave samples to DataSection.Decode
+	concurrency int      // Records decoded at once
+	integrity   IntegrityPolicy
+}
+
+// WithQualities keeps only the records whose data quality indicator is one of
+// qualities (D, R, Q or M). All four are accepted by default.
+func WithQualities(qualities ...string) ReadOption {
+	return func(o *readOptions) {
+		o.qualities = qualities
+	}
+}
+
+// WithLazyDecode leaves the samples of every record undecoded, with only
+// DataSection.RawData set, until DataSection.Decode is called.
+func WithLazyDecode() ReadOption {
+	return func(o *readOptions) {
+		o.lazyDecode = true
+	}
+}
+
+// WithConcurrency decodes up to n records at once, or as many as there are
+// CPUs if n < 1. Records keep their order in MiniSeedData.Series.
+func WithConcurrency(n int) ReadOption {
+	return func(o *readOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithIntegrity sets how records failing a Steim integrity check are handled,
+// INTEGRITY_STRICT by default: such records are left out of
+// MiniSeedData.Series, the other records are read, and the read returns the
+// errors of the records left out, joined. Records read with WithLazyDecode
+// are checked when DataSection.Decode is called, which returns the error to
+// the caller.
+func WithIntegrity(policy IntegrityPolicy) ReadOption {
+	return func(o *readOptions) {
+		o.integrity = policy
+	}
+}
+
+// getReadOptions applies options over the defaults.
+func getReadOptions(options []ReadOption) *readOptions {
+	o := &readOptions{
+		qualities:   []string{"D", "R", "Q", "M"},
+		concurrency: 1,
+	}
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
+
+// acceptsQuality reports whether records of the given quality are read.
+func (o *readOptions) acceptsQuality(quality string) bool {
+	for _, q := range o.qualities {
+		if q == quality && QualityRank(q) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,14 +1,9 @@
 package mseedio
 
-import "fmt"
-
-// knownBlockettes lists blockette types the parser recognizes but does not
-// decode field-by-field. They parse successfully with only BlocketteCode set,
-// preserving the original permissive behavior for records that carry them.
-var knownBlockettes = map[int32]bool{
-	100: true, 200: true, 201: true, 300: true, 310: true, 320: true,
-	390: true, 395: true, 400: true, 405: true, 500: true, 2000: true,
-}
+import (
+	"fmt"
+	"strings"
+)
 
 // Parse decodes a fixed data-record header from the fixed section of a miniSEED
 // record. buffer must contain at least FIXED_SECTION_LENGTH bytes.
@@ -25,66 +20,133 @@ func (f *FixedSection) Parse(buffer []byte, bitOrder int) error {
 	f.LocationCode = r.string(2)
 	f.ChannelCode = r.string(3)
 	f.NetworkCode = r.string(2)
-	f.StartTime = r.time()
-	f.SamplesNumber = r.int(2)
+	f.StartBTime = r.btime()
+	f.StartTime = f.StartBTime.Time()
+	f.SamplesNumber = int32(uint16(r.int(2)))
 	f.SampleFactor = r.int(2)
 	f.SampleMultiplier = r.int(2)
-	f.ActivityFlags = r.int(1)
-	f.IOClockFlags = r.int(1)
-	f.DataQualityFlags = r.int(1)
+	f.ActivityFlags = ActivityFlags(r.int(1))
+	f.IOClockFlags = IOClockFlags(r.int(1))
+	f.DataQualityFlags = DataQualityFlags(r.int(1))
 	f.BlockettesFollow = r.int(1)
 	f.TimeCorrection = r.int(4)
-	f.DataStartOffset = r.int(2)
+	f.DataStartOffset = int32(uint16(r.int(2)))
 	f.SectionEndOffset = r.int(2)
-	return nil
+	return r.err
 }
 
-// Parse decodes a blockette section. Only blockette 1000 (Data Only SEED) and
-// 1001 (Data Extension) are decoded in full; other standard blockette types are
-// accepted with only BlocketteCode populated, and unknown types return an error.
+// Parse decodes a blockette section. The type and next-blockette fields are
+// read here and the rest is handed to the decoder registered for the type:
+// blockettes 1000 (Data Only SEED), 1001 (Data Extension), 500 (Timing) and
+// 2000 (Opaque Data) are built in, see RegisterBlockette for custom ones.
+// Unregistered types are kept verbatim in Payload, so buffer should end where
+// the blockette does.
 func (b *BlocketteSection) Parse(buffer []byte, bitOrder int) error {
 	code, err := getBlocketteType(buffer, bitOrder)
 	if err != nil {
 		return err
 	}
+	if len(buffer) < 4 {
+		return fmt.Errorf("blockette %d requires 4 bytes, got %d", code, len(buffer))
+	}
 	b.BlocketteCode = code
+	b.NextBlockette = assembleInt(buffer[2:4], 2, bitOrder)
 
-	switch code {
-	case 1000:
-		if len(buffer) < 7 {
-			return fmt.Errorf("blockette 1000 requires 7 bytes, got %d", len(buffer))
-		}
-		r := &byteReader{buf: buffer, pos: 2, order: bitOrder}
-		b.NextBlockette = r.int(2)
-		b.EncodingFormat = r.int(1)
-		b.BitOrder = r.int(1)
-		b.RecordLength = r.int(1)
-	case 1001:
-		if len(buffer) < 8 {
-			return fmt.Errorf("blockette 1001 requires 8 bytes, got %d", len(buffer))
-		}
-		r := &byteReader{buf: buffer, pos: 2, order: bitOrder}
-		b.NextBlockette = r.int(2)
-		b.TimingQuality = r.int(1)
-		b.Microseconds = r.int(1)
-		r.skip(1) // reserved
-		b.FrameCount = r.int(1)
-	default:
-		if !knownBlockettes[code] {
-			return fmt.Errorf("blockette type %d is not supported", code)
+	codec, ok := getBlocketteCodec(code)
+	if !ok {
+		b.Payload = buffer[4:]
+		return nil
+	}
+
+	return codec.decoder(b, buffer[4:], bitOrder)
+}
+
+// parseBlockette1000 decodes the body of a blockette 1000 (Data Only SEED).
+func parseBlockette1000(b *BlocketteSection, body []byte, bitOrder int) error {
+	if len(body) < 3 {
+		return fmt.Errorf("blockette 1000 requires 7 bytes, got %d", len(body)+4)
+	}
+	r := &byteReader{buf: body, order: bitOrder}
+	b.EncodingFormat = r.int(1)
+	b.BitOrder = r.int(1)
+	b.RecordLength = r.int(1)
+	return r.err
+}
+
+// parseBlockette1001 decodes the body of a blockette 1001 (Data Extension).
+func parseBlockette1001(b *BlocketteSection, body []byte, bitOrder int) error {
+	if len(body) < 4 {
+		return fmt.Errorf("blockette 1001 requires 8 bytes, got %d", len(body)+4)
+	}
+	r := &byteReader{buf: body, order: bitOrder}
+	b.TimingQuality = r.int(1)
+	b.Microseconds = r.int(1)
+	r.skip(1) // reserved
+	b.FrameCount = r.int(1)
+	return r.err
+}
+
+// parseBlockette500 decodes the body of a blockette 500 (Timing).
+func parseBlockette500(b *BlocketteSection, body []byte, bitOrder int) error {
+	if len(body) < BLOCKETTE500_LENGTH-4 {
+		return fmt.Errorf("blockette 500 requires %d bytes, got %d", BLOCKETTE500_LENGTH, len(body)+4)
+	}
+	r := &byteReader{buf: body, order: bitOrder}
+	b.VCOCorrection = r.float32()
+	b.ExceptionTime = r.time()
+	b.Microseconds = r.int(1)
+	b.ReceptionQuality = int32(uint8(r.int(1)))
+	b.ExceptionCount = r.int(4)
+	b.ExceptionType = r.text(16)
+	b.ClockModel = r.text(32)
+	b.ClockStatus = r.text(128)
+	return r.err
+}
+
+// parseBlockette2000 decodes the body of a blockette 2000 (Opaque Data). Its
+// length is self-described, so body may extend past the blockette.
+func parseBlockette2000(b *BlocketteSection, body []byte, bitOrder int) error {
+	if len(body) < BLOCKETTE2000_HEADER_LENGTH-4 {
+		return fmt.Errorf("blockette 2000 requires %d bytes, got %d", BLOCKETTE2000_HEADER_LENGTH, len(body)+4)
+	}
+	r := &byteReader{buf: body, order: bitOrder}
+	length := int(uint16(r.int(2))) - 4
+	dataOffset := int(uint16(r.int(2))) - 4
+	b.RecordNumber = r.int(4)
+	b.OpaqueOrder = r.int(1)
+	b.OpaqueFlags = int32(uint8(r.int(1)))
+	fields := int(uint8(r.int(1)))
+	if dataOffset < BLOCKETTE2000_HEADER_LENGTH-4 || length < dataOffset || length > len(body) {
+		return fmt.Errorf("blockette 2000 has invalid length %d or data offset %d", length+4, dataOffset+4)
+	}
+
+	b.OpaqueTags = nil
+	header := string(r.bytes(dataOffset - r.pos))
+	for i := 0; i < fields; i++ {
+		tag, rest, found := strings.Cut(header, "~")
+		if !found {
+			return fmt.Errorf("blockette 2000 has %d header tags, found %d", fields, i)
 		}
+		b.OpaqueTags = append(b.OpaqueTags, tag)
+		header = rest
 	}
-	return nil
+	b.OpaqueData = r.bytes(length - dataOffset)
+	return r.err
 }
 
 // Parse decodes the data section into DataSection.Decoded according to the
-// record's encoding format, keeping the original bytes in RawData.
+// record's encoding format, keeping the original bytes in RawData. Steim
+// integrity errors (ErrSteimXn, ErrSteimCount) are returned with the samples
+// decoded all the same.
 func (d *DataSection) Parse(buffer []byte, samples, blockette, encoding, bitOrder int) error {
+	if samples < 0 {
+		return fmt.Errorf("samples number %d is negative", samples)
+	}
 	d.RawData = buffer
 
 	switch encoding {
 	case ASCII:
-		d.Decoded = append(d.Decoded, unpackAscii(buffer))
+		d.Decoded = append(d.Decoded, unpackAscii(buffer, samples))
 	case INT16:
 		appendDecoded(d, unpackInt(buffer, samples, 16, bitOrder))
 	case INT24:
@@ -97,22 +159,40 @@ func (d *DataSection) Parse(buffer []byte, samples, blockette, encoding, bitOrde
 		appendDecoded(d, unpackFloat(buffer, samples, 64, bitOrder))
 	case STEIM1:
 		result, err := unpackSteim1(buffer, samples, bitOrder)
-		if err != nil {
+		if err != nil && !isSteimIntegrityError(err) {
 			return err
 		}
 		appendDecoded(d, result)
+		return err
 	case STEIM2:
 		result, err := unpackSteim2(buffer, samples, bitOrder)
-		if err != nil {
+		if err != nil && !isSteimIntegrityError(err) {
 			return err
 		}
 		appendDecoded(d, result)
+		return err
 	default:
 		return fmt.Errorf("encoding %d is not supported", encoding)
 	}
 	return nil
 }
 
+// Decode decodes RawData into Decoded for a data section read with
+// WithLazyDecode. It does nothing if the samples are decoded already. Like
+// Parse, it keeps the samples when returning a Steim integrity error.
+func (d *DataSection) Decode() error {
+	if !d.lazy {
+		return nil
+	}
+
+	err := d.Parse(d.RawData, d.samples, 0, d.encoding, d.bitOrder)
+	if err != nil && !isSteimIntegrityError(err) {
+		return err
+	}
+	d.lazy = false
+	return err
+}
+
 // appendDecoded appends every element of vals to DataSection.Decoded, boxing
 // each into the any-typed slice that callers expect.
 func appendDecoded[T any](d *DataSection, vals []T) {
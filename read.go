@@ -1,29 +1,68 @@
 package mseedio
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 )
 
+// dataBlockettes lists the standard SEED data record blockette types, which
+// the record scan accepts unregistered.
+var dataBlockettes = map[int32]bool{
+	100: true, 200: true, 201: true, 300: true, 310: true, 320: true,
+	390: true, 395: true, 400: true, 405: true,
+}
+
 // Read parses a miniSEED file at filePath into structured MiniSeedData.
-func (m *MiniSeedData) Read(filePath string) error {
+func (m *MiniSeedData) Read(filePath string, options ...ReadOption) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	return m.ReadFromReader(file)
+	return m.ReadFromReader(file, options...)
 }
 
 // ReadFromReader parses miniSEED data from an io.Reader into MiniSeedData.
-func (m *MiniSeedData) ReadFromReader(data io.Reader) error {
+func (m *MiniSeedData) ReadFromReader(data io.Reader, options ...ReadOption) error {
 	bytes, err := io.ReadAll(data)
 	if err != nil {
 		return err
 	}
 
+	return m.parse(bytes, getReadOptions(options))
+}
+
+// ReadAt parses size bytes of miniSEED data from an io.ReaderAt. When r is a
+// MappedFile, records are parsed in place and DataSection.RawData references
+// the mapped region instead of a copy; combine with WithLazyDecode to keep
+// samples undecoded until DataSection.Decode is called.
+func (m *MiniSeedData) ReadAt(r io.ReaderAt, size int64, options ...ReadOption) error {
+	if size < 0 {
+		return fmt.Errorf("size %d is negative", size)
+	}
+	if mf, ok := r.(*MappedFile); ok && size <= int64(len(mf.data)) {
+		return m.parse(mf.data[:size], getReadOptions(options))
+	}
+
+	// Read through a section so that a bogus size cannot force a huge
+	// allocation up front
+	bytes, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+	if int64(len(bytes)) < size {
+		return io.ErrUnexpectedEOF
+	}
+
+	return m.parse(bytes, getReadOptions(options))
+}
+
+// parse parses the miniSEED records held in bytes, which the resulting data
+// series keep referencing.
+func (m *MiniSeedData) parse(bytes []byte, opts *readOptions) error {
 	// Return error if length is less than the fixed section
 	if len(bytes) < FIXED_SECTION_LENGTH {
 		return fmt.Errorf("data length is less than %d bytes", FIXED_SECTION_LENGTH)
@@ -39,9 +78,9 @@ func (m *MiniSeedData) ReadFromReader(data io.Reader) error {
 	var (
 		fixedSections     = []FixedSection{}
 		blocketteSections = []BlocketteSection{}
-		samplesNumber     = 0 // Total number of samples
+		chainedSections   = [][]BlocketteSection{}
 	)
-	for i := 0; i < len(bytes); i += 64 {
+	for i := 0; i+FIXED_SECTION_LENGTH <= len(bytes); i += 64 {
 		var (
 			fs = FixedSection{}
 			bs = BlocketteSection{}
@@ -52,27 +91,33 @@ func (m *MiniSeedData) ReadFromReader(data io.Reader) error {
 		err := fs.Parse(bytes[i:fsOffset], bitOrder)
 		if err != nil ||
 			fs.SectionEndOffset != FIXED_SECTION_LENGTH ||
-			(fs.DataQuality != "D" && fs.DataQuality != "R" &&
-				fs.DataQuality != "Q" && fs.DataQuality != "M") {
+			QualityRank(fs.DataQuality) == 0 {
 			continue
 		}
 
-		// Parse blockette
+		// Parse blockette, which must lie between the fixed section and data
 		bsOffset := i + int(fs.DataStartOffset)
+		if bsOffset <= fsOffset {
+			continue
+		}
 		if bsOffset >= len(bytes) {
 			break
 		}
-		err = bs.Parse(bytes[fsOffset:bsOffset], bitOrder)
-		if err != nil {
+		bsEnd := getBlocketteEnd(bytes, i, fsOffset, bsOffset, bitOrder)
+		err = bs.Parse(bytes[fsOffset:bsEnd], bitOrder)
+		if err != nil || !isPlausibleBlockette(&bs, int(fs.DataStartOffset)) {
 			continue
 		}
 
 		// Determine encoding for non 100-blockettes
-		if bs.BlocketteCode == 1001 {
+		if bs.BlocketteCode == 1001 && bsOffset-fsOffset > 12 {
 			// Encoding is usually in bytes[fsOffset:bsOffset][12]
 			bs.EncodingFormat = int32(bytes[fsOffset:bsOffset][12])
 		}
 
+		// Parse blockettes chained after the first one
+		chained := parseChainedBlockettes(bytes, i, bsOffset, bs.NextBlockette, bitOrder)
+
 		// Set slice position [start:end]
 		fs.ReaderOffset = SectionOffset{
 			i, fsOffset,
@@ -81,80 +126,153 @@ func (m *MiniSeedData) ReadFromReader(data io.Reader) error {
 			fsOffset, bsOffset,
 		}
 
-		// Set global start time
-		if i == 0 {
-			m.StartTime = fs.StartTime
-		}
-
-		// Add samples and append
-		samplesNumber += int(fs.SamplesNumber)
+		// Append sections
 		fixedSections = append(fixedSections, fs)
 		blocketteSections = append(blocketteSections, bs)
+		chainedSections = append(chainedSections, chained)
 	}
 
-	// Detect initial frame length automatically
-	var initLength int
-	for i := 64; i < len(bytes); i += 64 {
-		// Parse fixed section again
-		var fs = FixedSection{}
-		var fsOffset = i + FIXED_SECTION_LENGTH
-		err := fs.Parse(bytes[i:fsOffset], bitOrder)
-		if err == nil && fs.SectionEndOffset == FIXED_SECTION_LENGTH {
-			initLength = i
-			break
-		}
-	}
-	if initLength == 0 {
-		initLength = len(bytes)
-	}
-
-	// Detect rest each frame length automatically
+	// Parse data series section
 	var (
-		frameLength = []int{initLength}
-		lastOffset  SectionOffset
+		accepted      []int // Indexes of the records kept
+		samplesNumber = 0   // Total number of samples
 	)
-	for i := 1; i < len(fixedSections); i++ {
-		readerOffset := fixedSections[i].ReaderOffset
-		frameLength = append(frameLength, readerOffset.Start-lastOffset.Start)
-		lastOffset = readerOffset
-	}
-
-	// Parse data series section
 	for i := 0; i < len(fixedSections); i++ {
+		if !opts.acceptsQuality(fixedSections[i].DataQuality) {
+			continue
+		}
+
+		// Data runs up to the next record, skip records whose data offset
+		// points past it
 		var (
 			endIndex   = len(bytes)
 			startIndex = blocketteSections[i].ReaderOffset.End
 		)
 		if i != len(fixedSections)-1 {
-			endIndex = fixedSections[i].ReaderOffset.Start + frameLength[i+1]
+			endIndex = fixedSections[i+1].ReaderOffset.Start
 		}
-
-		var ds DataSection
-		err = ds.Parse(
-			bytes[startIndex:endIndex],
-			int(fixedSections[i].SamplesNumber),
-			int(blocketteSections[i].BlocketteCode),
-			int(blocketteSections[i].EncodingFormat),
-			bitOrder,
-		)
-		if err != nil {
-			return err
+		if startIndex > endIndex {
+			continue
 		}
 
-		// Append data series
+		// Append data series, decoded below
 		m.Series = append(m.Series, DataSeries{
-			DataSection:      ds,
+			DataSection: DataSection{
+				RawData:  bytes[startIndex:endIndex],
+				lazy:     true,
+				samples:  int(fixedSections[i].SamplesNumber),
+				encoding: int(blocketteSections[i].EncodingFormat),
+				bitOrder: bitOrder,
+			},
 			FixedSection:     fixedSections[i],
 			BlocketteSection: blocketteSections[i],
+			Blockettes:       chainedSections[i],
+		})
+		accepted = append(accepted, i)
+		samplesNumber += int(fixedSections[i].SamplesNumber)
+	}
+	if len(accepted) == 0 {
+		return fmt.Errorf("no valid records found")
+	}
+
+	// Decode samples, records being independent of each other. Under
+	// INTEGRITY_STRICT, records failing a Steim integrity check are dropped
+	// and their errors returned once the other records are read
+	var dropped []error
+	if !opts.lazyDecode {
+		var (
+			series = m.Series[len(m.Series)-len(accepted):]
+			errs   = make([]error, len(series))
+			kept   = accepted[:0]
+			n      = 0
+		)
+		parallelFor(len(series), opts.concurrency, func(i int) {
+			errs[i] = series[i].DataSection.Decode()
 		})
+		for i, err := range errs {
+			if err != nil && !isSteimIntegrityError(err) {
+				return err
+			}
+
+			if err != nil {
+				switch opts.integrity {
+				case INTEGRITY_WARN:
+					series[i].Warnings = append(series[i].Warnings, err)
+				case INTEGRITY_IGNORE:
+				default:
+					dropped = append(dropped, fmt.Errorf("record %s: %w", series[i].FixedSection.SequenceNumber, err))
+					samplesNumber -= int(series[i].FixedSection.SamplesNumber)
+					continue
+				}
+			}
+			series[n] = series[i]
+			kept = append(kept, accepted[i])
+			n++
+		}
+		m.Series = m.Series[:len(m.Series)-len(series)+n]
+		accepted = kept
+	}
+	if len(accepted) == 0 {
+		return errors.Join(dropped...)
 	}
 
 	// Set file info
+	first, last := accepted[0], accepted[len(accepted)-1]
 	m.Order = bitOrder
 	m.Samples = samplesNumber
-	m.Records = len(fixedSections)
-	m.Type = int(blocketteSections[0].BlocketteCode)
-	m.EndTime = fixedSections[len(fixedSections)-1].StartTime
+	m.Records = len(accepted)
+	m.Type = int(blocketteSections[first].BlocketteCode)
+	m.StartTime = fixedSections[first].StartTime
+	m.EndTime = fixedSections[last].StartTime
+
+	return errors.Join(dropped...)
+}
+
+// isPlausibleBlockette reports whether the first blockette of a candidate
+// record may be a real one, so that the 64-byte record scan does not take
+// arbitrary bytes for a record header. Registered types are checked by their
+// decoder, other types must be standard data blockettes pointing to no next
+// blockette, or to one between themselves and the data at dataOffset.
+func isPlausibleBlockette(b *BlocketteSection, dataOffset int) bool {
+	if _, ok := getBlocketteCodec(b.BlocketteCode); ok {
+		return true
+	}
+	if !dataBlockettes[b.BlocketteCode] {
+		return false
+	}
+
+	next := int(b.NextBlockette)
+	return next == 0 || (next >= FIXED_SECTION_LENGTH+4 && next < dataOffset)
+}
+
+// parseChainedBlockettes follows the NextBlockette chain of the record starting
+// at recordStart, whose data starts at dataStart. It stops at the first offset
+// that falls outside the data, does not move forward, or holds a blockette that
+// cannot be parsed.
+func parseChainedBlockettes(bytes []byte, recordStart, dataStart int, next int32, bitOrder int) []BlocketteSection {
+	var (
+		chained []BlocketteSection
+		last    = int32(FIXED_SECTION_LENGTH)
+	)
+	for next > last && recordStart+int(next) < len(bytes) {
+		start := recordStart + int(next)
+
+		end := getBlocketteEnd(bytes, recordStart, start, dataStart, bitOrder)
+
+		var bs BlocketteSection
+		if err := bs.Parse(bytes[start:end], bitOrder); err != nil {
+			break
+		}
+		if n := getBlocketteLength(&bs); n > 0 && start+n < end {
+			end = start + n
+		}
+		bs.ReaderOffset = SectionOffset{
+			start, end,
+		}
+
+		chained = append(chained, bs)
+		last, next = next, bs.NextBlockette
+	}
 
-	return nil
+	return chained
 }
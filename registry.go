@@ -0,0 +1,60 @@
+package mseedio
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BlocketteDecoder decodes the body of a blockette, i.e. the bytes following
+// its type and next-blockette fields, into b. BlocketteCode and NextBlockette
+// are already set; custom blockettes typically store their result in b.Value.
+// The body may extend past the end of the blockette.
+type BlocketteDecoder func(b *BlocketteSection, body []byte, bitOrder int) error
+
+// BlocketteEncoder encodes the body of b, without its type and next-blockette
+// fields, which Compose writes itself.
+type BlocketteEncoder func(b *BlocketteSection, bitOrder int) ([]byte, error)
+
+// blocketteCodec pairs the decoder and encoder of a blockette type.
+type blocketteCodec struct {
+	decoder BlocketteDecoder
+	encoder BlocketteEncoder
+}
+
+var (
+	blocketteMu       sync.RWMutex
+	blocketteRegistry = map[int32]blocketteCodec{
+		1000: {parseBlockette1000, composeBlockette1000},
+		1001: {parseBlockette1001, composeBlockette1001},
+		500:  {parseBlockette500, composeBlockette500},
+		2000: {parseBlockette2000, composeBlockette2000},
+	}
+)
+
+// RegisterBlockette installs the decoder and encoder for blockette type code,
+// replacing any previous registration, including the built-in ones. Blockette
+// types that are not registered are still read and written, their body being
+// kept verbatim in BlocketteSection.Payload. Reading skips records whose first
+// blockette is neither registered nor a standard data record blockette, as
+// those are taken for bytes that only look like a record header.
+func RegisterBlockette(code int32, decoder BlocketteDecoder, encoder BlocketteEncoder) error {
+	if code <= 0 || code > 0xffff {
+		return fmt.Errorf("blockette type %d is out of range", code)
+	}
+	if decoder == nil || encoder == nil {
+		return fmt.Errorf("blockette type %d requires both a decoder and an encoder", code)
+	}
+
+	blocketteMu.Lock()
+	defer blocketteMu.Unlock()
+	blocketteRegistry[code] = blocketteCodec{decoder, encoder}
+	return nil
+}
+
+// getBlocketteCodec returns the codec registered for blockette type code.
+func getBlocketteCodec(code int32) (blocketteCodec, bool) {
+	blocketteMu.RLock()
+	defer blocketteMu.RUnlock()
+	codec, ok := blocketteRegistry[code]
+	return codec, ok
+}
@@ -0,0 +1,20 @@
+// Package resp reads and writes the RESP text files of evalresp and rdseed,
+// mapping blockettes 53 to 62 into the response model of the stationxml
+// package, so that the response package can remove them without StationXML.
+//
+//	f, err := resp.ReadFile("RESP.IU.ANMO.00.BHZ")
+//	if err != nil {
+//		// handle error
+//	}
+//	c, err := f.LookupRecord(&series.FixedSection)
+//	if err != nil {
+//		// handle error
+//	}
+//	velocity, err := response.Remove(&trace, c.Response, nil)
+//
+// Each stage is read from its filter blockette (53 poles and zeros, 54
+// coefficients, 55 response list, 61 FIR or 62 polynomial), its decimation
+// (57) and its gain (58), a gain of stage 0 giving the overall sensitivity.
+// Channel epochs run from the start date up to but not including the end
+// date, as in StationXML.
+package resp
@@ -0,0 +1,350 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bclswl0827/mseedio/seed"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// blockette collects the fields of one blockette of a RESP file.
+type blockette struct {
+	typ    int
+	line   int
+	fields map[int]string     // Labeled fields by number
+	rows   map[int][][]string // Table rows by first field number
+	last   int                // Number of the last labeled field
+}
+
+// Parse reads the channel epochs of a RESP file. Blockettes 50 and 52 open
+// the epochs, blockettes 53 to 58, 61 and 62 make up their stages, and the
+// others (56, 59 and 60) are skipped.
+func Parse(r io.Reader) (*File, error) {
+	var (
+		f       = &File{}
+		current *blockette
+		number  int
+	)
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		b := current
+		current = nil
+		if len(f.Channels) == 0 {
+			return fmt.Errorf("line %d: blockette %d before any channel", b.line, b.typ)
+		}
+		if err := f.Channels[len(f.Channels)-1].apply(b); err != nil {
+			return fmt.Errorf("line %d: blockette %d: %w", b.line, b.typ, err)
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		typ, field, value, row, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+
+		switch typ {
+		case 50, 52:
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			if err := f.setHeader(typ, field, value); err != nil {
+				return nil, fmt.Errorf("line %d: %w", number, err)
+			}
+			continue
+		case 53, 54, 55, 57, 58, 61, 62:
+		default:
+			continue
+		}
+
+		// A field not after the last one starts a new blockette
+		if current == nil || current.typ != typ || row == nil && field <= current.last {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = &blockette{typ: typ, line: number, fields: make(map[int]string), rows: make(map[int][][]string)}
+		}
+		if row != nil {
+			current.rows[field] = append(current.rows[field], row)
+		} else {
+			current.fields[field], current.last = value, field
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	for i := range f.Channels {
+		if r := f.Channels[i].Response; r != nil {
+			r.SetSensitivityUnits()
+		}
+	}
+
+	return f, nil
+}
+
+// ReadFile reads a RESP file.
+func ReadFile(name string) (*File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// parseLine splits a line into its blockette type, field number and either
+// the value of a labeled field ("B053F04 Stage sequence number: 1") or the
+// values of a table row ("B053F10-13 0 0.0E+00 ...").
+func parseLine(line string) (typ, field int, value string, row []string, err error) {
+	key, rest, _ := strings.Cut(line, " ")
+	if len(key) < 7 || key[0] != 'B' || key[4] != 'F' {
+		return 0, 0, "", nil, fmt.Errorf("invalid field key %q", key)
+	}
+	if typ, err = strconv.Atoi(key[1:4]); err != nil {
+		return 0, 0, "", nil, fmt.Errorf("invalid field key %q", key)
+	}
+	fields, _, isRow := strings.Cut(key[5:], "-")
+	if field, err = strconv.Atoi(fields); err != nil {
+		return 0, 0, "", nil, fmt.Errorf("invalid field key %q", key)
+	}
+
+	// Rows of single-field tables, such as FIR coefficients, have no range
+	if _, value, labeled := strings.Cut(rest, ":"); labeled && !isRow {
+		return typ, field, strings.TrimSpace(value), nil, nil
+	}
+	return typ, field, "", strings.Fields(rest), nil
+}
+
+// setHeader sets a field of blockette 50 or 52, a station field opening a
+// new channel epoch.
+func (f *File) setHeader(typ, field int, value string) error {
+	if typ == 50 && field == 3 {
+		f.Channels = append(f.Channels, Channel{Station: value})
+		return nil
+	}
+	if len(f.Channels) == 0 {
+		return fmt.Errorf("blockette %d before the station", typ)
+	}
+
+	c := &f.Channels[len(f.Channels)-1]
+	var err error
+	switch {
+	case typ == 50 && field == 16:
+		c.Network = value
+	case typ == 52 && field == 3:
+		if value == "??" {
+			value = ""
+		}
+		c.Location = value
+	case typ == 52 && field == 4:
+		c.Channel = value
+	case typ == 52 && field == 22:
+		c.StartTime, err = seed.ParseTime(value)
+	case typ == 52 && field == 23:
+		if value != "No Ending Time" {
+			c.EndTime, err = seed.ParseTime(value)
+		}
+	}
+	return err
+}
+
+// apply adds a blockette to the stages of the channel.
+func (c *Channel) apply(b *blockette) error {
+	var err error
+	switch b.typ {
+	case 53:
+		pz := &stationxml.PolesZeros{Filter: b.getFilter(5, &err)}
+		switch typ := b.getCode(3); typ {
+		case "A":
+			pz.PzTransferFunctionType = stationxml.LAPLACE_RADIANS
+		case "B":
+			pz.PzTransferFunctionType = stationxml.LAPLACE_HERTZ
+		case "D":
+			pz.PzTransferFunctionType = stationxml.DIGITAL_Z
+		default:
+			return fmt.Errorf("unknown transfer function type %q", typ)
+		}
+		pz.NormalizationFactor, pz.NormalizationFrequency = b.getFloat(7, &err), b.getFloat(8, &err)
+		zeros, poles := b.getRows(10, 9, 2, &err), b.getRows(15, 14, 2, &err)
+		for _, row := range zeros {
+			pz.Zeros = append(pz.Zeros, stationxml.PoleZero{Number: int(row[0]), Real: row[1], Imaginary: row[2]})
+		}
+		for _, row := range poles {
+			pz.Poles = append(pz.Poles, stationxml.PoleZero{Number: int(row[0]), Real: row[1], Imaginary: row[2]})
+		}
+		c.getStage(b.getInt(4, &err)).PolesZeros = pz
+
+	case 54:
+		cf := &stationxml.Coefficients{Filter: b.getFilter(5, &err)}
+		switch typ := b.getCode(3); typ {
+		case "A":
+			cf.CfTransferFunctionType = stationxml.ANALOG_RADIANS
+		case "B":
+			cf.CfTransferFunctionType = stationxml.ANALOG_HERTZ
+		case "D":
+			cf.CfTransferFunctionType = stationxml.DIGITAL
+		default:
+			return fmt.Errorf("unsupported transfer function type %q", typ)
+		}
+		for _, row := range b.getRows(8, 7, 1, &err) {
+			cf.Numerators = append(cf.Numerators, row[1])
+		}
+		for _, row := range b.getRows(11, 10, 1, &err) {
+			cf.Denominators = append(cf.Denominators, row[1])
+		}
+		c.getStage(b.getInt(4, &err)).Coefficients = cf
+
+	case 55:
+		l := &stationxml.ResponseList{Filter: b.getFilter(4, &err)}
+		for _, row := range b.getRows(7, 6, 4, &err) {
+			l.Elements = append(l.Elements, stationxml.ResponseListElement{Frequency: row[1], Amplitude: row[2], Phase: row[4]})
+		}
+		c.getStage(b.getInt(3, &err)).ResponseList = l
+
+	case 57:
+		c.getStage(b.getInt(3, &err)).Decimation = &stationxml.Decimation{
+			InputSampleRate: b.getFloat(4, &err),
+			Factor:          b.getInt(5, &err),
+			Offset:          b.getInt(6, &err),
+			Delay:           b.getFloat(7, &err),
+			Correction:      b.getFloat(8, &err),
+		}
+
+	case 58:
+		stage, gain := b.getInt(3, &err), stationxml.Gain{Value: b.getFloat(4, &err), Frequency: b.getFloat(5, &err)}
+		if stage != 0 {
+			c.getStage(stage).StageGain = &gain
+			break
+		}
+		if c.Response == nil {
+			c.Response = &stationxml.Response{}
+		}
+		c.Response.InstrumentSensitivity = &stationxml.Sensitivity{Value: gain.Value, Frequency: gain.Frequency}
+
+	case 61:
+		fir := &stationxml.FIR{Filter: b.getFilter(6, &err)}
+		fir.Name = b.fields[4]
+		switch symmetry := b.getCode(5); symmetry {
+		case "A":
+			fir.Symmetry = stationxml.SYMMETRY_NONE
+		case "B":
+			fir.Symmetry = stationxml.SYMMETRY_ODD
+		case "C":
+			fir.Symmetry = stationxml.SYMMETRY_EVEN
+		default:
+			return fmt.Errorf("unknown symmetry code %q", symmetry)
+		}
+		for _, row := range b.getRows(9, 8, 1, &err) {
+			fir.NumeratorCoefficients = append(fir.NumeratorCoefficients, row[1])
+		}
+		c.getStage(b.getInt(3, &err)).FIR = fir
+
+	case 62:
+		if typ := b.getCode(3); typ != "P" {
+			return fmt.Errorf("unknown transfer function type %q", typ)
+		}
+		p := &stationxml.Polynomial{Filter: b.getFilter(5, &err)}
+		if b.getCode(7) == "M" {
+			p.ApproximationType = "MACLAURIN"
+		}
+		p.FrequencyLowerBound, p.FrequencyUpperBound = b.getFloat(9, &err), b.getFloat(10, &err)
+		p.ApproximationLowerBound, p.ApproximationUpperBound = b.getFloat(11, &err), b.getFloat(12, &err)
+		p.MaximumError = b.getFloat(13, &err)
+		for _, row := range b.getRows(15, 14, 1, &err) {
+			p.Coefficients = append(p.Coefficients, row[1])
+		}
+		c.getStage(b.getInt(4, &err)).Polynomial = p
+	}
+
+	return err
+}
+
+// getCode returns the code letter opening a field, such as "A" of
+// "A [Laplace Transform (Rad/sec)]".
+func (b *blockette) getCode(field int) string {
+	code, _, _ := strings.Cut(b.fields[field], " ")
+	return code
+}
+
+// getInt returns an integer field, keeping the first error in err.
+func (b *blockette) getInt(field int, err *error) int {
+	v, e := strconv.Atoi(b.fields[field])
+	if e != nil && *err == nil {
+		*err = fmt.Errorf("field %d: invalid integer %q", field, b.fields[field])
+	}
+	return v
+}
+
+// getFloat returns a floating point field, keeping the first error in err.
+func (b *blockette) getFloat(field int, err *error) float64 {
+	v, e := strconv.ParseFloat(b.fields[field], 64)
+	if e != nil && *err == nil {
+		*err = fmt.Errorf("field %d: invalid number %q", field, b.fields[field])
+	}
+	return v
+}
+
+// getFilter returns the units of the input units field and the one after.
+func (b *blockette) getFilter(field int, err *error) stationxml.Filter {
+	return stationxml.Filter{InputUnits: parseUnits(b.fields[field]), OutputUnits: parseUnits(b.fields[field+1])}
+}
+
+// getRows returns the rows of a table as numbers, the index first, checking
+// their count against a count field and that they have at least n values
+// after the index.
+func (b *blockette) getRows(field, count, n int, err *error) [][]float64 {
+	rows := b.rows[field]
+	if want := b.getInt(count, err); want != len(rows) && *err == nil {
+		*err = fmt.Errorf("field %d: want %d rows, got %d", field, want, len(rows))
+	}
+
+	values := make([][]float64, len(rows))
+	for i, row := range rows {
+		if len(row) < n+1 {
+			if *err == nil {
+				*err = fmt.Errorf("field %d: row %q has fewer than %d values", field, strings.Join(row, " "), n)
+			}
+			continue
+		}
+		for _, s := range row {
+			v, e := strconv.ParseFloat(s, 64)
+			if e != nil && *err == nil {
+				*err = fmt.Errorf("field %d: invalid number %q", field, s)
+			}
+			values[i] = append(values[i], v)
+		}
+	}
+	if *err != nil {
+		return nil
+	}
+	return values
+}
+
+// parseUnits parses "M/S - Velocity in Meters Per Second" into units.
+func parseUnits(s string) stationxml.Units {
+	name, description, _ := strings.Cut(s, " - ")
+	return stationxml.Units{Name: strings.TrimSpace(name), Description: strings.TrimSpace(description)}
+}
@@ -0,0 +1,158 @@
+package resp
+
+import (
+	"errors"
+	"math/cmplx"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/response"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+func TestParse(t *testing.T) {
+	f, err := ReadFile("testdata/RESP.IU.ANMO.00.BHZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Channels) != 2 {
+		t.Fatalf("want 2 channels, got %d", len(f.Channels))
+	}
+
+	c := &f.Channels[0]
+	if c.Network != "IU" || c.Station != "ANMO" || c.Location != "00" || c.Channel != "BHZ" {
+		t.Errorf("unexpected codes %q %q %q %q", c.Network, c.Station, c.Location, c.Channel)
+	}
+	if !c.StartTime.Equal(time.Date(2018, 7, 9, 20, 45, 0, 0, time.UTC)) || !c.EndTime.Equal(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected epoch %s to %s", c.StartTime, c.EndTime)
+	}
+
+	r := c.Response
+	if r == nil || len(r.Stages) != 3 || r.InstrumentSensitivity == nil {
+		t.Fatalf("unexpected response %+v", r)
+	}
+	s := r.InstrumentSensitivity
+	if s.Value != 1.88802e9 || s.Frequency != 0.02 || s.InputUnits.Name != "M/S" || s.OutputUnits.Name != "COUNTS" {
+		t.Errorf("unexpected sensitivity %+v", s)
+	}
+	pz := r.Stages[0].PolesZeros
+	if pz == nil || pz.PzTransferFunctionType != stationxml.LAPLACE_RADIANS || len(pz.Zeros) != 2 || len(pz.Poles) != 4 {
+		t.Fatalf("unexpected poles and zeros %+v", pz)
+	}
+	if pz.Poles[2].Complex() != complex(-39.18, 49.12) || pz.NormalizationFactor != 3948.58 || pz.InputUnits.Description != "Velocity in Meters Per Second" {
+		t.Errorf("unexpected pole %v, A0 %v or units %+v", pz.Poles[2], pz.NormalizationFactor, pz.InputUnits)
+	}
+	if g := r.Stages[0].StageGain; g == nil || g.Value != 1500 {
+		t.Errorf("unexpected stage 1 gain %+v", g)
+	}
+	if cf := r.Stages[1].Coefficients; cf == nil || cf.CfTransferFunctionType != stationxml.DIGITAL || r.Stages[1].StageGain.Value != 1258700 {
+		t.Errorf("unexpected stage 2 %+v", r.Stages[1])
+	}
+	fir := r.Stages[2].FIR
+	if fir == nil || fir.Name != "FIR_3" || fir.Symmetry != stationxml.SYMMETRY_EVEN || len(fir.NumeratorCoefficients) != 2 {
+		t.Errorf("unexpected FIR %+v", fir)
+	}
+	if d := r.Stages[2].Decimation; d == nil || d.InputSampleRate != 40 || d.Factor != 1 || d.Delay != 0.0375 {
+		t.Errorf("unexpected decimation %+v", d)
+	}
+
+	c = &f.Channels[1]
+	if c.Location != "" || c.Channel != "LKI" || !c.StartTime.Equal(time.Date(2018, 7, 9, 0, 0, 0, 0, time.UTC)) || !c.EndTime.IsZero() {
+		t.Errorf("unexpected channel %+v", c)
+	}
+	p := c.Response.Stages[0].Polynomial
+	if p == nil || p.ApproximationType != "MACLAURIN" || p.ApproximationLowerBound != -50 || len(p.Coefficients) != 2 || p.Coefficients[1] != 0.02 {
+		t.Errorf("unexpected polynomial %+v", p)
+	}
+	l := c.Response.Stages[1].ResponseList
+	if l == nil || len(l.Elements) != 2 || l.Elements[1] != (stationxml.ResponseListElement{Frequency: 1, Amplitude: 4e5, Phase: -18}) {
+		t.Errorf("unexpected response list %+v", l)
+	}
+	if s := c.Response.InstrumentSensitivity; s.Value != 4e5 || s.InputUnits.Name != "C" {
+		t.Errorf("unexpected sensitivity %+v", s)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	f, err := ReadFile("testdata/RESP.IU.ANMO.00.BHZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, err := f.LookupRecord(&mseedio.FixedSection{
+		NetworkCode: "IU", StationCode: "ANMO ", LocationCode: "00", ChannelCode: "BHZ", StartTime: at,
+	})
+	if err != nil || c != &f.Channels[0] {
+		t.Fatalf("unexpected channel %v, error %v", c, err)
+	}
+	if c, err := f.Lookup("IU", "ANMO", "--", "LKI", at); err != nil || c != &f.Channels[1] {
+		t.Errorf("unexpected channel %v, error %v", c, err)
+	}
+	if _, err := f.Lookup("IU", "ANMO", "00", "BHZ", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound after the epoch end, got %v", err)
+	}
+	if _, err := f.LookupTrace(&mseedio.Trace{NetworkCode: "IU", StationCode: "ANMO", LocationCode: "10", ChannelCode: "BHZ", StartTime: at}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound for another location, got %v", err)
+	}
+}
+
+func TestParseStationXML(t *testing.T) {
+	f, err := ReadFile("testdata/RESP.IU.ANMO.00.BHZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := stationxml.ReadFile("../stationxml/testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	want, err := d.Lookup("IU", "ANMO", "00", "BHZ", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := f.Lookup("IU", "ANMO", "00", "BHZ", at)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same response as RESP and as StationXML
+	frequencies := []float64{0.01, 0.1, 1, 10}
+	a, err := response.Evaluate(got.Response, frequencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := response.Evaluate(want.Response, frequencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range frequencies {
+		if cmplx.Abs(a[i]-b[i]) > 1e-9*cmplx.Abs(b[i]) {
+			t.Errorf("at %v Hz: RESP gives %v, StationXML %v", frequencies[i], a[i], b[i])
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, input := range map[string]string{
+		"before channel": "B058F03     Stage sequence number:                 0\n",
+		"invalid key":    "B050F03     Station:     ANMO\nB05XF16     Network:     IU\n",
+		"invalid time":   "B050F03     Station:     ANMO\nB052F22     Start date:  2018/190\n",
+		"row count": "B050F03     Station:     ANMO\n" +
+			"B061F03     Stage sequence number:                 1\n" +
+			"B061F05     Symmetry Code:                         A\n" +
+			"B061F08     Number of Coefficients:                2\n" +
+			"B061F09      0  2.500000E-01\n",
+		"transfer type": "B050F03     Station:     ANMO\n" +
+			"B053F03     Transfer function type:                X\n",
+		"number": "B050F03     Station:     ANMO\n" +
+			"B058F03     Stage sequence number:                 1\n" +
+			"B058F04     Sensitivity:                           many\n",
+	} {
+		if _, err := Parse(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package resp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// ErrNotFound is returned, wrapped with the identifier, when no channel
+// epoch matches a lookup.
+var ErrNotFound = errors.New("not found in RESP file")
+
+// Channel is the response of a channel epoch, the empty location code being
+// written "??" in RESP files.
+type Channel struct {
+	Network   string
+	Station   string
+	Location  string
+	Channel   string
+	StartTime time.Time
+	EndTime   time.Time // Zero for no ending time
+	Response  *stationxml.Response
+}
+
+// File holds the channel epochs of one or more RESP files.
+type File struct {
+	Channels []Channel
+}
+
+// FromStationXML collects the responses of the channels of a StationXML
+// document, to be written as RESP.
+func FromStationXML(d *stationxml.FDSNStationXML) *File {
+	f := &File{}
+	for i := range d.Networks {
+		n := &d.Networks[i]
+		for j := range n.Stations {
+			s := &n.Stations[j]
+			for k := range s.Channels {
+				c := &s.Channels[k]
+				if c.Response == nil {
+					continue
+				}
+				f.Channels = append(f.Channels, Channel{
+					Network: n.Code, Station: s.Code, Location: c.LocationCode, Channel: c.Code,
+					StartTime: c.StartDate.Time, EndTime: c.EndDate.Time, Response: c.Response,
+				})
+			}
+		}
+	}
+
+	return f
+}
+
+// Lookup returns the channel epoch holding t. Codes may carry the space
+// padding of FixedSection, and the location code may be given as "--" or
+// "??" when empty.
+func (f *File) Lookup(network, station, location, channel string, t time.Time) (*Channel, error) {
+	network, station = strings.TrimSpace(network), strings.TrimSpace(station)
+	location, channel = strings.TrimSpace(location), strings.TrimSpace(channel)
+	if location == "--" || location == "??" {
+		location = ""
+	}
+
+	for i := range f.Channels {
+		c := &f.Channels[i]
+		if c.Network == network && c.Station == station && c.Location == location && c.Channel == channel &&
+			!t.Before(c.StartTime) && (c.EndTime.IsZero() || t.Before(c.EndTime)) {
+			return c, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s.%s.%s.%s at %s: %w", network, station, location, channel, t.Format(time.RFC3339), ErrNotFound)
+}
+
+// LookupRecord returns the channel epoch of a record, by its codes and start
+// time.
+func (f *File) LookupRecord(fs *mseedio.FixedSection) (*Channel, error) {
+	return f.Lookup(fs.NetworkCode, fs.StationCode, fs.LocationCode, fs.ChannelCode, fs.StartTime)
+}
+
+// LookupTrace returns the channel epoch of a trace, by its codes and start
+// time.
+func (f *File) LookupTrace(t *mseedio.Trace) (*Channel, error) {
+	return f.Lookup(t.NetworkCode, t.StationCode, t.LocationCode, t.ChannelCode, t.StartTime)
+}
+
+// getStage returns the response stage of a number, adding it if missing.
+func (c *Channel) getStage(number int) *stationxml.Stage {
+	if c.Response == nil {
+		c.Response = &stationxml.Response{}
+	}
+	return c.Response.Stage(number)
+}
@@ -0,0 +1,265 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// Write writes the channel epochs in the layout of rdseed, each stage as its
+// filter blockette followed by its decimation (57) and gain (58), and the
+// overall sensitivity last as stage 0.
+func (f *File) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for i := range f.Channels {
+		if err := f.Channels[i].write(bw); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// write writes a channel epoch.
+func (c *Channel) write(w *bufio.Writer) error {
+	location := c.Location
+	if location == "" {
+		location = "??"
+	}
+	end := "No Ending Time"
+	if !c.EndTime.IsZero() {
+		end = formatTime(c.EndTime)
+	}
+
+	fmt.Fprintf(w, "#\n%s\n#\n", strings.Repeat("#", 83))
+	writeHeader(w, "B050F03", "Station:", c.Station)
+	writeHeader(w, "B050F16", "Network:", c.Network)
+	writeHeader(w, "B052F03", "Location:", location)
+	writeHeader(w, "B052F04", "Channel:", c.Channel)
+	writeHeader(w, "B052F22", "Start date:", formatTime(c.StartTime))
+	writeHeader(w, "B052F23", "End date:", end)
+
+	if c.Response == nil {
+		return nil
+	}
+	for i := range c.Response.Stages {
+		s := &c.Response.Stages[i]
+		number := s.Number
+		if number == 0 {
+			number = i + 1
+		}
+		if err := writeStage(w, s, number); err != nil {
+			return fmt.Errorf("%s.%s.%s.%s stage %d: %w", c.Network, c.Station, c.Location, c.Channel, number, err)
+		}
+	}
+	if s := c.Response.InstrumentSensitivity; s != nil {
+		writeGain(w, 0, stationxml.Gain{Value: s.Value, Frequency: s.Frequency})
+	}
+
+	return nil
+}
+
+// writeStage writes the blockettes of a stage.
+func writeStage(w *bufio.Writer, s *stationxml.Stage, number int) error {
+	switch {
+	case s.PolesZeros != nil:
+		pz := s.PolesZeros
+		var typ string
+		switch pz.PzTransferFunctionType {
+		case stationxml.LAPLACE_RADIANS:
+			typ = "A [Laplace Transform (Rad/sec)]"
+		case stationxml.LAPLACE_HERTZ:
+			typ = "B [Analog (Hz)]"
+		case stationxml.DIGITAL_Z:
+			typ = "D [Digital (Z-transform)]"
+		default:
+			return fmt.Errorf("unknown transfer function type %q", pz.PzTransferFunctionType)
+		}
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B053F03", "Transfer function type:", typ)
+		writeField(w, "B053F04", "Stage sequence number:", strconv.Itoa(number))
+		writeUnits(w, "B053F05", &pz.Filter)
+		writeField(w, "B053F07", "A0 normalization factor:", formatFloat(pz.NormalizationFactor))
+		writeField(w, "B053F08", "Normalization frequency:", formatFloat(pz.NormalizationFrequency))
+		writeField(w, "B053F09", "Number of zeroes:", strconv.Itoa(len(pz.Zeros)))
+		writeField(w, "B053F14", "Number of poles:", strconv.Itoa(len(pz.Poles)))
+		fmt.Fprint(w, "#              Complex zeroes:\n#              i  real          imag          real_error    imag_error\n")
+		for i, z := range pz.Zeros {
+			writeRow(w, "B053F10-13", i, z.Real, z.Imaginary, 0, 0)
+		}
+		fmt.Fprint(w, "#              Complex poles:\n#              i  real          imag          real_error    imag_error\n")
+		for i, p := range pz.Poles {
+			writeRow(w, "B053F15-18", i, p.Real, p.Imaginary, 0, 0)
+		}
+
+	case s.Coefficients != nil:
+		cf := s.Coefficients
+		var typ string
+		switch cf.CfTransferFunctionType {
+		case stationxml.ANALOG_RADIANS:
+			typ = "A"
+		case stationxml.ANALOG_HERTZ:
+			typ = "B"
+		case stationxml.DIGITAL:
+			typ = "D"
+		default:
+			return fmt.Errorf("unknown transfer function type %q", cf.CfTransferFunctionType)
+		}
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B054F03", "Transfer function type:", typ)
+		writeField(w, "B054F04", "Stage sequence number:", strconv.Itoa(number))
+		writeUnits(w, "B054F05", &cf.Filter)
+		writeField(w, "B054F07", "Number of numerators:", strconv.Itoa(len(cf.Numerators)))
+		writeField(w, "B054F10", "Number of denominators:", strconv.Itoa(len(cf.Denominators)))
+		if len(cf.Numerators) > 0 {
+			fmt.Fprint(w, "#              Numerator coefficients:\n#              i  coefficient   error\n")
+		}
+		for i, v := range cf.Numerators {
+			writeRow(w, "B054F08-09", i, v, 0)
+		}
+		if len(cf.Denominators) > 0 {
+			fmt.Fprint(w, "#              Denominator coefficients:\n#              i  coefficient   error\n")
+		}
+		for i, v := range cf.Denominators {
+			writeRow(w, "B054F11-12", i, v, 0)
+		}
+
+	case s.ResponseList != nil:
+		l := s.ResponseList
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B055F03", "Stage sequence number:", strconv.Itoa(number))
+		writeUnits(w, "B055F04", &l.Filter)
+		writeField(w, "B055F06", "Number of responses listed:", strconv.Itoa(len(l.Elements)))
+		fmt.Fprint(w, "#              i  frequency     amplitude     amplitude err phase angle   phase err\n")
+		for i, e := range l.Elements {
+			writeRow(w, "B055F07-11", i, e.Frequency, e.Amplitude, 0, e.Phase, 0)
+		}
+
+	case s.FIR != nil:
+		fir := s.FIR
+		var symmetry string
+		switch fir.Symmetry {
+		case stationxml.SYMMETRY_NONE, "":
+			symmetry = "A"
+		case stationxml.SYMMETRY_ODD:
+			symmetry = "B"
+		case stationxml.SYMMETRY_EVEN:
+			symmetry = "C"
+		default:
+			return fmt.Errorf("unknown symmetry %q", fir.Symmetry)
+		}
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B061F03", "Stage sequence number:", strconv.Itoa(number))
+		writeField(w, "B061F04", "Response Name:", fir.Name)
+		writeField(w, "B061F05", "Symmetry Code:", symmetry)
+		writeUnits(w, "B061F06", &fir.Filter)
+		writeField(w, "B061F08", "Number of Coefficients:", strconv.Itoa(len(fir.NumeratorCoefficients)))
+		fmt.Fprint(w, "#              i  FIR Coefficient\n")
+		for i, v := range fir.NumeratorCoefficients {
+			writeRow(w, "B061F09", i, v)
+		}
+
+	case s.Polynomial != nil:
+		p := s.Polynomial
+		approximation := "M"
+		if p.ApproximationType != "" && p.ApproximationType != "MACLAURIN" {
+			return fmt.Errorf("unknown approximation type %q", p.ApproximationType)
+		}
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B062F03", "Transfer function type:", "P")
+		writeField(w, "B062F04", "Stage sequence number:", strconv.Itoa(number))
+		writeUnits(w, "B062F05", &p.Filter)
+		writeField(w, "B062F07", "Polynomial Approximation Type:", approximation)
+		writeField(w, "B062F08", "Valid Frequency Units:", "B")
+		writeField(w, "B062F09", "Lower Valid Frequency Bound:", formatFloat(p.FrequencyLowerBound))
+		writeField(w, "B062F10", "Upper Valid Frequency Bound:", formatFloat(p.FrequencyUpperBound))
+		writeField(w, "B062F11", "Lower Bound of Approximation:", formatFloat(p.ApproximationLowerBound))
+		writeField(w, "B062F12", "Upper Bound of Approximation:", formatFloat(p.ApproximationUpperBound))
+		writeField(w, "B062F13", "Maximum Absolute Error:", formatFloat(p.MaximumError))
+		writeField(w, "B062F14", "Number of Coefficients:", strconv.Itoa(len(p.Coefficients)))
+		fmt.Fprint(w, "#              i  coefficient   error\n")
+		for i, v := range p.Coefficients {
+			writeRow(w, "B062F15-16", i, v, 0)
+		}
+	}
+
+	if d := s.Decimation; d != nil {
+		fmt.Fprint(w, "#\n")
+		writeField(w, "B057F03", "Stage sequence number:", strconv.Itoa(number))
+		writeField(w, "B057F04", "Input sample rate (HZ):", formatFloat(d.InputSampleRate))
+		writeField(w, "B057F05", "Decimation factor:", fmt.Sprintf("%05d", d.Factor))
+		writeField(w, "B057F06", "Decimation offset:", fmt.Sprintf("%05d", d.Offset))
+		writeField(w, "B057F07", "Estimated delay (seconds):", formatFloat(d.Delay))
+		writeField(w, "B057F08", "Correction applied (seconds):", formatFloat(d.Correction))
+	}
+	if g := s.StageGain; g != nil {
+		writeGain(w, number, *g)
+	}
+
+	return nil
+}
+
+// writeGain writes a blockette 58, stage 0 for the overall sensitivity.
+func writeGain(w *bufio.Writer, number int, g stationxml.Gain) {
+	fmt.Fprint(w, "#\n")
+	writeField(w, "B058F03", "Stage sequence number:", strconv.Itoa(number))
+	writeField(w, "B058F04", "Sensitivity:", formatFloat(g.Value))
+	writeField(w, "B058F05", "Frequency of sensitivity:", formatFloat(g.Frequency))
+	writeField(w, "B058F06", "Number of calibrations:", "0")
+}
+
+// writeHeader writes a field of blockettes 50 and 52.
+func writeHeader(w *bufio.Writer, key, label, value string) {
+	fmt.Fprintf(w, "%-12s%-13s%s\n", key, label, value)
+}
+
+// writeField writes a labeled field of a response blockette.
+func writeField(w *bufio.Writer, key, label, value string) {
+	fmt.Fprintf(w, "%-12s%-39s%s\n", key, label, value)
+}
+
+// writeUnits writes the input units field of a filter and the output units
+// field after it.
+func writeUnits(w *bufio.Writer, key string, f *stationxml.Filter) {
+	next := []byte(key)
+	next[6]++
+	writeField(w, key, "Response in units lookup:", formatUnits(f.InputUnits))
+	writeField(w, string(next), "Response out units lookup:", formatUnits(f.OutputUnits))
+}
+
+// writeRow writes a table row, the index first.
+func writeRow(w *bufio.Writer, key string, i int, values ...float64) {
+	fmt.Fprintf(w, "%-12s%4d", key, i)
+	for _, v := range values {
+		fmt.Fprintf(w, "  %s", formatFloat(v))
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// formatUnits formats units as "M/S - Velocity in Meters Per Second".
+func formatUnits(u stationxml.Units) string {
+	if u.Description == "" {
+		return u.Name
+	}
+	return u.Name + " - " + u.Description
+}
+
+// formatFloat formats a number with the 7 significant digits of rdseed, or
+// as many as it takes to read the same number back.
+func formatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'E', 6, 64)
+	if parsed, _ := strconv.ParseFloat(s, 64); parsed != v {
+		s = strconv.FormatFloat(v, 'E', -1, 64)
+	}
+	return s
+}
+
+// formatTime formats a RESP time, YYYY,DDD,HH:MM:SS.FFFF.
+func formatTime(t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%04d,%03d,%02d:%02d:%02d.%04d", t.Year(), t.YearDay(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond()/100000)
+}
@@ -0,0 +1,65 @@
+package resp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+func TestWrite(t *testing.T) {
+	f, err := ReadFile("testdata/RESP.IU.ANMO.00.BHZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "B052F03     Location:    ??\n") || !strings.Contains(buf.String(), "B053F15-18     2  -3.918000E+01  4.912000E+01") {
+		t.Errorf("unexpected output\n%s", buf.String())
+	}
+
+	g, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, g) {
+		t.Errorf("round trip changed the channels\nwant %+v\ngot  %+v", f.Channels, g.Channels)
+	}
+}
+
+func TestFromStationXML(t *testing.T) {
+	d, err := stationxml.ReadFile("../stationxml/testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := FromStationXML(d)
+	// LHZ has no response to write
+	if len(f.Channels) != 2 || !f.Channels[1].EndTime.IsZero() {
+		t.Fatalf("want the 2 BHZ epochs, got %+v", f.Channels)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range f.Channels {
+		want, got := f.Channels[i].Response, g.Channels[i].Response
+		if got.InstrumentSensitivity.Value != want.InstrumentSensitivity.Value || len(got.Stages) != len(want.Stages) {
+			t.Errorf("channel %d: want %+v, got %+v", i, want, got)
+		}
+		if !g.Channels[i].StartTime.Equal(f.Channels[i].StartTime) || !g.Channels[i].EndTime.Equal(f.Channels[i].EndTime) {
+			t.Errorf("channel %d: want epoch %s to %s, got %s to %s", i,
+				f.Channels[i].StartTime, f.Channels[i].EndTime, g.Channels[i].StartTime, g.Channels[i].EndTime)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package response evaluates instrument responses from the stationxml model
+// and removes them from traces, turning counts into ground displacement,
+// velocity or acceleration.
+//
+//	c, err := inv.LookupTrace(&trace)
+//	if err != nil {
+//		// handle error
+//	}
+//	velocity, err := response.Remove(&trace, c.Response, &response.RemoveOptions{
+//		Output:     response.VELOCITY,
+//		WaterLevel: 60,
+//		PreFilter:  [4]float64{0.005, 0.01, 8, 10},
+//		Taper:      0.05,
+//	})
+//
+// Evaluate multiplies the responses of all stages: poles and zeros (Laplace
+// in rad/s or Hz, or digital), coefficients, FIR filters, response lists and
+// stage gains. Deconvolution runs in the frequency domain with the radix-2
+// FFT of this package, so it needs no dependency.
+package response
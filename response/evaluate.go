@@ -0,0 +1,269 @@
+package response
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// Evaluate returns the complex response at each frequency in Hz, as the
+// product of the responses of all stages including their gains. Digital
+// stages are evaluated at the input sample rate of their decimation.
+func Evaluate(r *stationxml.Response, frequencies []float64) ([]complex128, error) {
+	if r == nil || len(r.Stages) == 0 {
+		return nil, fmt.Errorf("response has no stage")
+	}
+
+	h := make([]complex128, len(frequencies))
+	for i := range h {
+		h[i] = 1
+	}
+	for i := range r.Stages {
+		stage := &r.Stages[i]
+		fir, err := getFIRCoefficients(stage.FIR)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", stage.Number, err)
+		}
+		for j, f := range frequencies {
+			v, err := evaluateStage(stage, fir, f)
+			if err != nil {
+				return nil, fmt.Errorf("stage %d: %w", stage.Number, err)
+			}
+			h[j] *= v
+		}
+	}
+
+	return h, nil
+}
+
+// EvaluateStage returns the complex response of a stage at a frequency in
+// Hz, its gain included. A stage without filter is a pure gain. The delay
+// corrected by the decimation of a digital stage is taken off its phase, as
+// data loggers time-stamp samples after the correction.
+func EvaluateStage(s *stationxml.Stage, f float64) (complex128, error) {
+	fir, err := getFIRCoefficients(s.FIR)
+	if err != nil {
+		return 0, err
+	}
+	return evaluateStage(s, fir, f)
+}
+
+// evaluateStage evaluates a stage at a frequency in Hz, given the FIR
+// coefficients of the stage from getFIRCoefficients.
+func evaluateStage(s *stationxml.Stage, fir []float64, f float64) (complex128, error) {
+	gain := complex(1, 0)
+	if s.StageGain != nil {
+		gain = complex(s.StageGain.Value, 0)
+	}
+
+	var (
+		h   complex128
+		err error
+	)
+	switch {
+	case s.PolesZeros != nil:
+		h, err = evaluatePolesZeros(s.PolesZeros, s.Decimation, f)
+	case s.Coefficients != nil:
+		h, err = evaluateCoefficients(s.Coefficients, s.Decimation, f)
+	case s.FIR != nil:
+		h, err = evaluateFIR(fir, s.Decimation, f)
+	case s.ResponseList != nil:
+		h, err = evaluateResponseList(s.ResponseList, f)
+	case s.Polynomial != nil:
+		err = fmt.Errorf("polynomial responses are not supported")
+	default:
+		h = 1
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return h * gain, nil
+}
+
+// Sensitivity returns the magnitude of the response at a frequency in Hz,
+// which should match the instrument sensitivity given at the same
+// frequency.
+func Sensitivity(r *stationxml.Response, frequency float64) (float64, error) {
+	h, err := Evaluate(r, []float64{frequency})
+	if err != nil {
+		return 0, err
+	}
+	return cmplx.Abs(h[0]), nil
+}
+
+// getZ returns z = exp(iωT) of a digital stage, failing without a sample
+// rate to evaluate it at.
+func getZ(d *stationxml.Decimation, f float64) (complex128, error) {
+	if d == nil || d.InputSampleRate <= 0 {
+		return 0, fmt.Errorf("digital filter without input sample rate")
+	}
+	return cmplx.Exp(complex(0, 2*math.Pi*f/d.InputSampleRate)), nil
+}
+
+// getCorrection returns the phase factor taking the corrected delay of a
+// digital stage off its response.
+func getCorrection(d *stationxml.Decimation, f float64) complex128 {
+	if d == nil || d.Correction == 0 {
+		return 1
+	}
+	return cmplx.Exp(complex(0, 2*math.Pi*f*d.Correction))
+}
+
+// evaluatePolesZeros evaluates A0 * Π(x - zero) / Π(x - pole), x being s
+// for analog filters, in rad/s or Hz, and z for digital ones.
+func evaluatePolesZeros(pz *stationxml.PolesZeros, d *stationxml.Decimation, f float64) (complex128, error) {
+	var x complex128
+	switch pz.PzTransferFunctionType {
+	case stationxml.LAPLACE_RADIANS:
+		x = complex(0, 2*math.Pi*f)
+	case stationxml.LAPLACE_HERTZ:
+		x = complex(0, f)
+	case stationxml.DIGITAL_Z:
+		z, err := getZ(d, f)
+		if err != nil {
+			return 0, err
+		}
+		x = z
+	default:
+		return 0, fmt.Errorf("unknown transfer function type %q", pz.PzTransferFunctionType)
+	}
+
+	h := complex(pz.NormalizationFactor, 0)
+	for _, zero := range pz.Zeros {
+		h *= x - zero.Complex()
+	}
+	for _, pole := range pz.Poles {
+		h /= x - pole.Complex()
+	}
+
+	if pz.PzTransferFunctionType == stationxml.DIGITAL_Z {
+		h *= getCorrection(d, f)
+	}
+	return h, nil
+}
+
+// evaluateCoefficients evaluates Σ b_k x^k / Σ a_k x^k for analog filters,
+// and Σ b_k z^-k / Σ a_k z^-k for digital ones. Missing numerators or
+// denominators count as 1.
+func evaluateCoefficients(c *stationxml.Coefficients, d *stationxml.Decimation, f float64) (complex128, error) {
+	var x complex128
+	switch c.CfTransferFunctionType {
+	case stationxml.ANALOG_RADIANS:
+		x = complex(0, 2*math.Pi*f)
+	case stationxml.ANALOG_HERTZ:
+		x = complex(0, f)
+	case stationxml.DIGITAL:
+		if len(c.Numerators) <= 1 && len(c.Denominators) <= 1 {
+			// A gain-only stage, such as a digitizer, needs no sample rate
+			return getPolynomial(c.Numerators, 1) / getPolynomial(c.Denominators, 1), nil
+		}
+		z, err := getZ(d, f)
+		if err != nil {
+			return 0, err
+		}
+		x = 1 / z
+	default:
+		return 0, fmt.Errorf("unknown transfer function type %q", c.CfTransferFunctionType)
+	}
+
+	denominator := getPolynomial(c.Denominators, x)
+	if denominator == 0 {
+		return 0, fmt.Errorf("denominator is zero at %g Hz", f)
+	}
+	h := getPolynomial(c.Numerators, x) / denominator
+
+	if c.CfTransferFunctionType == stationxml.DIGITAL {
+		h *= getCorrection(d, f)
+	}
+	return h, nil
+}
+
+// getPolynomial evaluates Σ c_k x^k, 1 when there is no coefficient.
+func getPolynomial(coefficients []float64, x complex128) complex128 {
+	if len(coefficients) == 0 {
+		return 1
+	}
+
+	var v complex128
+	for k := len(coefficients) - 1; k >= 0; k-- {
+		v = v*x + complex(coefficients[k], 0)
+	}
+	return v
+}
+
+// getFIRCoefficients returns all the coefficients of a FIR filter, nil for
+// none, mirroring those of symmetric filters.
+func getFIRCoefficients(fir *stationxml.FIR) ([]float64, error) {
+	if fir == nil {
+		return nil, nil
+	}
+
+	coefficients := fir.NumeratorCoefficients
+	n := len(coefficients)
+	switch fir.Symmetry {
+	case stationxml.SYMMETRY_NONE, "":
+		return coefficients, nil
+	case stationxml.SYMMETRY_EVEN:
+		return append(append([]float64{}, coefficients...), reversed(coefficients)...), nil
+	case stationxml.SYMMETRY_ODD:
+		if n == 0 {
+			return nil, nil
+		}
+		return append(append([]float64{}, coefficients...), reversed(coefficients[:n-1])...), nil
+	default:
+		return nil, fmt.Errorf("unknown FIR symmetry %q", fir.Symmetry)
+	}
+}
+
+// evaluateFIR evaluates Σ h_k z^-k over the coefficients of a FIR filter,
+// see getFIRCoefficients.
+func evaluateFIR(coefficients []float64, d *stationxml.Decimation, f float64) (complex128, error) {
+	if len(coefficients) == 0 {
+		return 1, nil
+	}
+
+	z, err := getZ(d, f)
+	if err != nil {
+		return 0, err
+	}
+	return getPolynomial(coefficients, 1/z) * getCorrection(d, f), nil
+}
+
+// reversed returns a reversed copy of s.
+func reversed(s []float64) []float64 {
+	r := make([]float64, len(s))
+	for i, v := range s {
+		r[len(s)-1-i] = v
+	}
+	return r
+}
+
+// evaluateResponseList interpolates amplitude and phase linearly between the
+// listed frequencies, holding the end values outside them.
+func evaluateResponseList(l *stationxml.ResponseList, f float64) (complex128, error) {
+	elements := l.Elements
+	if len(elements) == 0 {
+		return 0, fmt.Errorf("empty response list")
+	}
+
+	amplitude, phase := elements[0].Amplitude, elements[0].Phase
+	for i := 1; i < len(elements); i++ {
+		prev, next := &elements[i-1], &elements[i]
+		if f < prev.Frequency {
+			break
+		}
+		if f >= next.Frequency || next.Frequency <= prev.Frequency {
+			amplitude, phase = next.Amplitude, next.Phase
+			continue
+		}
+		ratio := (f - prev.Frequency) / (next.Frequency - prev.Frequency)
+		amplitude = prev.Amplitude + ratio*(next.Amplitude-prev.Amplitude)
+		phase = prev.Phase + ratio*(next.Phase-prev.Phase)
+		break
+	}
+
+	return cmplx.Rect(amplitude, phase*math.Pi/180), nil
+}
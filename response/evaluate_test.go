@@ -0,0 +1,147 @@
+package response
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// getGeophoneResponse returns the response of a 1 Hz geophone with 0.7
+// damping and 30 V/(m/s), digitized at 10^6 counts/V.
+func getGeophoneResponse() *stationxml.Response {
+	w0, h := 2*math.Pi, 0.7
+	return &stationxml.Response{
+		InstrumentSensitivity: &stationxml.Sensitivity{
+			Value: 3e7, Frequency: 10,
+			InputUnits: stationxml.Units{Name: "M/S"}, OutputUnits: stationxml.Units{Name: "COUNTS"},
+		},
+		Stages: []stationxml.Stage{{
+			Number: 1,
+			PolesZeros: &stationxml.PolesZeros{
+				Filter: stationxml.Filter{
+					InputUnits: stationxml.Units{Name: "M/S"}, OutputUnits: stationxml.Units{Name: "V"},
+				},
+				PzTransferFunctionType: stationxml.LAPLACE_RADIANS,
+				NormalizationFactor:    1, NormalizationFrequency: 10,
+				Zeros: []stationxml.PoleZero{{}, {}},
+				Poles: []stationxml.PoleZero{
+					{Real: -w0 * h, Imaginary: w0 * math.Sqrt(1-h*h)},
+					{Real: -w0 * h, Imaginary: -w0 * math.Sqrt(1-h*h)},
+				},
+			},
+			StageGain: &stationxml.Gain{Value: 30, Frequency: 10},
+		}, {
+			Number: 2,
+			Coefficients: &stationxml.Coefficients{
+				Filter: stationxml.Filter{
+					InputUnits: stationxml.Units{Name: "V"}, OutputUnits: stationxml.Units{Name: "COUNTS"},
+				},
+				CfTransferFunctionType: stationxml.DIGITAL,
+			},
+			StageGain: &stationxml.Gain{Value: 1e6},
+		}},
+	}
+}
+
+// getGeophoneMagnitude returns the analytic magnitude of the geophone
+// response at f.
+func getGeophoneMagnitude(f float64) float64 {
+	return 3e7 * f * f / math.Sqrt(math.Pow(1-f*f, 2)+math.Pow(2*0.7*f, 2))
+}
+
+func TestEvaluate(t *testing.T) {
+	r := getGeophoneResponse()
+	frequencies := []float64{0.1, 1, 5, 10, 40}
+	h, err := Evaluate(r, frequencies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range frequencies {
+		if want := getGeophoneMagnitude(f); math.Abs(cmplx.Abs(h[i])-want) > want*1e-9 {
+			t.Errorf("%g Hz: want magnitude %g, got %g", f, want, cmplx.Abs(h[i]))
+		}
+	}
+	// At the natural frequency the phase leads by 90 degrees
+	if phase := cmplx.Phase(h[1]); math.Abs(phase-math.Pi/2) > 1e-9 {
+		t.Errorf("want phase π/2 at 1 Hz, got %g", phase)
+	}
+
+	sensitivity, err := Sensitivity(r, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(sensitivity/r.InstrumentSensitivity.Value-1) > 1e-3 {
+		t.Errorf("want sensitivity near %g, got %g", r.InstrumentSensitivity.Value, sensitivity)
+	}
+
+	if _, err := Evaluate(&stationxml.Response{}, frequencies); err == nil {
+		t.Error("want error for a response without stage")
+	}
+}
+
+func TestEvaluateStage(t *testing.T) {
+	digital := &stationxml.Decimation{InputSampleRate: 100, Factor: 1}
+	tests := []struct {
+		name  string
+		stage stationxml.Stage
+		f     float64
+		want  complex128
+	}{
+		{"pole in Hz", stationxml.Stage{PolesZeros: &stationxml.PolesZeros{
+			PzTransferFunctionType: stationxml.LAPLACE_HERTZ, NormalizationFactor: 1,
+			Poles: []stationxml.PoleZero{{Real: -1}},
+		}}, 1, 1 / complex(1, 1)},
+		{"pole in rad/s", stationxml.Stage{PolesZeros: &stationxml.PolesZeros{
+			PzTransferFunctionType: stationxml.LAPLACE_RADIANS, NormalizationFactor: 2 * math.Pi,
+			Poles: []stationxml.PoleZero{{Real: -2 * math.Pi}},
+		}}, 1, 1 / complex(1, 1)},
+		{"gain only", stationxml.Stage{StageGain: &stationxml.Gain{Value: 1e6}}, 3, 1e6},
+		{"IIR at 0 Hz", stationxml.Stage{Coefficients: &stationxml.Coefficients{
+			CfTransferFunctionType: stationxml.DIGITAL, Numerators: []float64{1}, Denominators: []float64{1, -0.5},
+		}, Decimation: digital}, 0, 2},
+		{"IIR at Nyquist", stationxml.Stage{Coefficients: &stationxml.Coefficients{
+			CfTransferFunctionType: stationxml.DIGITAL, Numerators: []float64{1}, Denominators: []float64{1, -0.5},
+		}, Decimation: digital}, 50, 1 / 1.5},
+		{"analog coefficients", stationxml.Stage{Coefficients: &stationxml.Coefficients{
+			CfTransferFunctionType: stationxml.ANALOG_HERTZ, Numerators: []float64{0, 1},
+		}}, 2, complex(0, 2)},
+		{"FIR even", stationxml.Stage{FIR: &stationxml.FIR{
+			Symmetry: stationxml.SYMMETRY_EVEN, NumeratorCoefficients: []float64{0.25, 0.25},
+		}, Decimation: digital}, 0, 1},
+		{"FIR odd", stationxml.Stage{FIR: &stationxml.FIR{
+			Symmetry: stationxml.SYMMETRY_ODD, NumeratorCoefficients: []float64{0.25, 0.5},
+		}, Decimation: digital}, 50, 0},
+		{"FIR delay corrected", stationxml.Stage{FIR: &stationxml.FIR{
+			NumeratorCoefficients: []float64{0, 1},
+		}, Decimation: &stationxml.Decimation{InputSampleRate: 100, Factor: 1, Delay: 0.01, Correction: 0.01}}, 13, 1},
+		{"response list", stationxml.Stage{ResponseList: &stationxml.ResponseList{
+			Elements: []stationxml.ResponseListElement{{Frequency: 1, Amplitude: 2, Phase: 0}, {Frequency: 3, Amplitude: 4, Phase: 180}},
+		}}, 2, complex(0, 3)},
+		{"response list above", stationxml.Stage{ResponseList: &stationxml.ResponseList{
+			Elements: []stationxml.ResponseListElement{{Frequency: 1, Amplitude: 2, Phase: 0}, {Frequency: 3, Amplitude: 4, Phase: 0}},
+		}}, 9, 4},
+	}
+	for _, test := range tests {
+		got, err := EvaluateStage(&test.stage, test.f)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if cmplx.Abs(got-test.want) > 1e-9*math.Max(1, cmplx.Abs(test.want)) {
+			t.Errorf("%s: want %v, got %v", test.name, test.want, got)
+		}
+	}
+
+	for name, stage := range map[string]stationxml.Stage{
+		"FIR without sample rate": {FIR: &stationxml.FIR{NumeratorCoefficients: []float64{0.5, 0.5}}},
+		"polynomial":              {Polynomial: &stationxml.Polynomial{Coefficients: []float64{1, 2}}},
+		"unknown type":            {PolesZeros: &stationxml.PolesZeros{PzTransferFunctionType: "LAPLACE"}},
+		"unknown symmetry":        {FIR: &stationxml.FIR{Symmetry: "B"}, Decimation: digital},
+	} {
+		if _, err := EvaluateStage(&stage, 1); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package response
+
+import (
+	"math"
+	"math/bits"
+)
+
+// getFFTLength returns the smallest power of two holding n samples.
+func getFFTLength(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// fft transforms x in place with the iterative radix-2 Cooley-Tukey
+// algorithm, its length being a power of two. The inverse transform is
+// scaled by 1/n, so that it undoes the forward one.
+func fft(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// Bit-reversal permutation
+	shift := bits.UintSize - bits.Len(uint(n-1))
+	for i := range x {
+		j := int(bits.Reverse(uint(i)) >> shift)
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	twiddles := make([]complex128, n/2)
+	for k := range twiddles {
+		angle := sign * 2 * math.Pi * float64(k) / float64(n)
+		twiddles[k] = complex(math.Cos(angle), math.Sin(angle))
+	}
+	for size := 2; size <= n; size <<= 1 {
+		stride := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], twiddles[k*stride]*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+			}
+		}
+	}
+
+	if inverse {
+		scale := complex(1/float64(n), 0)
+		for i := range x {
+			x[i] *= scale
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package response
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+func TestFFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 2, 8, 64} {
+		x := make([]complex128, n)
+		for i := range x {
+			x[i] = complex(rng.NormFloat64(), rng.NormFloat64())
+		}
+
+		// Compare with the direct DFT
+		want := make([]complex128, n)
+		for k := range want {
+			for j, v := range x {
+				want[k] += v * cmplx.Exp(complex(0, -2*math.Pi*float64(j*k)/float64(n)))
+			}
+		}
+		got := append([]complex128{}, x...)
+		fft(got, false)
+		for k := range got {
+			if cmplx.Abs(got[k]-want[k]) > 1e-9 {
+				t.Fatalf("n=%d, bin %d: want %v, got %v", n, k, want[k], got[k])
+			}
+		}
+
+		fft(got, true)
+		for i := range got {
+			if cmplx.Abs(got[i]-x[i]) > 1e-12 {
+				t.Fatalf("n=%d, sample %d: inverse gave %v, want %v", n, i, got[i], x[i])
+			}
+		}
+	}
+
+	for n, want := range map[int]int{0: 1, 1: 1, 2: 2, 3: 4, 1000: 1024, 1024: 1024} {
+		if got := getFFTLength(n); got != want {
+			t.Errorf("getFFTLength(%d): want %d, got %d", n, want, got)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package response
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// Ground motions output by Remove, 0 keeping the input units of the
+// response.
+const (
+	DISPLACEMENT = 1 // M
+	VELOCITY     = 2 // M/S
+	ACCELERATION = 3 // M/S**2
+)
+
+// RemoveOptions configures Remove and Deconvolve. A nil *RemoveOptions
+// outputs velocity with a 60 dB water level and 5% tapers.
+type RemoveOptions struct {
+	Output     int        // DISPLACEMENT, VELOCITY or ACCELERATION, 0 for the input units
+	WaterLevel float64    // dB below the peak of the response, 0 for none
+	PreFilter  [4]float64 // Corners f1 < f2 < f3 < f4 in Hz of a cosine band-pass, zeros for none
+	Taper      float64    // Fraction of the samples tapered at each end, up to 0.5
+}
+
+// getRemoveOptions returns the defaults for nil options.
+func getRemoveOptions(options *RemoveOptions) *RemoveOptions {
+	if options == nil {
+		return &RemoveOptions{Output: VELOCITY, WaterLevel: 60, Taper: 0.05}
+	}
+	return options
+}
+
+// Remove deconvolves the instrument response from a trace, returning its
+// samples in the ground motion units asked for, see Deconvolve.
+func Remove(trace *mseedio.Trace, r *stationxml.Response, options *RemoveOptions) ([]float64, error) {
+	samples, err := trace.Float64s()
+	if err != nil {
+		return nil, err
+	}
+	return Deconvolve(samples, trace.SampleRate, r, options)
+}
+
+// Deconvolve removes the instrument response from samples in counts. The
+// linear trend is removed and the ends tapered before the samples, padded to
+// avoid wrap-around, are divided in the frequency domain by the response.
+// The water level clips the inverse response where the response falls below
+// that level under its peak, and the pre-filter limits the band in which
+// noise is amplified. Output conversion integrates or differentiates in the
+// frequency domain, from the input units of the response (M, M/S or M/S**2).
+func Deconvolve(samples []float64, sampleRate float64, r *stationxml.Response, options *RemoveOptions) ([]float64, error) {
+	options = getRemoveOptions(options)
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %g", sampleRate)
+	}
+	if len(samples) == 0 {
+		return nil, nil
+	}
+	if options.Taper < 0 || options.Taper > 0.5 {
+		return nil, fmt.Errorf("taper fraction %g out of range", options.Taper)
+	}
+	pre := options.PreFilter
+	if pre != [4]float64{} && !(pre[0] >= 0 && pre[0] < pre[1] && pre[1] < pre[2] && pre[2] < pre[3]) {
+		return nil, fmt.Errorf("pre-filter corners %v not increasing", pre)
+	}
+
+	// Powers of iω converting from the input units to the output ones
+	order := 0
+	if options.Output != 0 {
+		input, err := getInputOrder(r)
+		if err != nil {
+			return nil, err
+		}
+		if options.Output < DISPLACEMENT || options.Output > ACCELERATION {
+			return nil, fmt.Errorf("unknown output %d", options.Output)
+		}
+		order = options.Output - input
+	}
+
+	n := len(samples)
+	nfft := getFFTLength(2 * n)
+	x := make([]complex128, nfft)
+	for i, v := range getTapered(getDetrended(samples), options.Taper) {
+		x[i] = complex(v, 0)
+	}
+	fft(x, false)
+
+	frequencies := make([]float64, nfft/2+1)
+	for k := range frequencies {
+		frequencies[k] = float64(k) * sampleRate / float64(nfft)
+	}
+	h, err := Evaluate(r, frequencies)
+	if err != nil {
+		return nil, err
+	}
+	inverse := getInverse(h, options.WaterLevel)
+
+	for k, f := range frequencies {
+		v := x[k] * inverse[k] * getConversion(f, order)
+		if pre != [4]float64{} {
+			v *= complex(getCosineTaper(f, pre), 0)
+		}
+		x[k] = v
+	}
+	// The spectrum of real samples is Hermitian
+	x[nfft/2] = complex(real(x[nfft/2]), 0)
+	for k := 1; k < nfft/2; k++ {
+		x[nfft-k] = cmplx.Conj(x[k])
+	}
+	fft(x, true)
+
+	output := make([]float64, n)
+	for i := range output {
+		output[i] = real(x[i])
+	}
+	return output, nil
+}
+
+// RemoveSensitivity divides the samples of a trace by the overall
+// sensitivity, which holds in the flat band of the response only.
+func RemoveSensitivity(trace *mseedio.Trace, s *stationxml.Sensitivity) ([]float64, error) {
+	if s == nil || s.Value == 0 {
+		return nil, fmt.Errorf("no sensitivity")
+	}
+
+	samples, err := trace.Float64s()
+	if err != nil {
+		return nil, err
+	}
+	for i := range samples {
+		samples[i] /= s.Value
+	}
+	return samples, nil
+}
+
+// getInputOrder returns the ground motion the response takes as input, from
+// the instrument sensitivity or else the first stage.
+func getInputOrder(r *stationxml.Response) (int, error) {
+	if r == nil {
+		return 0, fmt.Errorf("no response")
+	}
+
+	var units string
+	if r.InstrumentSensitivity != nil {
+		units = r.InstrumentSensitivity.InputUnits.Name
+	} else if len(r.Stages) > 0 {
+		units = getStageInputUnits(&r.Stages[0])
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(units)) {
+	case "M", "METER", "METERS":
+		return DISPLACEMENT, nil
+	case "M/S", "M/SEC":
+		return VELOCITY, nil
+	case "M/S**2", "M/S2", "M/S/S", "M/SEC**2":
+		return ACCELERATION, nil
+	}
+	return 0, fmt.Errorf("cannot convert from input units %q", units)
+}
+
+// getStageInputUnits returns the input units of the filter of a stage.
+func getStageInputUnits(s *stationxml.Stage) string {
+	if f := s.Filter(); f != nil {
+		return f.InputUnits.Name
+	}
+	return ""
+}
+
+// getInverse inverts the response, raising its magnitude to the water level
+// where it falls below, with its phase kept. Zeros invert to zero.
+func getInverse(h []complex128, waterLevel float64) []complex128 {
+	level := 0.0
+	if waterLevel != 0 {
+		peak := 0.0
+		for _, v := range h {
+			peak = math.Max(peak, cmplx.Abs(v))
+		}
+		level = peak * math.Pow(10, -waterLevel/20)
+	}
+
+	inverse := make([]complex128, len(h))
+	for i, v := range h {
+		magnitude := cmplx.Abs(v)
+		if magnitude == 0 {
+			continue
+		}
+		if magnitude < level {
+			v *= complex(level/magnitude, 0)
+		}
+		inverse[i] = 1 / v
+	}
+	return inverse
+}
+
+// getConversion returns (iω)^order, integrating for negative orders, and
+// zero at 0 Hz then.
+func getConversion(f float64, order int) complex128 {
+	iw := complex(0, 2*math.Pi*f)
+	c := complex(1, 0)
+	for i := 0; i < order; i++ {
+		c *= iw
+	}
+	for i := 0; i > order; i-- {
+		if iw == 0 {
+			return 0
+		}
+		c /= iw
+	}
+	return c
+}
+
+// getCosineTaper returns the band-pass weight at f, rising from f1 to f2 and
+// falling from f3 to f4 along half cosines.
+func getCosineTaper(f float64, corners [4]float64) float64 {
+	f1, f2, f3, f4 := corners[0], corners[1], corners[2], corners[3]
+	switch {
+	case f <= f1 || f >= f4:
+		return 0
+	case f < f2:
+		return 0.5 * (1 - math.Cos(math.Pi*(f-f1)/(f2-f1)))
+	case f > f3:
+		return 0.5 * (1 + math.Cos(math.Pi*(f-f3)/(f4-f3)))
+	}
+	return 1
+}
+
+// getDetrended returns the samples minus their least-squares line.
+func getDetrended(samples []float64) []float64 {
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX float64
+	for i, y := range samples {
+		x := float64(i)
+		sumX, sumY, sumXY, sumXX = sumX+x, sumY+y, sumXY+x*y, sumXX+x*x
+	}
+
+	slope := 0.0
+	if d := n*sumXX - sumX*sumX; d != 0 {
+		slope = (n*sumXY - sumX*sumY) / d
+	}
+	intercept := (sumY - slope*sumX) / n
+
+	detrended := make([]float64, len(samples))
+	for i, y := range samples {
+		detrended[i] = y - intercept - slope*float64(i)
+	}
+	return detrended
+}
+
+// getTapered applies a Hann taper to a fraction of the samples at each end,
+// in place.
+func getTapered(samples []float64, fraction float64) []float64 {
+	width := int(fraction * float64(len(samples)))
+	for i := 0; i < width; i++ {
+		w := 0.5 * (1 - math.Cos(math.Pi*float64(i)/float64(width)))
+		samples[i] *= w
+		samples[len(samples)-1-i] *= w
+	}
+	return samples
+}
@@ -0,0 +1,155 @@
+package response
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// getSineTrace returns 20 seconds at 100 Hz of a sine of 10^4 counts at f.
+func getSineTrace(f float64) *mseedio.Trace {
+	trace := &mseedio.Trace{
+		NetworkCode: "XX", StationCode: "TEST", ChannelCode: "HHZ",
+		SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for i := 0; i < 2000; i++ {
+		trace.Samples = append(trace.Samples, int32(math.Round(1e4*math.Sin(2*math.Pi*f*float64(i)/100))))
+	}
+	return trace
+}
+
+// getPeak returns the largest magnitude of the middle half of samples, away
+// from the tapers.
+func getPeak(samples []float64) float64 {
+	peak := 0.0
+	for _, v := range samples[len(samples)/4 : 3*len(samples)/4] {
+		peak = math.Max(peak, math.Abs(v))
+	}
+	return peak
+}
+
+func TestRemove(t *testing.T) {
+	r := getGeophoneResponse()
+	trace := getSineTrace(5)
+	velocity := 1e4 / getGeophoneMagnitude(5)
+	w := 2 * math.Pi * 5
+	band := [4]float64{0.5, 1, 20, 40}
+
+	tests := []struct {
+		name    string
+		options *RemoveOptions
+		want    float64
+	}{
+		{"velocity", &RemoveOptions{Output: VELOCITY, WaterLevel: 60, PreFilter: band, Taper: 0.05}, velocity},
+		{"displacement", &RemoveOptions{Output: DISPLACEMENT, WaterLevel: 60, PreFilter: band, Taper: 0.05}, velocity / w},
+		{"acceleration", &RemoveOptions{Output: ACCELERATION, PreFilter: band, Taper: 0.05}, velocity * w},
+		{"no water level", &RemoveOptions{Output: VELOCITY, PreFilter: band}, velocity},
+		{"stopped by pre-filter", &RemoveOptions{Output: VELOCITY, PreFilter: [4]float64{10, 15, 20, 40}, Taper: 0.05}, 0},
+	}
+	for _, test := range tests {
+		output, err := Remove(trace, r, test.options)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if len(output) != len(trace.Samples) {
+			t.Fatalf("%s: want %d samples, got %d", test.name, len(trace.Samples), len(output))
+		}
+		if peak := getPeak(output); math.Abs(peak-test.want) > 0.01*velocity*math.Max(1, test.want/velocity) {
+			t.Errorf("%s: want peak %g, got %g", test.name, test.want, peak)
+		}
+	}
+
+	// Nil options are the defaults
+	defaults, err := Remove(trace, r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	explicit, err := Remove(trace, r, &RemoveOptions{Output: VELOCITY, WaterLevel: 60, Taper: 0.05})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range defaults {
+		if defaults[i] != explicit[i] {
+			t.Fatalf("sample %d: nil options gave %g, the defaults %g", i, defaults[i], explicit[i])
+		}
+	}
+}
+
+func TestRemoveWaterLevel(t *testing.T) {
+	// Far below the natural frequency the response is down 40 dB from its
+	// peak, which a 20 dB water level keeps from being amplified in full
+	r := getGeophoneResponse()
+	trace := getSineTrace(0.1)
+
+	full, err := Remove(trace, r, &RemoveOptions{Output: VELOCITY})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clipped, err := Remove(trace, r, &RemoveOptions{Output: VELOCITY, WaterLevel: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if getPeak(clipped) > getPeak(full)/5 {
+		t.Errorf("want the water level to cut the amplification, got peaks %g and %g", getPeak(full), getPeak(clipped))
+	}
+}
+
+func TestRemoveErrors(t *testing.T) {
+	r := getGeophoneResponse()
+	trace := getSineTrace(5)
+
+	pressure := getGeophoneResponse()
+	pressure.InstrumentSensitivity.InputUnits.Name = "PA"
+
+	ascii := &mseedio.Trace{SampleRate: 1, Samples: []any{"a", "b"}}
+	tests := map[string]func() error{
+		"units": func() error {
+			_, err := Remove(trace, pressure, nil)
+			return err
+		},
+		"pre-filter": func() error {
+			_, err := Remove(trace, r, &RemoveOptions{PreFilter: [4]float64{1, 0.5, 20, 40}})
+			return err
+		},
+		"taper": func() error {
+			_, err := Remove(trace, r, &RemoveOptions{Taper: 0.6})
+			return err
+		},
+		"output": func() error {
+			_, err := Remove(trace, r, &RemoveOptions{Output: 4})
+			return err
+		},
+		"sample rate": func() error {
+			_, err := Deconvolve([]float64{1, 2}, 0, r, nil)
+			return err
+		},
+		"ASCII": func() error {
+			_, err := Remove(ascii, r, nil)
+			return err
+		},
+	}
+	for name, f := range tests {
+		if err := f(); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	// Input units are kept without conversion
+	if _, err := Remove(trace, pressure, &RemoveOptions{}); err != nil {
+		t.Errorf("want no error keeping the input units, got %v", err)
+	}
+}
+
+func TestRemoveSensitivity(t *testing.T) {
+	trace := &mseedio.Trace{SampleRate: 1, Samples: []any{int32(300), float32(-600), 1.5e7}}
+	output, err := RemoveSensitivity(trace, &stationxml.Sensitivity{Value: 3e7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output[0] != 1e-5 || output[1] != -2e-5 || output[2] != 0.5 {
+		t.Fatalf("unexpected output %v", output)
+	}
+}
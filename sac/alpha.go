@@ -0,0 +1,162 @@
+package sac
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	ALPHA_HEADER_LINES = 30 // 14 lines of floats, 8 of integers and 8 of characters
+	ALPHA_FLOAT_WIDTH  = 15
+	ALPHA_INT_WIDTH    = 10
+)
+
+// WriteAlpha writes the file as alphanumeric SAC, five floats or integers
+// per line, three character fields per line after KSTNM and KEVNM.
+func (f *File) WriteAlpha(w io.Writer) error {
+	if err := f.checkExtra(); err != nil {
+		return err
+	}
+
+	r := f.Header.getRaw(len(f.Data))
+	bw := bufio.NewWriter(w)
+	for i, v := range r.floats {
+		writeAlphaFloat(bw, v, i)
+	}
+	for i, v := range r.ints {
+		fmt.Fprintf(bw, "%*d", ALPHA_INT_WIDTH, v)
+		if i%5 == 4 {
+			bw.WriteByte('\n')
+		}
+	}
+	for i, v := range r.chars {
+		_, length := getCharOffset(i)
+		bw.WriteString(formatChars(v, length))
+		if i == kevnm || i > kevnm && (i-kevnm)%3 == 0 {
+			bw.WriteByte('\n')
+		}
+	}
+
+	var n int
+	for _, samples := range [][]float32{f.Data, f.Extra} {
+		for _, v := range samples {
+			writeAlphaFloat(bw, v, n)
+			n++
+		}
+	}
+	if n%5 != 0 {
+		bw.WriteByte('\n')
+	}
+
+	return bw.Flush()
+}
+
+// writeAlphaFloat writes the i-th float of a block, ending every fifth line.
+func writeAlphaFloat(w *bufio.Writer, v float32, i int) {
+	fmt.Fprintf(w, "%*s", ALPHA_FLOAT_WIDTH, strconv.FormatFloat(float64(v), 'g', 7, 32))
+	if i%5 == 4 {
+		w.WriteByte('\n')
+	}
+}
+
+// parseAlpha reads an alphanumeric file. Values are read by column, so that
+// wide numbers touching each other still parse.
+func parseAlpha(data []byte) (*File, error) {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(lines) < ALPHA_HEADER_LINES {
+		return nil, fmt.Errorf("not a SAC file, %d lines in alphanumeric header: %w", len(lines), io.ErrUnexpectedEOF)
+	}
+
+	r := &rawHeader{}
+	floats, err := parseAlphaValues(lines[:14], ALPHA_FLOAT_WIDTH, 1)
+	if err != nil {
+		return nil, err
+	}
+	ints, err := parseAlphaValues(lines[14:22], ALPHA_INT_WIDTH, 15)
+	if err != nil {
+		return nil, err
+	}
+	if len(floats) != len(r.floats) || len(ints) != len(r.ints) {
+		return nil, fmt.Errorf("not a SAC file, %d floats and %d integers in header", len(floats), len(ints))
+	}
+	for i, v := range floats {
+		r.floats[i] = float32(v)
+	}
+	for i, v := range ints {
+		r.ints[i] = int32(v)
+	}
+	if r.ints[nvhdr] != HEADER_VERSION {
+		return nil, fmt.Errorf("not a SAC file of header version %d, got %d", HEADER_VERSION, r.ints[nvhdr])
+	}
+
+	// Lines may be short of the trailing spaces of their last field
+	for i := range r.chars {
+		line, column, length := 0, 0, 8
+		switch {
+		case i == kevnm:
+			column, length = 8, 16
+		case i > kevnm:
+			line, column = 1+(i-khole)/3, (i-khole)%3*8
+		}
+		text := lines[22+line] + strings.Repeat(" ", 24)
+		r.chars[i] = parseChars(text[column : column+length])
+	}
+
+	f := &File{}
+	f.Header.setRaw(r)
+	n := int(r.ints[npts])
+	if n < 0 {
+		return nil, fmt.Errorf("invalid number of points %d", n)
+	}
+	components := 1
+	if f.hasExtra() {
+		components = 2
+	}
+
+	values, err := parseAlphaValues(lines[ALPHA_HEADER_LINES:], ALPHA_FLOAT_WIDTH, ALPHA_HEADER_LINES+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) < n*components {
+		return nil, fmt.Errorf("%d points in header, got %d values: %w", n, len(values), io.ErrUnexpectedEOF)
+	}
+	f.Data = make([]float32, n)
+	for i := range f.Data {
+		f.Data[i] = float32(values[i])
+	}
+	if components == 2 {
+		f.Extra = make([]float32, n)
+		for i := range f.Extra {
+			f.Extra[i] = float32(values[n+i])
+		}
+	}
+
+	return f, nil
+}
+
+// parseAlphaValues reads the numbers of lines in columns of a width, the
+// first line being numbered first in errors.
+func parseAlphaValues(lines []string, width, first int) ([]float64, error) {
+	var values []float64
+	for i, line := range lines {
+		for column := 0; column < len(line); column += width {
+			end := column + width
+			if end > len(line) {
+				end = len(line)
+			}
+			field := strings.TrimSpace(line[column:end])
+			if field == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid number %q", first+i, field)
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,135 @@
+package sac
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// Parse reads a binary SAC file, telling its byte order from the header
+// version, or else an alphanumeric one.
+func Parse(r io.Reader) (*File, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if order := getByteOrder(data); order != nil {
+		return parseBinary(data, order)
+	}
+	return parseAlpha(data)
+}
+
+// Write writes the file as binary SAC in a byte order, MSBFIRST or LSBFIRST.
+func (f *File) Write(w io.Writer, bitOrder int) error {
+	var order binary.ByteOrder
+	switch bitOrder {
+	case mseedio.MSBFIRST:
+		order = binary.BigEndian
+	case mseedio.LSBFIRST:
+		order = binary.LittleEndian
+	default:
+		return fmt.Errorf("invalid bit order %d", bitOrder)
+	}
+	if err := f.checkExtra(); err != nil {
+		return err
+	}
+
+	r := f.Header.getRaw(len(f.Data))
+	buf := make([]byte, HEADER_LENGTH+4*(len(f.Data)+len(f.Extra)))
+	for i, v := range r.floats {
+		order.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	for i, v := range r.ints {
+		order.PutUint32(buf[280+4*i:], uint32(v))
+	}
+	for i, v := range r.chars {
+		offset, length := getCharOffset(i)
+		copy(buf[440+offset:], formatChars(v, length))
+	}
+	for i, v := range append(append([]float32{}, f.Data...), f.Extra...) {
+		order.PutUint32(buf[HEADER_LENGTH+4*i:], math.Float32bits(v))
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(buf); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// getByteOrder returns the byte order in which the header version of a
+// binary file reads HEADER_VERSION, nil if neither does.
+func getByteOrder(data []byte) binary.ByteOrder {
+	if len(data) < HEADER_LENGTH {
+		return nil
+	}
+	for _, order := range []binary.ByteOrder{binary.BigEndian, binary.LittleEndian} {
+		if order.Uint32(data[280+4*nvhdr:]) == HEADER_VERSION {
+			return order
+		}
+	}
+	return nil
+}
+
+// parseBinary reads a binary file in a byte order.
+func parseBinary(data []byte, order binary.ByteOrder) (*File, error) {
+	r := &rawHeader{}
+	for i := range r.floats {
+		r.floats[i] = math.Float32frombits(order.Uint32(data[4*i:]))
+	}
+	for i := range r.ints {
+		r.ints[i] = int32(order.Uint32(data[280+4*i:]))
+	}
+	for i := range r.chars {
+		offset, length := getCharOffset(i)
+		r.chars[i] = parseChars(string(data[440+offset : 440+offset+length]))
+	}
+
+	f := &File{}
+	f.Header.setRaw(r)
+	n := int(r.ints[npts])
+	if n < 0 {
+		return nil, fmt.Errorf("invalid number of points %d", n)
+	}
+	components := 1
+	if f.hasExtra() {
+		components = 2
+	}
+	if len(data) < HEADER_LENGTH+4*n*components {
+		return nil, fmt.Errorf("%d points in header, file too short: %w", n, io.ErrUnexpectedEOF)
+	}
+
+	f.Data = make([]float32, n)
+	for i := range f.Data {
+		f.Data[i] = math.Float32frombits(order.Uint32(data[HEADER_LENGTH+4*i:]))
+	}
+	if components == 2 {
+		f.Extra = make([]float32, n)
+		for i := range f.Extra {
+			f.Extra[i] = math.Float32frombits(order.Uint32(data[HEADER_LENGTH+4*(n+i):]))
+		}
+	}
+
+	return f, nil
+}
+
+// hasExtra reports whether the file type holds a second component.
+func (f *File) hasExtra() bool {
+	return f.Header.IfType == IRLIM || f.Header.IfType == IAMPH || f.Header.IfType != UNDEFINED && !f.Header.Leven
+}
+
+// checkExtra checks that the second component matches the file type.
+func (f *File) checkExtra() error {
+	switch {
+	case f.hasExtra() && len(f.Extra) != len(f.Data):
+		return fmt.Errorf("want %d points in second component, got %d", len(f.Data), len(f.Extra))
+	case !f.hasExtra() && len(f.Extra) != 0:
+		return fmt.Errorf("second component in an evenly sampled time series")
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+// Package sac converts traces to and from SAC, the Seismic Analysis Code
+// file format, in binary form of either byte order or in alphanumeric form.
+//
+//	f, err := sac.ToSAC(&trace, &sac.Coordinates{Latitude: 34.9459, Longitude: -106.4572})
+//	if err != nil {
+//		// handle error
+//	}
+//	err = f.Write(w, mseedio.MSBFIRST)
+//
+// Parse and ReadFile tell binary files from their header version, in either
+// byte order, and read others as alphanumeric. FromSAC turns an evenly
+// sampled time series back into a trace, and AppendOptions with Int32s
+// encode it to miniSEED again:
+//
+//	f, err := sac.ReadFile("IU.ANMO.00.BHZ.sac")
+//	if err != nil {
+//		// handle error
+//	}
+//	options, err := f.AppendOptions()
+//	if err != nil {
+//		// handle error
+//	}
+//	err = ms.Append(f.Int32s(), options)
+//
+// Codes are stored in KNETWK, KSTNM, KHOLE and KCMPNM, and the start time as
+// the reference time to the millisecond plus the begin time B.
+package sac
@@ -0,0 +1,190 @@
+package sac
+
+import "strings"
+
+const (
+	HEADER_LENGTH  = 632 // Bytes of the binary header: 70 floats, 40 integers and 192 characters
+	HEADER_VERSION = 6   // NVHDR of the files read and written
+	UNDEFINED      = -12345
+)
+
+// File types (IFTYPE)
+const (
+	ITIME = 1 // Time series
+	IRLIM = 2 // Spectrum, real and imaginary parts
+	IAMPH = 3 // Spectrum, amplitude and phase
+	IXY   = 4 // General x versus y
+)
+
+// Other enumerated header values
+const (
+	IUNKN = 5 // Unknown dependent variable (IDEP)
+	IB    = 9 // Reference time at the begin time (IZTYPE)
+)
+
+// Header field indexes in the float, integer and character parts of the
+// header.
+const (
+	delta, depmin, depmax, scale, b, e, o = 0, 1, 2, 3, 5, 6, 7
+	stla, stlo, stel, stdp                = 31, 32, 33, 34
+	depmen, cmpaz, cmpinc                 = 56, 57, 58
+
+	nzyear, nzjday, nzhour, nzmin, nzsec, nzmsec = 0, 1, 2, 3, 4, 5
+	nvhdr, npts, iftype, idep, iztype, leven     = 6, 9, 15, 16, 17, 35
+
+	kstnm, kevnm, khole, kcmpnm, knetwk, kinst = 0, 1, 2, 19, 20, 22
+)
+
+// Header holds the SAC header fields this package reads and writes. Floats
+// set to UNDEFINED and empty strings are undefined. Other fields of a parsed
+// file are kept as they were and written back unchanged.
+type Header struct {
+	Delta  float32 // Sample period in seconds
+	DepMin float32
+	DepMax float32
+	DepMen float32
+	Scale  float32
+	B      float32 // Begin time, seconds after the reference time
+	E      float32 // End time, seconds after the reference time
+	O      float32 // Origin time, seconds after the reference time
+	Stla   float32 // Station latitude in degrees
+	Stlo   float32 // Station longitude in degrees
+	Stel   float32 // Station elevation in meters
+	Stdp   float32 // Station depth in meters
+	Cmpaz  float32 // Component azimuth, degrees clockwise from north
+	Cmpinc float32 // Component incidence, degrees from vertical up
+
+	NzYear int32 // Reference time, UTC
+	NzJDay int32
+	NzHour int32
+	NzMin  int32
+	NzSec  int32
+	NzMsec int32
+	IfType int32 // ITIME, IRLIM, IAMPH or IXY
+	IDep   int32
+	IzType int32
+	Leven  bool // Evenly spaced samples
+
+	Kstnm  string // Station code
+	Khole  string // Location code
+	Kcmpnm string // Channel code
+	Knetwk string // Network code
+	Kinst  string
+
+	raw *rawHeader // Fields as parsed, nil for a new header
+}
+
+// rawHeader is the header as stored, strings without their padding.
+type rawHeader struct {
+	floats [70]float32
+	ints   [40]int32
+	chars  [23]string
+}
+
+// getUndefinedHeader returns a header with every field undefined.
+func getUndefinedHeader() *rawHeader {
+	r := &rawHeader{}
+	for i := range r.floats {
+		r.floats[i] = UNDEFINED
+	}
+	for i := range r.ints {
+		r.ints[i] = UNDEFINED
+	}
+	// Logicals are false rather than undefined
+	for i := leven; i < leven+4; i++ {
+		r.ints[i] = 0
+	}
+	return r
+}
+
+// getFloats returns the float fields of the header by index.
+func (h *Header) getFloats() map[int]*float32 {
+	return map[int]*float32{
+		delta: &h.Delta, depmin: &h.DepMin, depmax: &h.DepMax, depmen: &h.DepMen, scale: &h.Scale,
+		b: &h.B, e: &h.E, o: &h.O, stla: &h.Stla, stlo: &h.Stlo, stel: &h.Stel, stdp: &h.Stdp,
+		cmpaz: &h.Cmpaz, cmpinc: &h.Cmpinc,
+	}
+}
+
+// getInts returns the integer fields of the header by index.
+func (h *Header) getInts() map[int]*int32 {
+	return map[int]*int32{
+		nzyear: &h.NzYear, nzjday: &h.NzJDay, nzhour: &h.NzHour, nzmin: &h.NzMin, nzsec: &h.NzSec, nzmsec: &h.NzMsec,
+		iftype: &h.IfType, idep: &h.IDep, iztype: &h.IzType,
+	}
+}
+
+// getChars returns the character fields of the header by index.
+func (h *Header) getChars() map[int]*string {
+	return map[int]*string{kstnm: &h.Kstnm, khole: &h.Khole, kcmpnm: &h.Kcmpnm, knetwk: &h.Knetwk, kinst: &h.Kinst}
+}
+
+// getRaw returns the header as stored, for n samples.
+func (h *Header) getRaw(n int) *rawHeader {
+	r := getUndefinedHeader()
+	if h.raw != nil {
+		*r = *h.raw
+	}
+	for i, p := range h.getFloats() {
+		r.floats[i] = *p
+	}
+	for i, p := range h.getInts() {
+		r.ints[i] = *p
+	}
+	for i, p := range h.getChars() {
+		r.chars[i] = *p
+	}
+	r.ints[nvhdr], r.ints[npts], r.ints[leven] = HEADER_VERSION, int32(n), 0
+	if h.Leven {
+		r.ints[leven] = 1
+	}
+	return r
+}
+
+// setRaw sets the header from its stored fields.
+func (h *Header) setRaw(r *rawHeader) {
+	for i, p := range h.getFloats() {
+		*p = r.floats[i]
+	}
+	for i, p := range h.getInts() {
+		*p = r.ints[i]
+	}
+	for i, p := range h.getChars() {
+		*p = r.chars[i]
+	}
+	h.Leven = r.ints[leven] == 1
+	h.raw = r
+}
+
+// getCharOffset returns the offset and length of a character field in the
+// character part of the header, KEVNM being the only one of 16 bytes.
+func getCharOffset(i int) (int, int) {
+	switch i {
+	case kstnm:
+		return 0, 8
+	case kevnm:
+		return 8, 16
+	}
+	return 24 + (i-2)*8, 8
+}
+
+// parseChars trims the padding of a character field, "-12345" being
+// undefined.
+func parseChars(s string) string {
+	s = strings.TrimRight(strings.TrimSpace(s), "\x00")
+	if s == "-12345" {
+		return ""
+	}
+	return s
+}
+
+// formatChars pads a character field to its length, undefined when empty.
+func formatChars(s string, length int) string {
+	if s == "" {
+		s = "-12345"
+	}
+	if len(s) > length {
+		s = s[:length]
+	}
+	return s + strings.Repeat(" ", length-len(s))
+}
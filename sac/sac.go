@@ -0,0 +1,231 @@
+package sac
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// File is a SAC file, evenly sampled time series holding their samples in
+// Data. Spectral and unevenly sampled files hold their second component in
+// Extra, and can be read and written but not converted to traces.
+type File struct {
+	Header Header
+	Data   []float32
+	Extra  []float32
+}
+
+// Coordinates locate the station and orient the component of a trace, with
+// the dip from the horizontal of SEED and StationXML, -90 pointing up.
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+	Elevation float64 // Meters
+	Depth     float64 // Meters below the surface
+	Azimuth   float64 // Degrees clockwise from north
+	Dip       float64 // Degrees down from the horizontal
+}
+
+// ToSAC converts a trace to a SAC file, with the reference time at the
+// millisecond of its start, and with station coordinates if not nil.
+func ToSAC(t *mseedio.Trace, coordinates *Coordinates) (*File, error) {
+	if t.SampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate %v", t.SampleRate)
+	}
+	data, err := getFloat32s(t)
+	if err != nil {
+		return nil, err
+	}
+
+	start := t.StartTime.UTC()
+	reference := start.Truncate(time.Millisecond)
+	f := &File{Data: data}
+	h := &f.Header
+	h.setRaw(getUndefinedHeader())
+	h.raw = nil
+
+	h.Delta = float32(1 / t.SampleRate)
+	h.B = float32(start.Sub(reference).Seconds())
+	h.E = h.B + float32(float64(len(data)-1)/t.SampleRate)
+	h.NzYear, h.NzJDay = int32(reference.Year()), int32(reference.YearDay())
+	h.NzHour, h.NzMin, h.NzSec = int32(reference.Hour()), int32(reference.Minute()), int32(reference.Second())
+	h.NzMsec = int32(reference.Nanosecond() / int(time.Millisecond))
+	h.IfType, h.IDep, h.IzType, h.Leven = ITIME, IUNKN, IB, true
+	h.Kstnm, h.Khole, h.Kcmpnm, h.Knetwk = t.StationCode, t.LocationCode, t.ChannelCode, t.NetworkCode
+	h.setStatistics(data)
+
+	if c := coordinates; c != nil {
+		h.Stla, h.Stlo, h.Stel, h.Stdp = float32(c.Latitude), float32(c.Longitude), float32(c.Elevation), float32(c.Depth)
+		h.Cmpaz, h.Cmpinc = float32(c.Azimuth), float32(c.Dip+90)
+	}
+
+	return f, nil
+}
+
+// FromSAC converts an evenly sampled time series to a trace of float32
+// samples, the data quality being D.
+func FromSAC(f *File) (*mseedio.Trace, error) {
+	h := &f.Header
+	if h.IfType != ITIME || !h.Leven {
+		return nil, fmt.Errorf("not an evenly sampled time series, IFTYPE %d", h.IfType)
+	}
+	rate := getSampleRate(h.Delta)
+	if rate <= 0 {
+		return nil, fmt.Errorf("invalid sample period %v", h.Delta)
+	}
+	if h.NzYear == UNDEFINED || h.NzJDay == UNDEFINED {
+		return nil, fmt.Errorf("undefined reference time")
+	}
+
+	begin := float64(h.B)
+	if h.B == UNDEFINED {
+		begin = 0
+	}
+	reference := time.Date(int(h.NzYear), time.January, int(h.NzJDay), int(h.NzHour), int(h.NzMin), int(h.NzSec),
+		int(h.NzMsec)*int(time.Millisecond), time.UTC)
+	start := reference.Add(time.Duration(begin * float64(time.Second)).Round(time.Microsecond))
+
+	t := &mseedio.Trace{
+		NetworkCode:  h.Knetwk,
+		StationCode:  h.Kstnm,
+		LocationCode: h.Khole,
+		ChannelCode:  h.Kcmpnm,
+		DataQuality:  "D",
+		SampleRate:   rate,
+		StartTime:    start,
+		EndTime:      start,
+		Samples:      make([]any, len(f.Data)),
+	}
+	if len(f.Data) > 1 {
+		t.EndTime = start.Add(time.Duration(float64(len(f.Data)-1) * float64(time.Second) / rate))
+	}
+	for i, v := range f.Data {
+		t.Samples[i] = v
+	}
+
+	return t, nil
+}
+
+// Coordinates returns the station coordinates of the file, nil when its
+// latitude or longitude is undefined. Other undefined fields are zero.
+func (f *File) Coordinates() *Coordinates {
+	h := &f.Header
+	if h.Stla == UNDEFINED || h.Stlo == UNDEFINED {
+		return nil
+	}
+
+	defined := func(v float32) float64 {
+		if v == UNDEFINED {
+			return 0
+		}
+		return float64(v)
+	}
+	c := &Coordinates{
+		Latitude:  float64(h.Stla),
+		Longitude: float64(h.Stlo),
+		Elevation: defined(h.Stel),
+		Depth:     defined(h.Stdp),
+		Azimuth:   defined(h.Cmpaz),
+	}
+	if h.Cmpinc != UNDEFINED {
+		c.Dip = float64(h.Cmpinc) - 90
+	}
+	return c
+}
+
+// AppendOptions returns the options to append the samples of the file to a
+// MiniSeedData with Append, see Int32s.
+func (f *File) AppendOptions() (*mseedio.AppendOptions, error) {
+	t, err := FromSAC(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mseedio.AppendOptions{
+		NetworkCode:  t.NetworkCode,
+		StationCode:  t.StationCode,
+		LocationCode: t.LocationCode,
+		ChannelCode:  t.ChannelCode,
+		DataQuality:  t.DataQuality,
+		SampleRate:   t.SampleRate,
+		StartTime:    t.StartTime,
+	}, nil
+}
+
+// Int32s returns the samples rounded to integers, as Append takes them.
+func (f *File) Int32s() []int32 {
+	data := make([]int32, len(f.Data))
+	for i, v := range f.Data {
+		data[i] = int32(math.Round(float64(v)))
+	}
+	return data
+}
+
+// ReadFile reads a binary SAC file of either byte order, or an alphanumeric
+// one.
+func ReadFile(name string) (*File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	f, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return f, nil
+}
+
+// setStatistics sets the minimum, maximum and mean of the samples.
+func (h *Header) setStatistics(data []float32) {
+	if len(data) == 0 {
+		return
+	}
+
+	minimum, maximum, sum := data[0], data[0], 0.0
+	for _, v := range data {
+		if v < minimum {
+			minimum = v
+		}
+		if v > maximum {
+			maximum = v
+		}
+		sum += float64(v)
+	}
+	h.DepMin, h.DepMax, h.DepMen = minimum, maximum, float32(sum/float64(len(data)))
+}
+
+// getSampleRate returns the sample rate of a period stored as float32,
+// rounded to the whole rate or period it stands for.
+func getSampleRate(delta float32) float64 {
+	if delta <= 0 || delta == UNDEFINED {
+		return 0
+	}
+
+	rate := 1 / float64(delta)
+	if r := math.Round(rate); r > 0 && math.Abs(rate-r) < rate*1e-6 {
+		return r
+	}
+	if p := math.Round(float64(delta)); p > 0 && math.Abs(float64(delta)-p) < p*1e-6 {
+		return 1 / p
+	}
+	return rate
+}
+
+// getFloat32s converts the samples of a trace to float32.
+func getFloat32s(t *mseedio.Trace) ([]float32, error) {
+	samples, err := t.Float64s()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]float32, 0, len(samples))
+	for _, v := range samples {
+		data = append(data, float32(v))
+	}
+	return data, nil
+}
@@ -0,0 +1,216 @@
+package sac
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// getTestTrace returns a 40 Hz trace of n samples starting off the
+// millisecond.
+func getTestTrace(n int) *mseedio.Trace {
+	start := time.Date(2024, 3, 1, 12, 30, 15, 123456000, time.UTC)
+	t := &mseedio.Trace{
+		NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+		DataQuality: "D", SampleRate: 40, StartTime: start,
+		EndTime: start.Add(time.Duration(n-1) * 25 * time.Millisecond),
+	}
+	for i := 0; i < n; i++ {
+		t.Samples = append(t.Samples, int32(i*i-100))
+	}
+	return t
+}
+
+func TestToSAC(t *testing.T) {
+	f, err := ToSAC(getTestTrace(12), &Coordinates{Latitude: 34.9459, Longitude: -106.4572, Elevation: 1850, Azimuth: 0, Dip: -90})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &f.Header
+	if h.Delta != 0.025 || h.NzYear != 2024 || h.NzJDay != 61 || h.NzHour != 12 || h.NzMin != 30 || h.NzSec != 15 || h.NzMsec != 123 {
+		t.Errorf("unexpected timing %+v", h)
+	}
+	if h.B < 455e-6 || h.B > 457e-6 || h.E != h.B+11*0.025 {
+		t.Errorf("unexpected begin %v or end %v", h.B, h.E)
+	}
+	if h.Knetwk != "IU" || h.Kstnm != "ANMO" || h.Khole != "00" || h.Kcmpnm != "BHZ" || h.IfType != ITIME || !h.Leven {
+		t.Errorf("unexpected codes or type %+v", h)
+	}
+	if h.DepMin != -100 || h.DepMax != 21 || h.O != UNDEFINED {
+		t.Errorf("unexpected statistics %v %v or origin %v", h.DepMin, h.DepMax, h.O)
+	}
+	if c := f.Coordinates(); c == nil || c.Elevation != 1850 || c.Dip != -90 || h.Cmpinc != 0 {
+		t.Errorf("unexpected coordinates %+v", c)
+	}
+
+	if f, err = ToSAC(getTestTrace(3), nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.Header.Stla != UNDEFINED || f.Coordinates() != nil {
+		t.Errorf("want undefined coordinates, got %v", f.Header.Stla)
+	}
+}
+
+func TestFromSAC(t *testing.T) {
+	want := getTestTrace(20)
+	f, err := ToSAC(want, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FromSAC(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NetworkCode != "IU" || got.StationCode != "ANMO" || got.LocationCode != "00" || got.ChannelCode != "BHZ" || got.SampleRate != 40 {
+		t.Errorf("unexpected trace %+v", got)
+	}
+	if !got.StartTime.Equal(want.StartTime) || !got.EndTime.Equal(want.EndTime) {
+		t.Errorf("want %s to %s, got %s to %s", want.StartTime, want.EndTime, got.StartTime, got.EndTime)
+	}
+	for i, v := range got.Samples {
+		if v != float32(want.Samples[i].(int32)) {
+			t.Fatalf("sample %d: want %v, got %v", i, want.Samples[i], v)
+		}
+	}
+
+	f.Header.IfType = IAMPH
+	if _, err := FromSAC(f); err == nil {
+		t.Error("want error for a spectrum")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	f, err := ToSAC(getTestTrace(7), &Coordinates{Latitude: 1, Longitude: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bitOrder := range []int{mseedio.MSBFIRST, mseedio.LSBFIRST} {
+		var buf bytes.Buffer
+		if err := f.Write(&buf, bitOrder); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+		if len(data) != HEADER_LENGTH+7*4 {
+			t.Fatalf("want %d bytes, got %d", HEADER_LENGTH+7*4, len(data))
+		}
+		var order binary.ByteOrder = binary.LittleEndian
+		if bitOrder == mseedio.MSBFIRST {
+			order = binary.BigEndian
+		}
+		if order.Uint32(data[304:]) != HEADER_VERSION || order.Uint32(data[316:]) != 7 || string(data[440:448]) != "ANMO    " {
+			t.Errorf("bit order %d: unexpected header %v", bitOrder, data[280:448])
+		}
+
+		g, err := Parse(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g.Header.Kcmpnm != "BHZ" || g.Header.Stla != 1 || g.Header.Kinst != "" || len(g.Data) != 7 || g.Data[6] != -64 {
+			t.Errorf("bit order %d: unexpected file %+v", bitOrder, g)
+		}
+
+		// Fields this package does not know are kept
+		var again bytes.Buffer
+		g.Header.raw.floats[40] = 1.5
+		if err := g.Write(&again, bitOrder); err != nil {
+			t.Fatal(err)
+		}
+		if h, err := Parse(&again); err != nil || h.Header.raw.floats[40] != 1.5 {
+			t.Errorf("bit order %d: USER0 lost, error %v", bitOrder, err)
+		}
+	}
+}
+
+func TestWriteAlpha(t *testing.T) {
+	f, err := ToSAC(getTestTrace(12), &Coordinates{Latitude: 34.9459, Longitude: -106.4572})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Header.Khole = ""
+
+	var buf bytes.Buffer
+	if err := f.WriteAlpha(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != ALPHA_HEADER_LINES+3 || lines[22] != "ANMO    -12345          " || len(lines[30]) != 5*ALPHA_FLOAT_WIDTH {
+		t.Fatalf("unexpected layout\n%s", buf.String())
+	}
+
+	g, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &g.Header
+	if h.Kstnm != "ANMO" || h.Khole != "" || h.Knetwk != "IU" || h.Stlo != f.Header.Stlo || h.NzMsec != 123 || !h.Leven {
+		t.Errorf("unexpected header %+v", h)
+	}
+	if len(g.Data) != 12 || g.Data[11] != 21 {
+		t.Errorf("unexpected data %v", g.Data)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	f, err := ToSAC(getTestTrace(50), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options, err := f.AppendOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.Init(mseedio.STEIM2, mseedio.MSBFIRST); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Append(f.Int32s(), options); err != nil {
+		t.Fatal(err)
+	}
+	data, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read mseedio.MiniSeedData
+	if err := read.ReadFromReader(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	traces := read.Traces()
+	if len(traces) != 1 || len(traces[0].Samples) != 50 || traces[0].Samples[49] != int32(49*49-100) {
+		t.Fatalf("unexpected traces %+v", traces)
+	}
+	if want := getTestTrace(1).StartTime.Truncate(100 * time.Microsecond); !traces[0].StartTime.Equal(want) {
+		t.Errorf("want start %s, got %s", want, traces[0].StartTime)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	f, err := ToSAC(getTestTrace(5), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf, mseedio.MSBFIRST); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	for name, input := range map[string][]byte{
+		"short":     data[:100],
+		"truncated": data[:len(data)-4],
+		"version":   append(append([]byte{}, data[:304]...), append([]byte{0, 0, 0, 7}, data[308:]...)...),
+		"text":      []byte(strings.Repeat("not sac\n", 40)),
+	} {
+		if _, err := Parse(bytes.NewReader(input)); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}
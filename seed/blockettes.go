@@ -0,0 +1,367 @@
+package seed
+
+import (
+	"fmt"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// Response types of blockettes 43, 44, 53 and 54.
+const (
+	RESPONSE_LAPLACE_RADIANS = "A"
+	RESPONSE_LAPLACE_HERTZ   = "B"
+	RESPONSE_COMPOSITE       = "C"
+	RESPONSE_DIGITAL         = "D"
+)
+
+// Symmetry codes of blockettes 41 and 61.
+const (
+	SYMMETRY_NONE = "A"
+	SYMMETRY_ODD  = "B" // Odd number of coefficients, the first half given
+	SYMMETRY_EVEN = "C" // Even number of coefficients, the first half given
+)
+
+// sensitivity is a channel sensitivity dictionary entry, kept apart from
+// stage gains as it may describe stage 0.
+type sensitivity struct {
+	stationxml.Gain
+}
+
+// parseAbbreviation parses the volume and abbreviation blockettes.
+func (v *Volume) parseAbbreviation(b *Blockette) error {
+	r := newFieldReader(b)
+	switch b.Type {
+	case 5, 8, 10:
+		v.Version = r.text(4)
+		r.int(2)
+		if b.Type == 8 {
+			// Station, location, channel and times of a telemetry volume
+			r.text(5)
+			r.text(2)
+			r.text(3)
+		}
+		v.StartTime = r.time()
+		if b.Type != 5 {
+			v.EndTime = r.time()
+		}
+		if b.Type == 10 && r.pos < len(r.data) {
+			v.VolumeTime = r.time()
+			v.Organization, v.Label = r.variable(), r.variable()
+		}
+
+	case 30:
+		f := &DataFormat{Name: r.variable(), Code: r.int(4), Family: r.int(3)}
+		for n := r.int(2); n > 0 && r.err == nil; n-- {
+			f.Keys = append(f.Keys, r.variable())
+		}
+		v.Formats[f.Code] = f
+
+	case 33:
+		code := r.int(3)
+		v.Abbreviations[code] = r.variable()
+
+	case 34:
+		code := r.int(3)
+		v.Units[code] = stationxml.Units{Name: r.variable(), Description: r.variable()}
+
+	case 41:
+		key, name := r.int(4), r.variable()
+		v.dictionary[key] = v.getFIR(r, name)
+
+	case 43:
+		key, name := r.int(4), r.variable()
+		v.dictionary[key] = v.getPolesZeros(r, r.text(1), name)
+
+	case 44:
+		key, name := r.int(4), r.variable()
+		v.dictionary[key] = v.getCoefficients(r, r.text(1), name)
+
+	case 47:
+		key, _ := r.int(4), r.variable()
+		v.dictionary[key] = getDecimation(r)
+
+	case 48:
+		key, _ := r.int(4), r.variable()
+		v.dictionary[key] = &sensitivity{getGain(r)}
+	}
+
+	return r.err
+}
+
+// parseStation parses the station blockettes, in order, into stations,
+// channels and their response stages.
+func (v *Volume) parseStation(b *Blockette) error {
+	if b.Type < 50 || b.Type > 62 {
+		return nil
+	}
+	r := newFieldReader(b)
+	if b.Type == 50 {
+		s := Station{Code: r.text(5), Latitude: r.float(10), Longitude: r.float(11), Elevation: r.float(7)}
+		r.int(4) // Number of channels
+		r.int(3) // Number of comments
+		s.Site = r.variable()
+		r.int(3) // Network identifier code
+		r.text(4)
+		r.text(2) // Word orders
+		s.StartTime, s.EndTime = r.time(), r.time()
+		r.text(1) // Update flag
+		if r.pos < len(r.data) {
+			s.Network = r.text(2)
+		}
+		v.Stations = append(v.Stations, s)
+		return r.err
+	}
+
+	if len(v.Stations) == 0 {
+		return fmt.Errorf("no station before it")
+	}
+	s := &v.Stations[len(v.Stations)-1]
+	if b.Type == 51 {
+		return nil
+	}
+	if b.Type == 52 {
+		c := Channel{Location: r.text(2), Code: r.text(3)}
+		r.int(4) // Subchannel
+		c.Instrument = v.Abbreviations[r.int(3)]
+		r.variable() // Optional comment
+		c.SignalUnits = v.Units[r.int(3)]
+		r.int(3) // Calibration units
+		c.Latitude, c.Longitude, c.Elevation = r.float(10), r.float(11), r.float(7)
+		c.Depth, c.Azimuth, c.Dip = r.float(5), r.float(5), r.float(5)
+		c.FormatCode = r.int(4)
+		if exponent := r.int(2); exponent > 0 && exponent <= MAX_RECORD_EXPONENT {
+			c.RecordLength = 1 << exponent
+		}
+		c.SampleRate = r.float(10)
+		r.float(10) // Maximum clock drift
+		r.int(4)    // Number of comments
+		c.Flags = r.variable()
+		c.StartTime, c.EndTime = r.time(), r.time()
+		s.Channels = append(s.Channels, c)
+		return r.err
+	}
+
+	if len(s.Channels) == 0 {
+		return fmt.Errorf("no channel before it")
+	}
+	c := &s.Channels[len(s.Channels)-1]
+	switch b.Type {
+	case 53:
+		typ, stage := r.text(1), r.int(2)
+		c.getStage(stage).PolesZeros = v.getPolesZeros(r, typ, "")
+
+	case 54:
+		typ, stage := r.text(1), r.int(2)
+		c.getStage(stage).Coefficients = v.getCoefficients(r, typ, "")
+
+	case 55:
+		stage := r.int(2)
+		l := &stationxml.ResponseList{Filter: v.getFilter(r, "")}
+		for n := r.int(4); n > 0 && r.err == nil; n-- {
+			e := stationxml.ResponseListElement{Frequency: r.float(12), Amplitude: r.float(12)}
+			r.float(12)
+			e.Phase = r.float(12)
+			r.float(12)
+			l.Elements = append(l.Elements, e)
+		}
+		c.getStage(stage).ResponseList = l
+
+	case 57:
+		stage := r.int(2)
+		c.getStage(stage).Decimation = getDecimation(r)
+
+	case 58:
+		stage := r.int(2)
+		gain := getGain(r)
+		c.setGain(stage, gain)
+
+	case 60:
+		for stages := r.int(2); stages > 0 && r.err == nil; stages-- {
+			stage := r.int(2)
+			for n := r.int(2); n > 0 && r.err == nil; n-- {
+				key := r.int(4)
+				if err := v.applyDictionary(c, stage, key); err != nil {
+					return err
+				}
+			}
+		}
+
+	case 61:
+		stage := r.int(2)
+		name := r.variable()
+		c.getStage(stage).FIR = v.getFIR(r, name)
+
+	case 62:
+		typ := r.text(1)
+		stage := r.int(2)
+		p := &stationxml.Polynomial{Filter: v.getFilter(r, "")}
+		if typ != "P" {
+			return fmt.Errorf("unknown polynomial transfer function type %q", typ)
+		}
+		if r.text(1) == "M" {
+			p.ApproximationType = "MACLAURIN"
+		}
+		r.text(1) // Valid frequency units
+		p.FrequencyLowerBound, p.FrequencyUpperBound = r.float(12), r.float(12)
+		p.ApproximationLowerBound, p.ApproximationUpperBound = r.float(12), r.float(12)
+		p.MaximumError = r.float(12)
+		for n := r.int(3); n > 0 && r.err == nil; n-- {
+			p.Coefficients = append(p.Coefficients, r.float(12))
+			r.float(12)
+		}
+		c.getStage(stage).Polynomial = p
+	}
+
+	return r.err
+}
+
+// getFilter reads the input and output units codes of a filter.
+func (v *Volume) getFilter(r *fieldReader, name string) stationxml.Filter {
+	return stationxml.Filter{Name: name, InputUnits: v.Units[r.int(3)], OutputUnits: v.Units[r.int(3)]}
+}
+
+// getPolesZeros reads the fields of blockettes 43 and 53 from the units on,
+// typ being their response type.
+func (v *Volume) getPolesZeros(r *fieldReader, typ, name string) *stationxml.PolesZeros {
+	pz := &stationxml.PolesZeros{Filter: v.getFilter(r, name)}
+	switch typ {
+	case RESPONSE_LAPLACE_RADIANS:
+		pz.PzTransferFunctionType = stationxml.LAPLACE_RADIANS
+	case RESPONSE_LAPLACE_HERTZ:
+		pz.PzTransferFunctionType = stationxml.LAPLACE_HERTZ
+	case RESPONSE_DIGITAL:
+		pz.PzTransferFunctionType = stationxml.DIGITAL_Z
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown transfer function type %q", typ)
+		}
+	}
+	pz.NormalizationFactor, pz.NormalizationFrequency = r.float(12), r.float(12)
+
+	number := 0
+	for n := r.int(3); n > 0 && r.err == nil; n-- {
+		pz.Zeros = append(pz.Zeros, stationxml.PoleZero{Number: number, Real: r.float(12), Imaginary: r.float(12)})
+		r.float(12)
+		r.float(12)
+		number++
+	}
+	for n := r.int(3); n > 0 && r.err == nil; n-- {
+		pz.Poles = append(pz.Poles, stationxml.PoleZero{Number: number, Real: r.float(12), Imaginary: r.float(12)})
+		r.float(12)
+		r.float(12)
+		number++
+	}
+
+	return pz
+}
+
+// getCoefficients reads the fields of blockettes 44 and 54 from the units
+// on, typ being their response type.
+func (v *Volume) getCoefficients(r *fieldReader, typ, name string) *stationxml.Coefficients {
+	cf := &stationxml.Coefficients{Filter: v.getFilter(r, name)}
+	switch typ {
+	case RESPONSE_LAPLACE_RADIANS:
+		cf.CfTransferFunctionType = stationxml.ANALOG_RADIANS
+	case RESPONSE_LAPLACE_HERTZ:
+		cf.CfTransferFunctionType = stationxml.ANALOG_HERTZ
+	case RESPONSE_DIGITAL:
+		cf.CfTransferFunctionType = stationxml.DIGITAL
+	default:
+		if r.err == nil {
+			r.err = fmt.Errorf("unsupported response type %q", typ)
+		}
+	}
+	for n := r.int(4); n > 0 && r.err == nil; n-- {
+		cf.Numerators = append(cf.Numerators, r.float(12))
+		r.float(12)
+	}
+	for n := r.int(4); n > 0 && r.err == nil; n-- {
+		cf.Denominators = append(cf.Denominators, r.float(12))
+		r.float(12)
+	}
+
+	return cf
+}
+
+// getFIR reads the fields of blockettes 41 and 61 after the name.
+func (v *Volume) getFIR(r *fieldReader, name string) *stationxml.FIR {
+	symmetry := r.text(1)
+	fir := &stationxml.FIR{Filter: v.getFilter(r, name)}
+	switch symmetry {
+	case SYMMETRY_ODD:
+		fir.Symmetry = stationxml.SYMMETRY_ODD
+	case SYMMETRY_EVEN:
+		fir.Symmetry = stationxml.SYMMETRY_EVEN
+	default:
+		fir.Symmetry = stationxml.SYMMETRY_NONE
+	}
+	for n := r.int(4); n > 0 && r.err == nil; n-- {
+		fir.NumeratorCoefficients = append(fir.NumeratorCoefficients, r.float(14))
+	}
+
+	return fir
+}
+
+// getDecimation reads the fields of blockettes 47 and 57 after the name or
+// stage number.
+func getDecimation(r *fieldReader) *stationxml.Decimation {
+	return &stationxml.Decimation{
+		InputSampleRate: r.float(10),
+		Factor:          r.int(5),
+		Offset:          r.int(5),
+		Delay:           r.float(11),
+		Correction:      r.float(11),
+	}
+}
+
+// getGain reads the fields of blockettes 48 and 58 after the name or stage
+// number, skipping the calibration history.
+func getGain(r *fieldReader) stationxml.Gain {
+	g := stationxml.Gain{Value: r.float(12), Frequency: r.float(12)}
+	for n := r.int(2); n > 0 && r.err == nil; n-- {
+		r.float(12)
+		r.float(12)
+		r.variable()
+	}
+	return g
+}
+
+// applyDictionary sets the dictionary entry of a key on a stage of c.
+func (v *Volume) applyDictionary(c *Channel, stage, key int) error {
+	switch entry := v.dictionary[key].(type) {
+	case *stationxml.PolesZeros:
+		c.getStage(stage).PolesZeros = entry
+	case *stationxml.Coefficients:
+		c.getStage(stage).Coefficients = entry
+	case *stationxml.FIR:
+		c.getStage(stage).FIR = entry
+	case *stationxml.Decimation:
+		c.getStage(stage).Decimation = entry
+	case *sensitivity:
+		c.setGain(stage, entry.Gain)
+	default:
+		return fmt.Errorf("unknown response dictionary key %d", key)
+	}
+	return nil
+}
+
+// getStage returns the response stage of a number, adding it if missing.
+func (c *Channel) getStage(number int) *stationxml.Stage {
+	if c.Response == nil {
+		c.Response = &stationxml.Response{}
+	}
+	return c.Response.Stage(number)
+}
+
+// setGain sets the gain of a stage, stage 0 being the overall sensitivity.
+func (c *Channel) setGain(stage int, gain stationxml.Gain) {
+	if stage != 0 {
+		c.getStage(stage).StageGain = &gain
+		return
+	}
+
+	if c.Response == nil {
+		c.Response = &stationxml.Response{}
+	}
+	c.Response.InstrumentSensitivity = &stationxml.Sensitivity{Value: gain.Value, Frequency: gain.Frequency}
+}
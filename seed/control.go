@@ -0,0 +1,224 @@
+package seed
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CONTROL_HEADER_LENGTH   = 8 // Sequence number, type and continuation flag
+	BLOCKETTE_HEADER_LENGTH = 7 // Type (3 digits) and length (4 digits)
+)
+
+// Blockette is a control header blockette as stored in the volume, its type
+// and length included.
+type Blockette struct {
+	Type int
+	Data []byte
+}
+
+// controlSegment is the payload of a control record and the records that
+// continue it.
+type controlSegment struct {
+	kind byte // V, A, S or T
+	data []byte
+}
+
+// isControlRecord reports whether a record is a control header of a kind.
+func isControlRecord(record []byte) bool {
+	if len(record) < CONTROL_HEADER_LENGTH || !isDigits(record[:6]) {
+		return false
+	}
+	switch record[6] {
+	case 'V', 'A', 'S', 'T':
+		return record[7] == ' ' || record[7] == '*'
+	}
+	return false
+}
+
+// appendControlRecord appends the payload of a control record to the
+// segments, joining it to the last one when it continues it.
+func appendControlRecord(segments []controlSegment, record []byte) []controlSegment {
+	payload := record[CONTROL_HEADER_LENGTH:]
+	if n := len(segments); n > 0 && record[7] == '*' && segments[n-1].kind == record[6] {
+		segments[n-1].data = append(segments[n-1].data, payload...)
+		return segments
+	}
+	return append(segments, controlSegment{kind: record[6], data: append([]byte{}, payload...)})
+}
+
+// getBlockettes splits a segment into blockettes, skipping the space padding
+// at the end of records.
+func getBlockettes(segment []byte) ([]Blockette, error) {
+	var blockettes []Blockette
+	for pos := 0; pos < len(segment); {
+		if segment[pos] == ' ' {
+			pos++
+			continue
+		}
+		if pos+BLOCKETTE_HEADER_LENGTH > len(segment) || !isDigits(segment[pos:pos+BLOCKETTE_HEADER_LENGTH]) {
+			return nil, fmt.Errorf("invalid blockette header %q", truncate(segment[pos:], BLOCKETTE_HEADER_LENGTH))
+		}
+
+		typ, _ := strconv.Atoi(string(segment[pos : pos+3]))
+		length, _ := strconv.Atoi(string(segment[pos+3 : pos+7]))
+		if length < BLOCKETTE_HEADER_LENGTH || pos+length > len(segment) {
+			return nil, fmt.Errorf("blockette %d: length %d out of range", typ, length)
+		}
+		blockettes = append(blockettes, Blockette{Type: typ, Data: segment[pos : pos+length]})
+		pos += length
+	}
+
+	return blockettes, nil
+}
+
+// fieldReader reads the fixed and variable length fields of a blockette,
+// keeping the first error.
+type fieldReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// newFieldReader reads the fields after the header of a blockette.
+func newFieldReader(b *Blockette) *fieldReader {
+	return &fieldReader{data: b.Data, pos: BLOCKETTE_HEADER_LENGTH}
+}
+
+// fixed reads an n-byte field.
+func (r *fieldReader) fixed(n int) string {
+	if r.err != nil {
+		return ""
+	}
+	if r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("field at %d runs past the blockette", r.pos)
+		return ""
+	}
+
+	s := string(r.data[r.pos : r.pos+n])
+	r.pos += n
+	return s
+}
+
+// text reads an n-byte text field without its padding.
+func (r *fieldReader) text(n int) string {
+	return strings.TrimSpace(r.fixed(n))
+}
+
+// int reads an n-digit integer field.
+func (r *fieldReader) int(n int) int {
+	s := r.text(n)
+	if r.err != nil || s == "" {
+		return 0
+	}
+
+	v, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+	if err != nil {
+		r.err = fmt.Errorf("field at %d: invalid integer %q", r.pos-n, s)
+	}
+	return v
+}
+
+// float reads an n-byte floating point field.
+func (r *fieldReader) float(n int) float64 {
+	s := r.text(n)
+	if r.err != nil || s == "" {
+		return 0
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		r.err = fmt.Errorf("field at %d: invalid number %q", r.pos-n, s)
+	}
+	return v
+}
+
+// variable reads a field ended by a tilde.
+func (r *fieldReader) variable() string {
+	if r.err != nil {
+		return ""
+	}
+
+	end := strings.IndexByte(string(r.data[r.pos:]), '~')
+	if end < 0 {
+		r.err = fmt.Errorf("field at %d has no terminating tilde", r.pos)
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+end])
+	r.pos += end + 1
+	return strings.TrimSpace(s)
+}
+
+// time reads a variable length time field, empty for none.
+func (r *fieldReader) time() time.Time {
+	s := r.variable()
+	if r.err != nil || s == "" {
+		return time.Time{}
+	}
+
+	t, err := ParseTime(s)
+	if err != nil {
+		r.err = err
+	}
+	return t
+}
+
+// ParseTime parses a SEED time, YYYY,DDD,HH:MM:SS.FFFF, as found in volumes
+// and RESP files. The fields after the day may be left out.
+func ParseTime(s string) (time.Time, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) < 2 || len(parts) > 3 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	year, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	day, err := strconv.Atoi(parts[1])
+	if err != nil || day < 1 || day > 366 {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+
+	t := time.Date(year, time.January, day, 0, 0, 0, 0, time.UTC)
+	if len(parts) == 3 && parts[2] != "" {
+		clock := strings.Split(parts[2], ":")
+		units := []time.Duration{time.Hour, time.Minute}
+		for i, field := range clock {
+			if i == 2 {
+				seconds, err := strconv.ParseFloat(field, 64)
+				if err != nil || len(clock) > 3 {
+					return time.Time{}, fmt.Errorf("invalid time %q", s)
+				}
+				t = t.Add(time.Duration(seconds * float64(time.Second)).Round(100 * time.Microsecond))
+				break
+			}
+			v, err := strconv.Atoi(field)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid time %q", s)
+			}
+			t = t.Add(time.Duration(v) * units[i])
+		}
+	}
+
+	return t, nil
+}
+
+// isDigits reports whether b holds ASCII digits only.
+func isDigits(b []byte) bool {
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(b) > 0
+}
+
+// truncate returns at most the first n bytes of b.
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
@@ -0,0 +1,26 @@
+// Package seed reads full and dataless SEED volumes: the volume,
+// abbreviation and station control headers (blockettes 5 to 74) and the
+// data records that follow them.
+//
+//	v, err := seed.ReadFile("volume.seed")
+//	if err != nil {
+//		// handle error
+//	}
+//	m, err := v.ReadData() // data records, through mseedio
+//	inv := v.StationXML()  // stations, channels and responses
+//	for _, series := range m.Series {
+//		c, err := inv.LookupRecord(&series.FixedSection)
+//		// use c.Response
+//	}
+//
+// Control blockettes are kept as they are in Volume.Blockettes. Stations
+// (50), channels (52) and their responses (53 to 62, with the dictionaries
+// of blockettes 41 to 48 referenced by 60) are parsed into the model of the
+// stationxml package, together with the data formats (30), abbreviations
+// (33) and units (34) they refer to. Time span blockettes (70 to 74) are
+// kept unparsed.
+//
+// Data records of older volumes often have no blockette 1000, their
+// encoding being given by the data format of their channel instead. Parse
+// inserts one in those records, so that mseedio can read them.
+package seed
@@ -0,0 +1,204 @@
+package seed
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// isDataRecord reports whether a record is a data record.
+func isDataRecord(record []byte) bool {
+	if len(record) < mseedio.FIXED_SECTION_LENGTH || !isDigits(record[:6]) && strings.TrimSpace(string(record[:6])) != "" {
+		return false
+	}
+	switch record[6] {
+	case 'D', 'R', 'Q', 'M':
+		return true
+	}
+	return false
+}
+
+// getByteOrder tells the byte order of a data record from the year of its
+// start time.
+func getByteOrder(record []byte) (binary.ByteOrder, int) {
+	if year := binary.BigEndian.Uint16(record[20:]); year >= 1900 && year <= 2500 {
+		return binary.BigEndian, mseedio.MSBFIRST
+	}
+	return binary.LittleEndian, mseedio.LSBFIRST
+}
+
+// getBlockette1000 returns the offset of blockette 1000 in a data record, 0
+// when it has none.
+func getBlockette1000(record []byte) int {
+	order, _ := getByteOrder(record)
+	offset := int(order.Uint16(record[46:]))
+	for n := 0; offset >= mseedio.FIXED_SECTION_LENGTH && offset+8 <= len(record) && n < 256; n++ {
+		if order.Uint16(record[offset:]) == 1000 {
+			return offset
+		}
+		next := int(order.Uint16(record[offset+2:]))
+		if next <= offset {
+			break
+		}
+		offset = next
+	}
+	return 0
+}
+
+// getDataRecordLength returns the length given by blockette 1000 of a data
+// record, 0 when it has none.
+func getDataRecordLength(record []byte) int {
+	offset := getBlockette1000(record)
+	if offset == 0 {
+		return 0
+	}
+	if exponent := int(record[offset+6]); exponent >= 7 && exponent <= MAX_RECORD_EXPONENT {
+		return 1 << exponent
+	}
+	return 0
+}
+
+// completeRecord returns a data record that mseedio can read: records with
+// blockette 1000 as the first blockette are kept as they are, records with it
+// further down the chain get it moved to the front, and others get one
+// inserted after the fixed section, built from the data format of their
+// channel. When other blockettes fill the room before the data, they are
+// shifted after it, and the data moves to the next 64-byte boundary.
+func (v *Volume) completeRecord(record []byte) ([]byte, error) {
+	order, bitOrder := getByteOrder(record)
+	first := int(order.Uint16(record[46:]))
+	offset := getBlockette1000(record)
+	if offset == mseedio.FIXED_SECTION_LENGTH && first == mseedio.FIXED_SECTION_LENGTH {
+		return record, nil
+	}
+	if offset != 0 {
+		return moveBlockette1000(record, offset), nil
+	}
+
+	network := strings.TrimSpace(string(record[18:20]))
+	station := strings.TrimSpace(string(record[8:13]))
+	location := strings.TrimSpace(string(record[13:15]))
+	channel := strings.TrimSpace(string(record[15:18]))
+	start := time.Date(int(order.Uint16(record[20:])), time.January, int(order.Uint16(record[22:])),
+		int(record[24]), int(record[25]), int(record[26]), int(order.Uint16(record[28:]))*100000, time.UTC)
+	c, err := v.getChannel(network, station, location, channel, start)
+	if err != nil {
+		return nil, err
+	}
+	format, ok := v.Formats[c.FormatCode]
+	if !ok {
+		return nil, fmt.Errorf("unknown data format code %d", c.FormatCode)
+	}
+	encoding, err := format.Encoding()
+	if err != nil {
+		return nil, err
+	}
+
+	// Without other blockettes, the room left before the data may do
+	dataOffset := int(order.Uint16(record[44:]))
+	if dataOffset == 0 || dataOffset > len(record) {
+		dataOffset = len(record)
+	}
+	if first == 0 && dataOffset >= mseedio.FIXED_SECTION_LENGTH+8 {
+		completed := append([]byte{}, record...)
+		completed[39]++
+		order.PutUint16(completed[46:], mseedio.FIXED_SECTION_LENGTH)
+		b := completed[mseedio.FIXED_SECTION_LENGTH:]
+		order.PutUint16(b, 1000)
+		order.PutUint16(b[2:], 0)
+		b[4], b[5], b[6], b[7] = byte(encoding), byte(bitOrder), byte(bits.Len(uint(len(record)-1))), 0
+		return completed, nil
+	}
+
+	// Header blockettes are shifted by blockette 1000, and data to the next
+	// frame boundary after them
+	const shift = 8
+	headerEnd := dataOffset
+	if headerEnd < mseedio.FIXED_SECTION_LENGTH {
+		return nil, fmt.Errorf("data offset %d inside the fixed section", dataOffset)
+	}
+	newDataOffset := (headerEnd + shift + 63) / 64 * 64
+	length := newDataOffset + len(record) - dataOffset
+	exponent := bits.Len(uint(length - 1))
+	if exponent > MAX_RECORD_EXPONENT {
+		return nil, fmt.Errorf("record of %d bytes too long", length)
+	}
+
+	completed := make([]byte, 1<<exponent)
+	copy(completed, record[:mseedio.FIXED_SECTION_LENGTH])
+	copy(completed[mseedio.FIXED_SECTION_LENGTH+shift:], record[mseedio.FIXED_SECTION_LENGTH:headerEnd])
+	copy(completed[newDataOffset:], record[dataOffset:])
+
+	completed[39]++
+	order.PutUint16(completed[46:], mseedio.FIXED_SECTION_LENGTH)
+	if dataOffset < len(record) {
+		order.PutUint16(completed[44:], uint16(newDataOffset))
+	}
+
+	b := completed[mseedio.FIXED_SECTION_LENGTH:]
+	order.PutUint16(b, 1000)
+	b[4], b[5], b[6] = byte(encoding), byte(bitOrder), byte(exponent)
+	if first >= mseedio.FIXED_SECTION_LENGTH {
+		order.PutUint16(b[2:], uint16(first+shift))
+	}
+
+	// Walk the shifted chain to fix the offsets of the next blockettes
+	for pos := first + shift; pos >= mseedio.FIXED_SECTION_LENGTH+shift && pos+4 <= newDataOffset; {
+		next := int(order.Uint16(completed[pos+2:]))
+		if next < mseedio.FIXED_SECTION_LENGTH || next <= first {
+			break
+		}
+		order.PutUint16(completed[pos+2:], uint16(next+shift))
+		pos, first = next+shift, next
+	}
+
+	return completed, nil
+}
+
+// moveBlockette1000 returns a copy of a data record with its blockette 1000,
+// found at offset, moved right after the fixed section and first in the
+// chain. The blockettes it was after are shifted by its 8 bytes, and keep
+// their order in the chain.
+func moveBlockette1000(record []byte, offset int) []byte {
+	order, _ := getByteOrder(record)
+	const shift = 8
+	moved := func(pos int) int {
+		if pos == offset {
+			return mseedio.FIXED_SECTION_LENGTH
+		}
+		if pos < offset {
+			return pos + shift
+		}
+		return pos
+	}
+
+	// The chain without blockette 1000, as getBlockette1000 walks it
+	var chain []int
+	for pos, n := int(order.Uint16(record[46:])), 0; pos >= mseedio.FIXED_SECTION_LENGTH && pos+4 <= len(record) && n < 256; n++ {
+		if pos != offset {
+			chain = append(chain, pos)
+		}
+		next := int(order.Uint16(record[pos+2:]))
+		if next <= pos {
+			break
+		}
+		pos = next
+	}
+
+	completed := append([]byte{}, record...)
+	copy(completed[mseedio.FIXED_SECTION_LENGTH+shift:], record[mseedio.FIXED_SECTION_LENGTH:offset])
+	copy(completed[mseedio.FIXED_SECTION_LENGTH:], record[offset:offset+shift])
+
+	order.PutUint16(completed[46:], mseedio.FIXED_SECTION_LENGTH)
+	prev := mseedio.FIXED_SECTION_LENGTH
+	for _, pos := range chain {
+		order.PutUint16(completed[prev+2:], uint16(moved(pos)))
+		prev = moved(pos)
+	}
+	order.PutUint16(completed[prev+2:], 0)
+	return completed
+}
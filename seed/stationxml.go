@@ -0,0 +1,105 @@
+package seed
+
+import (
+	"time"
+
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// channelTypes names the channel flags of blockette 52 in StationXML.
+var channelTypes = map[rune]string{
+	'T': "TRIGGERED",
+	'C': "CONTINUOUS",
+	'H': "HEALTH",
+	'G': "GEOPHYSICAL",
+	'W': "WEATHER",
+	'F': "FLAG",
+	'S': "SYNTHESIZED",
+	'I': "INPUT",
+	'E': "EXPERIMENTAL",
+	'M': "MAINTENANCE",
+	'B': "BEAM",
+}
+
+// StationXML converts the stations, channels and responses of the volume to
+// a StationXML document, one network epoch per network code spanning its
+// stations. Responses are shared with the volume, not copied.
+func (v *Volume) StationXML() *stationxml.FDSNStationXML {
+	created := v.VolumeTime
+	if created.IsZero() {
+		created = time.Now().UTC()
+	}
+	d := &stationxml.FDSNStationXML{
+		SchemaVersion: stationxml.SCHEMA_VERSION,
+		Source:        v.Organization,
+		Module:        "mseedio/seed",
+		Created:       stationxml.Time{Time: created},
+	}
+	if d.Source == "" {
+		d.Source = "SEED volume"
+	}
+
+	for i := range v.Stations {
+		s := &v.Stations[i]
+		n := getNetwork(d, s.Network)
+		if n.StartDate.IsZero() || s.StartTime.Before(n.StartDate.Time) {
+			n.StartDate.Time = s.StartTime
+		}
+
+		station := stationxml.Station{
+			Code:      s.Code,
+			StartDate: stationxml.Time{Time: s.StartTime},
+			EndDate:   stationxml.Time{Time: s.EndTime},
+			Latitude:  s.Latitude,
+			Longitude: s.Longitude,
+			Elevation: s.Elevation,
+			Site:      stationxml.Site{Name: s.Site},
+		}
+		for j := range s.Channels {
+			station.Channels = append(station.Channels, s.Channels[j].stationXML())
+		}
+		n.Stations = append(n.Stations, station)
+	}
+
+	return d
+}
+
+// getNetwork returns the network of a code in d, adding it if missing.
+func getNetwork(d *stationxml.FDSNStationXML, code string) *stationxml.Network {
+	for i := range d.Networks {
+		if d.Networks[i].Code == code {
+			return &d.Networks[i]
+		}
+	}
+
+	d.Networks = append(d.Networks, stationxml.Network{Code: code})
+	return &d.Networks[len(d.Networks)-1]
+}
+
+// stationXML converts a channel epoch.
+func (c *Channel) stationXML() stationxml.Channel {
+	channel := stationxml.Channel{
+		Code:         c.Code,
+		LocationCode: c.Location,
+		StartDate:    stationxml.Time{Time: c.StartTime},
+		EndDate:      stationxml.Time{Time: c.EndTime},
+		Latitude:     c.Latitude,
+		Longitude:    c.Longitude,
+		Elevation:    c.Elevation,
+		Depth:        c.Depth,
+		Azimuth:      c.Azimuth,
+		Dip:          c.Dip,
+		SampleRate:   c.SampleRate,
+		Response:     c.Response,
+	}
+	for _, flag := range c.Flags {
+		if typ, ok := channelTypes[flag]; ok {
+			channel.Types = append(channel.Types, typ)
+		}
+	}
+	if c.Instrument != "" {
+		channel.Sensor = &stationxml.Equipment{Description: c.Instrument}
+	}
+
+	return channel
+}
@@ -0,0 +1,271 @@
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+const (
+	MIN_RECORD_EXPONENT = 8  // 256 bytes, smallest logical record of a volume
+	MAX_RECORD_EXPONENT = 16 // 65536 bytes
+)
+
+// Volume is a full or dataless SEED volume: the volume, abbreviation and
+// station control headers, and the data records that follow them.
+type Volume struct {
+	Version      string // SEED format version, such as "02.4"
+	RecordLength int    // Logical record length in bytes
+	StartTime    time.Time
+	EndTime      time.Time
+	VolumeTime   time.Time
+	Organization string
+	Label        string
+
+	Formats       map[int]*DataFormat      // Data format dictionary (blockette 30) by code
+	Abbreviations map[int]string           // Generic abbreviations (blockette 33) by code
+	Units         map[int]stationxml.Units // Units abbreviations (blockette 34) by code
+	Stations      []Station                // Station epochs (blockette 50) with their channels
+	Blockettes    []Blockette              // Every control blockette, in volume order
+	Records       [][]byte                 // Data records, see ReadData
+
+	dictionary map[int]any // Response dictionaries (blockettes 41 to 48) by lookup key
+}
+
+// DataFormat is an entry of the data format dictionary, describing an
+// encoding with the data description language of SEED.
+type DataFormat struct {
+	Code   int
+	Name   string
+	Family int
+	Keys   []string
+}
+
+// Station is a station epoch.
+type Station struct {
+	Network   string
+	Code      string
+	Latitude  float64
+	Longitude float64
+	Elevation float64
+	Site      string
+	StartTime time.Time
+	EndTime   time.Time // Zero for an open epoch
+	Channels  []Channel
+}
+
+// Channel is a channel epoch and its response, which responses of stage 0
+// give the overall sensitivity of.
+type Channel struct {
+	Location     string
+	Code         string
+	Instrument   string // Description of the instrument abbreviation
+	SignalUnits  stationxml.Units
+	Latitude     float64
+	Longitude    float64
+	Elevation    float64
+	Depth        float64
+	Azimuth      float64
+	Dip          float64
+	FormatCode   int // Data format dictionary code of the records
+	RecordLength int // Data record length in bytes
+	SampleRate   float64
+	Flags        string // Channel type letters, such as "CG"
+	StartTime    time.Time
+	EndTime      time.Time // Zero for an open epoch
+	Response     *stationxml.Response
+}
+
+// Parse reads a SEED volume. Data records without blockette 1000 get one
+// built from the data format of their channel, so that mseedio can read
+// them.
+func Parse(r io.Reader) (*Volume, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := getVolumeRecordLength(data)
+	if err != nil {
+		return nil, err
+	}
+	v := &Volume{
+		RecordLength:  length,
+		Formats:       make(map[int]*DataFormat),
+		Abbreviations: make(map[int]string),
+		Units:         make(map[int]stationxml.Units),
+		dictionary:    make(map[int]any),
+	}
+
+	// Control headers first, data records wherever they are
+	var (
+		segments []controlSegment
+		records  [][]byte
+	)
+	for pos := 0; pos < len(data); {
+		if pos+length > len(data) {
+			return nil, fmt.Errorf("record at %d: %w", pos, io.ErrUnexpectedEOF)
+		}
+		record := data[pos : pos+length]
+		switch {
+		case isControlRecord(record):
+			segments = appendControlRecord(segments, record)
+		case isDataRecord(record):
+			if n := getDataRecordLength(record); n > 0 && pos+n <= len(data) {
+				record = data[pos : pos+n]
+			}
+			records = append(records, record)
+			pos += len(record)
+			continue
+		}
+		pos += length
+	}
+
+	for _, segment := range segments {
+		blockettes, err := getBlockettes(segment.data)
+		if err != nil {
+			return nil, fmt.Errorf("%c control header: %w", segment.kind, err)
+		}
+		v.Blockettes = append(v.Blockettes, blockettes...)
+	}
+
+	// Abbreviations before the stations referencing them
+	for i := range v.Blockettes {
+		if err := v.parseAbbreviation(&v.Blockettes[i]); err != nil {
+			return nil, fmt.Errorf("blockette %d: %w", v.Blockettes[i].Type, err)
+		}
+	}
+	for i := range v.Blockettes {
+		if err := v.parseStation(&v.Blockettes[i]); err != nil {
+			return nil, fmt.Errorf("blockette %d: %w", v.Blockettes[i].Type, err)
+		}
+	}
+	for i := range v.Stations {
+		for j := range v.Stations[i].Channels {
+			if r := v.Stations[i].Channels[j].Response; r != nil {
+				r.SetSensitivityUnits()
+			}
+		}
+	}
+
+	for _, record := range records {
+		completed, err := v.completeRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("data record %s: %w", record[:6], err)
+		}
+		v.Records = append(v.Records, completed)
+	}
+
+	return v, nil
+}
+
+// ReadFile reads a SEED volume from a file.
+func ReadFile(name string) (*Volume, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	v, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+// ReadData reads the data records of the volume with mseedio.
+func (v *Volume) ReadData(options ...mseedio.ReadOption) (*mseedio.MiniSeedData, error) {
+	if len(v.Records) == 0 {
+		return nil, fmt.Errorf("volume has no data record")
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(bytes.Join(v.Records, nil)), options...); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Encoding returns the mseedio encoding of a data format, told from its name
+// or else from its family and decoder keys.
+func (f *DataFormat) Encoding() (int, error) {
+	name := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(f.Name))
+	keys := strings.ToUpper(strings.Join(f.Keys, " "))
+	switch {
+	case strings.Contains(name, "STEIM2"):
+		return mseedio.STEIM2, nil
+	case strings.Contains(name, "STEIM1"):
+		return mseedio.STEIM1, nil
+	case strings.Contains(name, "ASCII"):
+		return mseedio.ASCII, nil
+	case strings.Contains(name, "IEEE") || strings.Contains(name, "FLOAT"):
+		if strings.Contains(name, "64") || strings.Contains(name, "DOUBLE") {
+			return mseedio.FLOAT64, nil
+		}
+		return mseedio.FLOAT32, nil
+	case f.Family == 50 && (strings.Contains(keys, "K0") || strings.Contains(keys, "D30")):
+		return mseedio.STEIM2, nil
+	case f.Family == 50:
+		return mseedio.STEIM1, nil
+	case f.Family == 0 && strings.Contains(keys, "W2"):
+		return mseedio.INT16, nil
+	case f.Family == 0 && strings.Contains(keys, "W3"):
+		return mseedio.INT24, nil
+	case f.Family == 0 && strings.Contains(keys, "W4"):
+		return mseedio.INT32, nil
+	}
+	return 0, fmt.Errorf("unsupported data format %q of family %d", f.Name, f.Family)
+}
+
+// getVolumeRecordLength reads the logical record length from the volume
+// identifier blockette (5, 8 or 10) opening the volume.
+func getVolumeRecordLength(data []byte) (int, error) {
+	const start = CONTROL_HEADER_LENGTH + BLOCKETTE_HEADER_LENGTH + 4
+	if len(data) < start+2 || !isControlRecord(data) || data[6] != 'V' {
+		return 0, fmt.Errorf("not a SEED volume, no volume control header")
+	}
+	switch typ := string(data[CONTROL_HEADER_LENGTH : CONTROL_HEADER_LENGTH+3]); typ {
+	case "005", "008", "010":
+	default:
+		return 0, fmt.Errorf("volume starts with blockette %s instead of a volume identifier", typ)
+	}
+
+	exponent, err := strconv.Atoi(strings.TrimSpace(string(data[start : start+2])))
+	if err != nil || exponent < MIN_RECORD_EXPONENT || exponent > MAX_RECORD_EXPONENT {
+		return 0, fmt.Errorf("invalid logical record length exponent %q", data[start:start+2])
+	}
+	return 1 << exponent, nil
+}
+
+// getChannel returns the channel epoch holding t.
+func (v *Volume) getChannel(network, station, location, channel string, t time.Time) (*Channel, error) {
+	for i := range v.Stations {
+		s := &v.Stations[i]
+		if s.Code != station || s.Network != "" && network != "" && s.Network != network || !inEpoch(s.StartTime, s.EndTime, t) {
+			continue
+		}
+		for j := range s.Channels {
+			c := &s.Channels[j]
+			if c.Location == location && c.Code == channel && inEpoch(c.StartTime, c.EndTime, t) {
+				return c, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no channel %s.%s.%s.%s at %s in volume", network, station, location, channel, t.Format(time.RFC3339))
+}
+
+// inEpoch reports whether an epoch holds t, a zero end leaving it open. The
+// end is exclusive, as in stationxml and resp, so that an epoch ending when
+// the next starts leaves that instant to the next.
+func inEpoch(start, end, t time.Time) bool {
+	return !t.Before(start) && (end.IsZero() || t.Before(end))
+}
@@ -0,0 +1,383 @@
+package seed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+	"github.com/bclswl0827/mseedio/stationxml"
+)
+
+// getBlockette formats a control blockette from its fields.
+func getBlockette(typ int, fields ...string) string {
+	body := strings.Join(fields, "")
+	return fmt.Sprintf("%03d%04d%s", typ, BLOCKETTE_HEADER_LENGTH+len(body), body)
+}
+
+// e12 formats a number as the 12-byte exponential fields of blockettes.
+func e12(v float64) string { return fmt.Sprintf("%+12.5E", v) }
+
+// getControlRecords packs blockettes into 512-byte control records of a
+// kind, continuing them across records.
+func getControlRecords(sequence *int, kind byte, blockettes ...string) []byte {
+	const length = 512
+
+	var (
+		stream  = []byte(strings.Join(blockettes, ""))
+		records []byte
+	)
+	for continuation := byte(' '); len(stream) > 0; continuation = '*' {
+		*sequence++
+		record := bytes.Repeat([]byte{' '}, length)
+		copy(record, fmt.Sprintf("%06d%c%c", *sequence, kind, continuation))
+		n := copy(record[CONTROL_HEADER_LENGTH:], stream)
+		stream = stream[n:]
+		records = append(records, record...)
+	}
+	return records
+}
+
+// getDataRecord encodes a 512-byte Steim-2 record of IU.ANMO.00.BHZ at 40 Hz.
+func getDataRecord(t *testing.T, start time.Time, samples []int32) []byte {
+	var m mseedio.MiniSeedData
+	_ = m.Init(mseedio.STEIM2, mseedio.MSBFIRST)
+	err := m.Append(samples, &mseedio.AppendOptions{
+		SampleRate: 40, StartTime: start, RecordLength: 512, SequenceNumber: "000001",
+		NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(record) != 512 || string(record[48:50]) != "\x03\xe8" || record[44] != 0 || record[45] != 64 {
+		t.Fatalf("unexpected test record layout % x", record[:64])
+	}
+	return record
+}
+
+// getTestVolume returns a volume describing IU.ANMO.00.BHZ, its response
+// given partly through dictionaries and with a 40-tap FIR continuing across
+// records, followed by data records: one without blockettes, one with
+// blockette 100 only and one with blockette 1000.
+func getTestVolume(t *testing.T) ([]byte, [][]int32) {
+	var fir strings.Builder
+	for i := 0; i < 40; i++ {
+		fir.WriteString(fmt.Sprintf("%+14.7E", 0.0125))
+	}
+	sequence := 0
+	volume := getControlRecords(&sequence, 'V',
+		getBlockette(10, "02.4", "09", "2024,001~", "2024,002~", "2024,001,12:00:00.0000~", "TEST ORG~", "TEST LABEL~"),
+	)
+	volume = append(volume, getControlRecords(&sequence, 'A',
+		getBlockette(30, "Steim2 Integer Compression Format~", "0001", "050", "01", "F1 P4 W4 D C2 R1 P8 W4 D C2~"),
+		getBlockette(33, "001", "Geophone 1 Hz~"),
+		getBlockette(34, "001", "M/S~", "Velocity in Meters Per Second~"),
+		getBlockette(34, "002", "V~", "Volts~"),
+		getBlockette(34, "003", "COUNTS~", "Digital Counts~"),
+		getBlockette(43, "0001", "GEOPHONE~", "A", "001", "002", e12(1), e12(10),
+			"002", e12(0), e12(0), e12(0), e12(0), e12(0), e12(0), e12(0), e12(0),
+			"002", e12(-4.39823), e12(4.48709), e12(0), e12(0), e12(-4.39823), e12(-4.48709), e12(0), e12(0)),
+		getBlockette(48, "0002", "GEOPHONE GAIN~", e12(30), e12(10), "00"),
+	)...)
+	volume = append(volume, getControlRecords(&sequence, 'S',
+		getBlockette(50, "ANMO ", "+34.945981", "-106.457133", "+1671.0", "0001", "000",
+			"Albuquerque, New Mexico, USA~", "000", "3210", "10", "2020,001~", "~", "N", "IU"),
+		getBlockette(52, "00", "BHZ", "0000", "001", "~", "001", "003",
+			"+34.945981", "-106.457133", "+1671.0", "145.0", "  0.0", "-90.0",
+			"0001", "12", fmt.Sprintf("%10.4E", 40.0), fmt.Sprintf("%10.4E", 0.0), "0000", "CG~",
+			"2020,001~", "~", "N"),
+		getBlockette(60, "01", "01", "02", "0001", "0002"),
+		getBlockette(54, "D", "02", "002", "003", "0000", "0000"),
+		getBlockette(57, "02", fmt.Sprintf("%10.4E", 40.0), "00001", "00000", fmt.Sprintf("%+11.4E", 0.0), fmt.Sprintf("%+11.4E", 0.0)),
+		getBlockette(58, "02", e12(1e6), e12(0), "00"),
+		getBlockette(61, "03", "FIR_3~", "C", "003", "003", "0040", fir.String()),
+		getBlockette(57, "03", fmt.Sprintf("%10.4E", 40.0), "00001", "00000", fmt.Sprintf("%+11.4E", 0.975), fmt.Sprintf("%+11.4E", 0.975)),
+		getBlockette(58, "03", e12(1), e12(0), "00"),
+		getBlockette(58, "00", e12(3e7), e12(10), "00"),
+	)...)
+
+	// Data records of 100 samples each, 2.5 seconds apart
+	var samples [][]int32
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		s := make([]int32, 100)
+		for j := range s {
+			s[j] = int32(i*1000 + j*j)
+		}
+		samples = append(samples, s)
+		record := getDataRecord(t, start.Add(time.Duration(i)*2500*time.Millisecond), s)
+
+		switch i {
+		case 0:
+			// No blockette at all
+			record[39] = 0
+			copy(record[46:48], []byte{0, 0})
+			copy(record[48:56], make([]byte, 8))
+		case 1:
+			// Blockette 100, with the actual sample rate, instead of 1000
+			copy(record[48:60], []byte{0, 100, 0, 0, 0x42, 0x20, 0, 0, 0, 0, 0, 0})
+		}
+		volume = append(volume, record...)
+	}
+
+	return volume, samples
+}
+
+func TestParse(t *testing.T) {
+	data, _ := getTestVolume(t)
+	v, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Version != "02.4" || v.RecordLength != 512 || v.Organization != "TEST ORG" || v.Label != "TEST LABEL" ||
+		!v.VolumeTime.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected volume header %+v", v)
+	}
+	if len(v.Blockettes) != 18 || v.Formats[1].Name != "Steim2 Integer Compression Format" || v.Units[3].Name != "COUNTS" {
+		t.Errorf("unexpected %d blockettes, formats %+v and units %+v", len(v.Blockettes), v.Formats, v.Units)
+	}
+	if len(v.Stations) != 1 || len(v.Stations[0].Channels) != 1 {
+		t.Fatalf("want one station with one channel, got %+v", v.Stations)
+	}
+
+	s := &v.Stations[0]
+	if s.Network != "IU" || s.Code != "ANMO" || s.Longitude != -106.457133 || s.Site != "Albuquerque, New Mexico, USA" ||
+		!s.StartTime.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) || !s.EndTime.IsZero() {
+		t.Errorf("unexpected station %+v", s)
+	}
+	c := &s.Channels[0]
+	if c.Location != "00" || c.Code != "BHZ" || c.Instrument != "Geophone 1 Hz" || c.SignalUnits.Name != "M/S" ||
+		c.Depth != 145 || c.Dip != -90 || c.SampleRate != 40 || c.RecordLength != 4096 || c.Flags != "CG" {
+		t.Errorf("unexpected channel %+v", c)
+	}
+
+	r := c.Response
+	if r == nil || len(r.Stages) != 3 {
+		t.Fatalf("want 3 response stages, got %+v", r)
+	}
+	pz := r.Stages[0].PolesZeros
+	if pz == nil || pz.Name != "GEOPHONE" || pz.PzTransferFunctionType != stationxml.LAPLACE_RADIANS ||
+		len(pz.Zeros) != 2 || len(pz.Poles) != 2 || pz.Poles[1].Complex() != complex(-4.39823, -4.48709) ||
+		pz.InputUnits.Name != "M/S" || pz.OutputUnits.Name != "V" || r.Stages[0].StageGain.Value != 30 {
+		t.Errorf("unexpected stage 1 %+v, poles and zeros %+v", r.Stages[0], pz)
+	}
+	if cf := r.Stages[1].Coefficients; cf == nil || cf.CfTransferFunctionType != stationxml.DIGITAL ||
+		r.Stages[1].Decimation.InputSampleRate != 40 || r.Stages[1].StageGain.Value != 1e6 {
+		t.Errorf("unexpected stage 2 %+v", r.Stages[1])
+	}
+	fir := r.Stages[2].FIR
+	if fir == nil || fir.Name != "FIR_3" || fir.Symmetry != stationxml.SYMMETRY_EVEN || len(fir.NumeratorCoefficients) != 40 ||
+		r.Stages[2].Decimation.Correction != 0.975 {
+		t.Errorf("unexpected stage 3 %+v", r.Stages[2])
+	}
+	if sens := r.InstrumentSensitivity; sens == nil || sens.Value != 3e7 || sens.InputUnits.Name != "M/S" || sens.OutputUnits.Name != "COUNTS" {
+		t.Errorf("unexpected sensitivity %+v", sens)
+	}
+}
+
+func TestReadData(t *testing.T) {
+	data, samples := getTestVolume(t)
+	v, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Records) != 3 || len(v.Records[0]) != 512 || len(v.Records[1]) != 1024 || len(v.Records[2]) != 512 {
+		t.Fatalf("unexpected record lengths of %d records", len(v.Records))
+	}
+
+	m, err := v.ReadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Series) != 3 {
+		t.Fatalf("want 3 records, got %d", len(m.Series))
+	}
+	for i, series := range m.Series {
+		if series.BlocketteSection.BlocketteCode != 1000 || series.BlocketteSection.EncodingFormat != mseedio.STEIM2 {
+			t.Errorf("record %d: unexpected blockette %+v", i, series.BlocketteSection)
+		}
+		decoded := series.DataSection.Decoded
+		var got []int32
+		for _, v := range decoded {
+			switch v := v.(type) {
+			case int32:
+				got = append(got, v)
+			case []int32:
+				got = append(got, v...)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(samples[i]) {
+			t.Errorf("record %d: want samples %v, got %v", i, samples[i], got)
+		}
+	}
+	if b := m.Series[1].Blockettes; len(b) != 1 || b[0].BlocketteCode != 100 {
+		t.Errorf("want blockette 100 chained after 1000, got %+v", b)
+	}
+
+	traces := m.Traces()
+	if len(traces) != 1 || len(traces[0].Samples) != 300 {
+		t.Errorf("want one trace of 300 samples, got %d traces", len(traces))
+	}
+}
+
+func TestBlockette1000Moved(t *testing.T) {
+	data, _ := getTestVolume(t)
+	volume := data[:len(data)-3*512]
+
+	// Blockette 100 first, chained to blockette 1000, and the Steim frames
+	// at 128 of a 1024-byte record
+	samples := []int32{5, -3, 100, 7, 7, 0, -250, 1}
+	record := getDataRecord(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), samples)
+	moved := make([]byte, 1024)
+	copy(moved, record[:48])
+	copy(moved[48:60], []byte{0, 100, 0, 60, 0x42, 0x20, 0, 0, 0, 0, 0, 0})
+	copy(moved[60:68], record[48:56])
+	moved[66] = 10
+	moved[39] = 2
+	moved[44], moved[45] = 0, 128
+	copy(moved[128:], record[64:])
+
+	v, err := Parse(bytes.NewReader(append(volume, moved...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := v.Records[0]
+	if len(got) != 1024 || string(got[46:48]) != "\x00\x30" || string(got[48:52]) != "\x03\xe8\x00\x38" ||
+		string(got[56:60]) != "\x00\x64\x00\x00" || got[39] != 2 {
+		t.Fatalf("want blockette 1000 at 48 chained to 100 at 56, got % x", got[:68])
+	}
+
+	m, err := v.ReadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	traces := m.Traces()
+	if len(traces) != 1 || fmt.Sprint(traces[0].Samples) != fmt.Sprint(samples) || traces[0].SampleRate != 40 {
+		t.Errorf("want samples %v, got %+v", samples, traces)
+	}
+	if b := m.Series[0].Blockettes; len(b) != 1 || b[0].BlocketteCode != 100 {
+		t.Errorf("want blockette 100 chained after 1000, got %+v", b)
+	}
+}
+
+func TestChannelEpochs(t *testing.T) {
+	change := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := &Volume{Stations: []Station{{Network: "IU", Code: "ANMO", Channels: []Channel{
+		{Location: "00", Code: "BHZ", FormatCode: 1, EndTime: change},
+		{Location: "00", Code: "BHZ", FormatCode: 2, StartTime: change},
+	}}}}
+
+	// The instant one epoch ends and the next starts belongs to the next
+	for _, c := range []struct {
+		t    time.Time
+		want int
+	}{{change.Add(-time.Second), 1}, {change, 2}, {change.Add(time.Second), 2}} {
+		got, err := v.getChannel("IU", "ANMO", "00", "BHZ", c.t)
+		if err != nil || got.FormatCode != c.want {
+			t.Errorf("at %s: want format %d, got %+v: %v", c.t, c.want, got, err)
+		}
+	}
+}
+
+func TestStationXML(t *testing.T) {
+	data, _ := getTestVolume(t)
+	v, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := v.ReadData()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Through a document, to check it is a valid one
+	var buf bytes.Buffer
+	if err := v.StationXML().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := stationxml.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Source != "TEST ORG" || len(inv.Networks) != 1 || !inv.Networks[0].StartDate.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected document %+v", inv)
+	}
+
+	c, err := inv.LookupRecord(&m.Series[0].FixedSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Sensor.Description != "Geophone 1 Hz" || len(c.Types) != 2 || c.Types[0] != "CONTINUOUS" ||
+		len(c.Response.Stages) != 3 || c.Response.InstrumentSensitivity.Value != 3e7 {
+		t.Errorf("unexpected channel %+v", c)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	data, _ := getTestVolume(t)
+	if _, err := Parse(bytes.NewReader(data[:len(data)-100])); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("want io.ErrUnexpectedEOF for a truncated volume, got %v", err)
+	}
+
+	record := getDataRecord(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), make([]int32, 10))
+	if _, err := Parse(bytes.NewReader(record)); err == nil {
+		t.Error("want error for miniSEED without volume header")
+	}
+
+	// A record of a channel not in the volume cannot be given blockette 1000
+	other := append([]byte{}, data...)
+	copy(other[len(other)-3*512+15:], "BHN")
+	if _, err := Parse(bytes.NewReader(other)); err == nil || !strings.Contains(err.Error(), "no channel") {
+		t.Errorf("want error for a record of an unknown channel, got %v", err)
+	}
+
+	sequence := 0
+	for _, volume := range [][]byte{
+		getControlRecords(&sequence, 'V', getBlockette(10, "02.4", "05", "2024,001~")),
+		getControlRecords(&sequence, 'V', getBlockette(11, "000")),
+		getControlRecords(&sequence, 'V', getBlockette(10, "02.4", "09", "2024,001~", "2024,002~"), "123"),
+		append(getControlRecords(&sequence, 'V', getBlockette(10, "02.4", "09", "2024,001~", "2024,002~")),
+			getControlRecords(&sequence, 'S', getBlockette(52, "00BHZ"))...),
+		append(getControlRecords(&sequence, 'V', getBlockette(10, "02.4", "09", "2024,001~", "2024,002~")),
+			getControlRecords(&sequence, 'S', getBlockette(50, "ANMO ", "north"))...),
+	} {
+		if _, err := Parse(bytes.NewReader(volume)); err == nil {
+			t.Errorf("want error parsing %q", bytes.TrimRight(volume, " "))
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	tests := []struct {
+		format DataFormat
+		want   int
+	}{
+		{DataFormat{Name: "Steim1 Integer Compression Format", Family: 50}, mseedio.STEIM1},
+		{DataFormat{Name: "STEIM-2 Compression", Family: 50}, mseedio.STEIM2},
+		{DataFormat{Name: "Compressed", Family: 50, Keys: []string{"F1 P4 W4 D0-31 C2 R1 P8 W4 D0-31 C2", "K0 X D30"}}, mseedio.STEIM2},
+		{DataFormat{Name: "Compressed", Family: 50, Keys: []string{"F1 P4 W4 D C2 R1 P8 W4 D C2", "T1 Y4 W1 D C2"}}, mseedio.STEIM1},
+		{DataFormat{Name: "16-bit Integer Format", Family: 0, Keys: []string{"M0", "W2 D0-15 C2"}}, mseedio.INT16},
+		{DataFormat{Name: "24-bit Integer Format", Family: 0, Keys: []string{"M0", "W3 D0-23 C2"}}, mseedio.INT24},
+		{DataFormat{Name: "32-bit Integer Format", Family: 0, Keys: []string{"M0", "W4 D0-31 C2"}}, mseedio.INT32},
+		{DataFormat{Name: "IEEE Floating Point", Family: 0}, mseedio.FLOAT32},
+		{DataFormat{Name: "IEEE 64-bit Floating Point", Family: 0}, mseedio.FLOAT64},
+	}
+	for _, test := range tests {
+		if got, err := test.format.Encoding(); err != nil || got != test.want {
+			t.Errorf("%s: want %d, got %d, %v", test.format.Name, test.want, got, err)
+		}
+	}
+
+	if _, err := (&DataFormat{Name: "Gain Ranged", Family: 1}).Encoding(); err == nil {
+		t.Error("want error for a gain-ranged format")
+	}
+}
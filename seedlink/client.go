@@ -0,0 +1,337 @@
+package seedlink
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Selection is what AddStation requests from one station.
+type Selection struct {
+	Network   string
+	Station   string
+	Selectors []string  // SELECT patterns, e.g. "BH?" or "00BHZ.D" in SeedLink 3, "00_B_H_Z" in SeedLink 4
+	Resume    bool      // Carry on right after Sequence
+	Sequence  int64     // Last sequence number received, see Client.Sequences
+	StartTime time.Time // Only records from StartTime on (TIME command unless resuming)
+	EndTime   time.Time // Only records up to EndTime, requires StartTime
+}
+
+// Client is a connection to a SeedLink server.
+type Client struct {
+	Version      int    // Negotiated protocol version, 3 or 4
+	Software     string // First line of the HELLO reply, e.g. "SeedLink v3.1 (2020.075) :: SLPROTO:3.1"
+	Organization string // Second line of the HELLO reply
+
+	conn      net.Conn
+	reader    *bufio.Reader
+	opts      *clientOptions
+	stations  int
+	started   bool
+	lastRead  time.Time
+	sequences map[string]int64
+}
+
+// Dial connects to a SeedLink server at address (host:port) and says HELLO,
+// see NewClient.
+func Dial(ctx context.Context, address string, options ...Option) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := NewClient(conn, options...)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewClient says HELLO over an established connection and switches to
+// SeedLink 4 if both the server and WithVersion allow it.
+func NewClient(conn net.Conn, options ...Option) (*Client, error) {
+	c := &Client{
+		Version:   3,
+		conn:      conn,
+		reader:    bufio.NewReader(conn),
+		opts:      getOptions(options),
+		sequences: map[string]int64{},
+	}
+
+	if err := c.write("HELLO"); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.Software, err = c.readLine(); err != nil {
+		return nil, err
+	}
+	if c.Organization, err = c.readLine(); err != nil {
+		return nil, err
+	}
+
+	if c.opts.version >= 4 && strings.Contains(c.Software, "SLPROTO:4") {
+		if err := c.command("SLPROTO 4.0"); err != nil {
+			return nil, err
+		}
+		c.Version = 4
+	}
+
+	return c, nil
+}
+
+// AddStation requests data from a station: STATION, one SELECT per selector,
+// then DATA, TIME or FETCH depending on the selection and WithFetch.
+func (c *Client) AddStation(s Selection) error {
+	if c.started {
+		return fmt.Errorf("cannot add station after Start")
+	}
+
+	station := fmt.Sprintf("STATION %s %s", s.Station, s.Network)
+	if c.Version >= 4 {
+		station = "STATION " + getStationID(s.Network, s.Station)
+	}
+	if err := c.command(station); err != nil {
+		return err
+	}
+	for _, selector := range s.Selectors {
+		if err := c.command("SELECT " + selector); err != nil {
+			return err
+		}
+	}
+	if err := c.command(c.getDataCommand(&s)); err != nil {
+		return err
+	}
+
+	c.stations++
+	return nil
+}
+
+// getDataCommand returns the command starting the transfer of a station.
+func (c *Client) getDataCommand(s *Selection) string {
+	if c.Version >= 4 {
+		cmd := "DATA"
+		switch {
+		case s.Resume:
+			cmd += fmt.Sprintf(" %d", s.Sequence+1)
+		case !s.StartTime.IsZero():
+			cmd += " ALL"
+		}
+		if !s.StartTime.IsZero() {
+			cmd += " " + s.StartTime.UTC().Format(time.RFC3339Nano)
+			if !s.EndTime.IsZero() {
+				cmd += " " + s.EndTime.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		return cmd
+	}
+
+	cmd := "DATA"
+	if c.opts.fetch {
+		cmd = "FETCH"
+	}
+	switch {
+	case s.Resume:
+		cmd += fmt.Sprintf(" %06X", (s.Sequence+1)&V3_MAX_SEQUENCE)
+		if !s.StartTime.IsZero() {
+			cmd += " " + getV3Time(s.StartTime)
+		}
+	case !s.StartTime.IsZero():
+		cmd = "TIME " + getV3Time(s.StartTime)
+		if !s.EndTime.IsZero() {
+			cmd += " " + getV3Time(s.EndTime)
+		}
+	}
+	return cmd
+}
+
+// Info sends INFO with the given level (ID, CAPABILITIES, STATIONS, STREAMS,
+// CONNECTIONS...) and returns the document sent back, XML in SeedLink 3 and
+// JSON in SeedLink 4. It must be called before Start.
+func (c *Client) Info(level string) (string, error) {
+	if c.started {
+		return "", fmt.Errorf("cannot request INFO after Start")
+	}
+	if err := c.write("INFO " + level); err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for {
+		c.setDeadline()
+		p, err := readPacket(c.reader)
+		if err != nil {
+			return "", err
+		}
+		if !p.Info {
+			continue
+		}
+
+		text.WriteString(getInfoText(p))
+		if !p.More {
+			return text.String(), nil
+		}
+	}
+}
+
+// Start ends the handshake, after which the server sends packets. In
+// SeedLink 4 dial-up mode it sends ENDFETCH rather than END.
+func (c *Client) Start() error {
+	if c.started {
+		return fmt.Errorf("already started")
+	}
+	if c.stations == 0 {
+		return fmt.Errorf("no station added")
+	}
+
+	cmd := "END"
+	if c.Version >= 4 && c.opts.fetch {
+		cmd = "ENDFETCH"
+	}
+	if err := c.write(cmd); err != nil {
+		return err
+	}
+
+	c.started = true
+	c.lastRead = time.Now()
+	return nil
+}
+
+// Next returns the next data packet, skipping INFO packets such as keepalive
+// replies. It returns io.EOF once a FETCH stream is over. If only the record
+// of a packet cannot be parsed, the packet is returned along with the error
+// and the connection remains usable.
+func (c *Client) Next() (*Packet, error) {
+	if !c.started {
+		return nil, fmt.Errorf("not started")
+	}
+
+	for {
+		if err := c.wait(); err != nil {
+			return nil, err
+		}
+		c.setDeadline()
+		p, err := readPacket(c.reader)
+		if err != nil {
+			return p, err
+		}
+		c.lastRead = time.Now()
+		if p.Info {
+			continue
+		}
+
+		if err := parseRecord(p); err != nil {
+			return p, err
+		}
+		c.sequences[p.StationID] = p.Sequence
+		return p, nil
+	}
+}
+
+// wait blocks until a packet starts arriving, sending INFO ID every
+// keepalive interval meanwhile and failing after the timeout.
+func (c *Client) wait() error {
+	for {
+		var deadline time.Time
+		if c.opts.keepalive > 0 {
+			deadline = time.Now().Add(c.opts.keepalive)
+		}
+		if c.opts.timeout > 0 {
+			if t := c.lastRead.Add(c.opts.timeout); deadline.IsZero() || t.Before(deadline) {
+				deadline = t
+			}
+		}
+		if err := c.conn.SetReadDeadline(deadline); err != nil {
+			return err
+		}
+
+		_, err := c.reader.Peek(1)
+		var netErr net.Error
+		if err == nil || !errors.As(err, &netErr) || !netErr.Timeout() {
+			return err
+		}
+		if c.opts.timeout > 0 && time.Since(c.lastRead) >= c.opts.timeout {
+			return fmt.Errorf("no data received for %s", c.opts.timeout)
+		}
+		if err := c.write("INFO ID"); err != nil {
+			return err
+		}
+	}
+}
+
+// Sequences returns the last sequence number received per station, keyed by
+// NET_STA, for Selection.Sequence when reconnecting.
+func (c *Client) Sequences() map[string]int64 {
+	sequences := make(map[string]int64, len(c.sequences))
+	for k, v := range c.sequences {
+		sequences[k] = v
+	}
+
+	return sequences
+}
+
+// Close says BYE and closes the connection.
+func (c *Client) Close() error {
+	_ = c.write("BYE")
+	return c.conn.Close()
+}
+
+// command sends cmd and expects OK back.
+func (c *Client) command(cmd string) error {
+	if err := c.write(cmd); err != nil {
+		return err
+	}
+
+	reply, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if reply != "OK" {
+		return fmt.Errorf("%s: server replied %q", cmd, reply)
+	}
+	return nil
+}
+
+// write sends a command line.
+func (c *Client) write(cmd string) error {
+	if c.opts.timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(c.opts.timeout)); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(c.conn, cmd+"\r\n")
+	return err
+}
+
+// readLine reads a reply line without its line ending.
+func (c *Client) readLine() (string, error) {
+	c.setDeadline()
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline bounds the next read by the timeout, if any.
+func (c *Client) setDeadline() {
+	var deadline time.Time
+	if c.opts.timeout > 0 {
+		deadline = time.Now().Add(c.opts.timeout)
+	}
+	_ = c.conn.SetReadDeadline(deadline)
+}
+
+// getV3Time formats t as the comma-separated time of SeedLink 3 commands.
+func getV3Time(t time.Time) string {
+	return t.UTC().Format("2006,01,02,15,04,05")
+}
@@ -0,0 +1,403 @@
+package seedlink
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// fakeServer is a minimal SeedLink server holding the records of one station,
+// sequence numbers counting from 0.
+type fakeServer struct {
+	listener  net.Listener
+	version   int  // Highest protocol version offered
+	keepalive bool // Hold data until a keepalive INFO ID arrives
+	records   [][]byte
+
+	mu       sync.Mutex
+	commands []string
+}
+
+// newFakeServer serves n records of IU.ANMO on localhost.
+func newFakeServer(t *testing.T, version, n int) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: listener, version: version, records: getTestRecords(t, n)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// getTestRecords encodes n 512-byte Steim-2 records of IU.ANMO.00.BHZ.
+func getTestRecords(t *testing.T, n int) [][]byte {
+	var records [][]byte
+	for i := 0; i < n; i++ {
+		samples := make([]int32, 100)
+		for j := range samples {
+			samples[j] = int32(i*100 + j)
+		}
+
+		var m mseedio.MiniSeedData
+		_ = m.Init(mseedio.STEIM2, mseedio.MSBFIRST)
+		err := m.Append(samples, &mseedio.AppendOptions{
+			SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+			SequenceNumber: fmt.Sprintf("%06d", i+1), RecordLength: RECORD_LENGTH,
+			NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		record, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// getCommands returns the commands received so far.
+func (s *fakeServer) getCommands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// serve answers the commands of one client.
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	var (
+		reader    = bufio.NewReader(conn)
+		version   = 3
+		start     = 0
+		started   = false
+		known     = false
+		fetchMode = false
+	)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		fields := strings.Fields(cmd)
+		switch fields[0] {
+		case "HELLO":
+			protocols := "SLPROTO:3.1"
+			if s.version >= 4 {
+				protocols = "SLPROTO:4.0 SLPROTO:3.1"
+			}
+			reply("SeedLink v4.0 (fake) :: " + protocols + " CAP")
+			reply("Test organization")
+		case "SLPROTO":
+			version = 4
+			reply("OK")
+		case "STATION":
+			known = cmd == "STATION ANMO IU" || cmd == "STATION IU_ANMO"
+			if known {
+				reply("OK")
+			} else {
+				reply("ERROR")
+			}
+		case "SELECT", "TIME":
+			reply("OK")
+		case "DATA", "FETCH":
+			fetchMode = fields[0] == "FETCH"
+			if len(fields) > 1 && fields[1] != "ALL" {
+				base := 16
+				if version >= 4 {
+					base = 10
+				}
+				n, _ := strconv.ParseInt(fields[1], base, 64)
+				start = int(n)
+			}
+			reply("OK")
+		case "INFO":
+			if started && !s.keepalive {
+				continue
+			}
+			s.writeInfo(conn, version, "<seedlink software=\"fake\" "+strings.Repeat("x", 600)+"/>")
+			if started {
+				s.writeRecords(conn, version, start)
+			}
+		case "END", "ENDFETCH":
+			started = true
+			if !s.keepalive {
+				s.writeRecords(conn, version, start)
+			}
+			if fetchMode || fields[0] == "ENDFETCH" {
+				io.WriteString(conn, "END")
+				return
+			}
+		case "BYE":
+			return
+		}
+	}
+}
+
+// writeRecords sends the records from sequence number start on.
+func (s *fakeServer) writeRecords(conn net.Conn, version, start int) {
+	for i := start; i < len(s.records); i++ {
+		if version >= 4 {
+			writeV4Packet(conn, V4_FORMAT_MSEED2, V4_SUBFORMAT_DATA, int64(i), "IU_ANMO", s.records[i])
+		} else {
+			writeV3Packet(conn, int64(i), s.records[i])
+		}
+	}
+}
+
+// writeInfo sends text as SeedLink 3 log records or a SeedLink 4 JSON packet.
+func (s *fakeServer) writeInfo(conn net.Conn, version int, text string) {
+	if version >= 4 {
+		writeV4Packet(conn, V4_FORMAT_JSON, V4_SUBFORMAT_INFO, 0, "", []byte(`{"software":"fake"}`))
+		return
+	}
+
+	records, _ := getInfoRecords(text)
+	for i, record := range records {
+		writeV3Info(conn, i < len(records)-1, record)
+	}
+}
+
+func TestClientV3(t *testing.T) {
+	s := newFakeServer(t, 3, 5)
+	c, err := Dial(context.Background(), s.listener.Addr().String(), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Version != 3 || !strings.Contains(c.Software, "SLPROTO:3.1") || c.Organization != "Test organization" {
+		t.Errorf("unexpected HELLO reply: version %d, %q, %q", c.Version, c.Software, c.Organization)
+	}
+	info, err := c.Info("ID")
+	if err != nil || !strings.HasPrefix(info, "<seedlink") || !strings.HasSuffix(info, "/>") {
+		t.Fatalf("unexpected INFO reply %q: %v", info, err)
+	}
+
+	if err := c.AddStation(Selection{Network: "IU", Station: "ANMO", Selectors: []string{"00BHZ"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		p, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := p.Series.DataSection.Decoded
+		if p.Sequence != int64(i) || p.StationID != "IU_ANMO" || len(decoded) != 100 || decoded[0] != int32(i*100) {
+			t.Fatalf("packet %d: sequence %d, station %q, %d samples", i, p.Sequence, p.StationID, len(decoded))
+		}
+	}
+	if seq := c.Sequences()["IU_ANMO"]; seq != 4 {
+		t.Errorf("want last sequence 4, got %d", seq)
+	}
+
+	want := []string{"HELLO", "INFO ID", "STATION ANMO IU", "SELECT 00BHZ", "DATA", "END"}
+	if got := s.getCommands(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("want commands %q, got %q", want, got)
+	}
+}
+
+func TestClientResume(t *testing.T) {
+	for _, version := range []int{3, 4} {
+		s := newFakeServer(t, version, 5)
+		c, err := Dial(context.Background(), s.listener.Addr().String(), WithTimeout(5*time.Second))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := c.AddStation(Selection{Network: "IU", Station: "ANMO", Resume: true, Sequence: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		p, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Sequence != 3 || p.StationID != "IU_ANMO" {
+			t.Errorf("version %d: want sequence 3 of IU_ANMO, got %d of %q", version, p.Sequence, p.StationID)
+		}
+		c.Close()
+
+		want := "DATA 000003"
+		if version == 4 {
+			want = "DATA 3"
+		}
+		if got := s.getCommands(); !strings.Contains(strings.Join(got, "|"), "|"+want+"|END") {
+			t.Errorf("version %d: want %q, got commands %q", version, want, got)
+		}
+	}
+}
+
+func TestClientV4(t *testing.T) {
+	s := newFakeServer(t, 4, 3)
+	c, err := Dial(context.Background(), s.listener.Addr().String(), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Version != 4 {
+		t.Fatalf("want version 4, got %d", c.Version)
+	}
+	if info, err := c.Info("ID"); err != nil || info != `{"software":"fake"}` {
+		t.Fatalf("unexpected INFO reply %q: %v", info, err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = c.AddStation(Selection{Network: "IU", Station: "ANMO", Selectors: []string{"00_B_H_Z"}, StartTime: start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		p, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Sequence != int64(i) || p.StationID != "IU_ANMO" || p.Series.FixedSection.ChannelCode != "BHZ" {
+			t.Fatalf("packet %d: sequence %d of %q", i, p.Sequence, p.StationID)
+		}
+	}
+
+	got := s.getCommands()
+	for _, want := range []string{"SLPROTO 4.0", "STATION IU_ANMO", "SELECT 00_B_H_Z", "DATA ALL 2024-01-01T00:00:00Z"} {
+		if !strings.Contains(strings.Join(got, "|"), want) {
+			t.Errorf("missing command %q in %q", want, got)
+		}
+	}
+}
+
+func TestClientFetch(t *testing.T) {
+	s := newFakeServer(t, 3, 4)
+	c, err := Dial(context.Background(), s.listener.Addr().String(), WithFetch(), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.AddStation(Selection{Network: "IU", Station: "ANMO", Resume: true, Sequence: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i < 4; i++ {
+		if p, err := c.Next(); err != nil || p.Sequence != int64(i) {
+			t.Fatalf("want sequence %d, got %v: %v", i, p, err)
+		}
+	}
+	if _, err := c.Next(); err != io.EOF {
+		t.Errorf("want io.EOF at the end of a fetch, got %v", err)
+	}
+	if got := s.getCommands(); got[2] != "FETCH 000002" {
+		t.Errorf("unexpected FETCH command in %q", got)
+	}
+}
+
+func TestClientTimeWindow(t *testing.T) {
+	s := newFakeServer(t, 3, 1)
+	c, err := Dial(context.Background(), s.listener.Addr().String(), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := c.AddStation(Selection{Network: "IU", Station: "ANMO", StartTime: start, EndTime: start.Add(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.getCommands(); got[2] != "TIME 2024,01,01,00,00,00 2024,01,01,01,00,00" {
+		t.Errorf("unexpected TIME command in %q", got)
+	}
+}
+
+func TestClientKeepalive(t *testing.T) {
+	s := newFakeServer(t, 3, 1)
+	s.keepalive = true
+	c, err := Dial(context.Background(), s.listener.Addr().String(),
+		WithKeepalive(50*time.Millisecond), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := c.AddStation(Selection{Network: "IU", Station: "ANMO"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Info || p.Sequence != 0 {
+		t.Errorf("expected the data packet after the keepalive reply, got %+v", p)
+	}
+	if got := s.getCommands(); got[len(got)-1] != "INFO ID" {
+		t.Errorf("expected a keepalive INFO ID, got %q", got)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	s := newFakeServer(t, 4, 1)
+	c, err := Dial(context.Background(), s.listener.Addr().String(), WithVersion(3), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Version != 3 {
+		t.Errorf("WithVersion(3): got version %d", c.Version)
+	}
+	if err := c.Start(); err == nil {
+		t.Error("Start: expected an error without stations")
+	}
+	if err := c.AddStation(Selection{Network: "XX", Station: "NONE"}); err == nil {
+		t.Error("AddStation: expected an error for an unknown station")
+	}
+	if _, err := c.Next(); err == nil {
+		t.Error("Next: expected an error before Start")
+	}
+
+	// A packet cut short by the server is an error, not a panic
+	_, err = readPacket(bufio.NewReader(strings.NewReader("SL00000A\x00\x01")))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("want io.ErrUnexpectedEOF for a truncated packet, got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+// Package seedlink implements both sides of SeedLink, the TCP protocol used
+// to stream miniSEED records in real time, in versions 3 and 4.
+//
+// # Receiving data
+//
+//	c, err := seedlink.Dial(ctx, "rtserve.iris.washington.edu:18000")
+//	if err != nil {
+//		// handle error
+//	}
+//	defer c.Close()
+//
+//	c.AddStation(seedlink.Selection{Network: "IU", Station: "ANMO", Selectors: []string{"00BHZ"}})
+//	c.Start()
+//	for {
+//		p, err := c.Next()
+//		if err != nil {
+//			// handle error, see Next for which ones allow carrying on
+//		}
+//		fmt.Println(p.Sequence, p.Series.DataSection.Decoded)
+//	}
+//
+// Dial says HELLO and switches to SeedLink 4 when the server offers it (see
+// WithVersion). AddStation sends the STATION, SELECT and DATA, TIME or FETCH
+// commands of a station, and Start sends END, after which Next returns one
+// Packet per record with the record parsed by mseedio.
+//
+// # Resuming
+//
+// The client remembers the last sequence number received per station, see
+// Sequences. Passing it back in Selection.Sequence with Resume set on the next
+// connection carries on right after it, as far as the server buffer allows.
+//
+// # Keepalive
+//
+// With WithKeepalive, the client sends INFO ID whenever no packet has arrived
+// for the given interval, so that idle connections survive firewalls; the
+// INFO packets sent back are skipped by Next. Info requests INFO documents
+// (ID, CAPABILITIES, STATIONS, STREAMS, CONNECTIONS) before Start.
+//
+// # Serving data
+//
+//	s := seedlink.NewServer("My organization", 0)
+//	go s.ListenAndServe(":18000")
+//
+//	// Records of 512 bytes, see AppendOptions.RecordLength
+//	m.Append(samples, &mseedio.AppendOptions{RecordLength: seedlink.RECORD_LENGTH, ...})
+//	records, _ := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+//	s.Write(records)
+//
+// Server is an io.Writer: the records written to it go to the ring buffer of
+// their station, numbered from 0, and on to every client streaming that
+// station. Clients select stations (with wildcards), streams (SELECT) and
+// where to start: new records only, a sequence number to resume from, or a
+// time window. INFO ID, CAPABILITIES, STATIONS and STREAMS are answered.
+// Clients that stop reading for Server.WriteTimeout are dropped, as are
+// clients sending a command line longer than MAX_COMMAND_LENGTH. A client
+// may subscribe to MAX_STATIONS stations of MAX_SELECTORS selectors each.
+package seedlink
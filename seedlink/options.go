@@ -0,0 +1,57 @@
+package seedlink
+
+import "time"
+
+// Option configures Dial and NewClient.
+type Option func(*clientOptions)
+
+// clientOptions holds the settings applied by Option values.
+type clientOptions struct {
+	version   int           // Highest protocol version to negotiate
+	timeout   time.Duration // Network timeout, 0 for none
+	keepalive time.Duration // Idle time before sending INFO ID, 0 for none
+	fetch     bool          // Dial-up mode, FETCH instead of DATA
+}
+
+// WithVersion caps the protocol version to negotiate, 3 or 4 (the default).
+// SeedLink 4 is only used when the server offers it in its HELLO reply.
+func WithVersion(version int) Option {
+	return func(o *clientOptions) {
+		o.version = version
+	}
+}
+
+// WithTimeout fails commands that get no reply, and Next when nothing at all
+// arrives, within d. Keepalive replies count as traffic.
+func WithTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = d
+	}
+}
+
+// WithKeepalive sends INFO ID after d without packets.
+func WithKeepalive(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.keepalive = d
+	}
+}
+
+// WithFetch selects dial-up mode: the server sends the buffered packets of
+// every station and then ends the stream, Next returning io.EOF.
+func WithFetch() Option {
+	return func(o *clientOptions) {
+		o.fetch = true
+	}
+}
+
+// getOptions applies options over the defaults.
+func getOptions(options []Option) *clientOptions {
+	o := &clientOptions{
+		version: 4,
+	}
+	for _, option := range options {
+		option(o)
+	}
+
+	return o
+}
@@ -0,0 +1,237 @@
+package seedlink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	RECORD_LENGTH               = 512 // Length of the miniSEED records carried by SeedLink 3
+	V3_HEADER_LENGTH            = 8   // "SL" and a 6-digit hex sequence number, or "SLINFO" and 2 flags
+	V4_HEADER_LENGTH            = 17  // Fixed part of a SeedLink 4 header, before the station ID
+	V4_FORMAT_MSEED2            = '2' // SeedLink 4 payload format: miniSEED 2
+	V4_FORMAT_JSON              = 'J' // SeedLink 4 payload format: JSON, for INFO
+	V4_SUBFORMAT_DATA           = 'D' // SeedLink 4 payload subformat: data record
+	V4_SUBFORMAT_INFO           = 'I' // SeedLink 4 payload subformat: INFO reply
+	V4_MAX_PAYLOAD_LENGTH       = 1 << 24
+	V3_MAX_SEQUENCE             = 0xffffff // SeedLink 3 sequence numbers wrap around after it
+	NO_SEQUENCE           int64 = -1       // Sequence number of INFO packets
+)
+
+// Packet is one packet received from a SeedLink server.
+type Packet struct {
+	Sequence  int64              // NO_SEQUENCE for INFO packets
+	StationID string             // NET_STA of the record
+	Info      bool               // Reply to an INFO command rather than data
+	More      bool               // More INFO packets follow, SeedLink 3 only
+	Format    byte               // SeedLink 4 payload format, 0 for SeedLink 3
+	Record    []byte             // Payload as received, usually a miniSEED record
+	Series    mseedio.DataSeries // The record parsed, for data packets
+}
+
+// readPacket reads the next packet in either protocol version. A server
+// ending a FETCH stream yields io.EOF, an ERROR line an error.
+func readPacket(r *bufio.Reader) (*Packet, error) {
+	signature, err := r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	switch string(signature) {
+	case "SL":
+		return readV3Packet(r)
+	case "SE":
+		return readV4Packet(r)
+	case "EN":
+		end := make([]byte, 3)
+		if _, err := io.ReadFull(r, end); err != nil || string(end) != "END" {
+			return nil, fmt.Errorf("unexpected %q from server", end)
+		}
+		return nil, io.EOF
+	case "ER":
+		line, _ := r.ReadString('\n')
+		return nil, fmt.Errorf("server error: %s", strings.TrimSpace(line))
+	}
+
+	return nil, fmt.Errorf("unexpected packet signature %q", signature)
+}
+
+// readV3Packet reads an 8-byte SeedLink 3 header and its 512-byte record.
+func readV3Packet(r *bufio.Reader) (*Packet, error) {
+	header := make([]byte, V3_HEADER_LENGTH)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	p := &Packet{Sequence: NO_SEQUENCE}
+	if string(header[:6]) == "SLINFO" {
+		p.Info = true
+		p.More = header[7] == '*'
+	} else {
+		sequence, err := strconv.ParseInt(string(header[2:]), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sequence number in header %q", header)
+		}
+		p.Sequence = sequence
+	}
+
+	p.Record = make([]byte, RECORD_LENGTH)
+	if _, err := io.ReadFull(r, p.Record); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// readV4Packet reads a SeedLink 4 header, station ID and payload.
+func readV4Packet(r *bufio.Reader) (*Packet, error) {
+	header := make([]byte, V4_HEADER_LENGTH)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	var (
+		format    = header[2]
+		subformat = header[3]
+		length    = binary.LittleEndian.Uint32(header[4:8])
+		sequence  = binary.LittleEndian.Uint64(header[8:16])
+		station   = make([]byte, header[16])
+	)
+	if length > V4_MAX_PAYLOAD_LENGTH {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d", length, V4_MAX_PAYLOAD_LENGTH)
+	}
+	if _, err := io.ReadFull(r, station); err != nil {
+		return nil, err
+	}
+
+	p := &Packet{
+		Sequence:  int64(sequence),
+		StationID: string(station),
+		Format:    format,
+		Info:      subformat == V4_SUBFORMAT_INFO,
+		Record:    make([]byte, length),
+	}
+	if _, err := io.ReadFull(r, p.Record); err != nil {
+		return nil, err
+	}
+	if p.Info {
+		p.Sequence = NO_SEQUENCE
+	} else if format != V4_FORMAT_MSEED2 {
+		return p, fmt.Errorf("unsupported payload format %q", format)
+	}
+
+	return p, nil
+}
+
+// parseRecord parses the miniSEED record of a data packet into p.Series and
+// fills in the station ID if the header did not carry it.
+func parseRecord(p *Packet) error {
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(p.Record), mseedio.WithIntegrity(mseedio.INTEGRITY_WARN)); err != nil {
+		return err
+	}
+
+	p.Series = m.Series[0]
+	if p.StationID == "" {
+		f := &p.Series.FixedSection
+		p.StationID = getStationID(strings.TrimSpace(f.NetworkCode), strings.TrimSpace(f.StationCode))
+	}
+	return nil
+}
+
+// getInfoText returns the text carried by an INFO packet: the ASCII samples
+// of a SeedLink 3 log record, or the SeedLink 4 payload as is.
+func getInfoText(p *Packet) string {
+	if p.Format == V4_FORMAT_JSON {
+		return string(p.Record)
+	}
+
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(p.Record)); err != nil {
+		return string(p.Record)
+	}
+
+	var text strings.Builder
+	for _, s := range m.Series {
+		for _, v := range s.DataSection.Decoded {
+			if str, ok := v.(string); ok {
+				text.WriteString(str)
+			}
+		}
+	}
+	return text.String()
+}
+
+// writeV3Packet frames a record as a SeedLink 3 data packet, the sequence
+// number wrapping around after V3_MAX_SEQUENCE.
+func writeV3Packet(w io.Writer, sequence int64, record []byte) error {
+	packet := make([]byte, 0, V3_HEADER_LENGTH+len(record))
+	packet = fmt.Appendf(packet, "SL%06X", sequence&V3_MAX_SEQUENCE)
+	_, err := w.Write(append(packet, record...))
+	return err
+}
+
+// writeV3Info frames a log record as a SeedLink 3 INFO packet, more telling
+// whether others follow.
+func writeV3Info(w io.Writer, more bool, record []byte) error {
+	header := "SLINFO  "
+	if more {
+		header = "SLINFO *"
+	}
+	_, err := w.Write(append([]byte(header), record...))
+	return err
+}
+
+// writeV4Packet frames payload as a SeedLink 4 packet.
+func writeV4Packet(w io.Writer, format, subformat byte, sequence int64, station string, payload []byte) error {
+	header := make([]byte, V4_HEADER_LENGTH, V4_HEADER_LENGTH+len(station)+len(payload))
+	copy(header, "SE")
+	header[2], header[3] = format, subformat
+	binary.LittleEndian.PutUint32(header[4:], uint32(len(payload)))
+	binary.LittleEndian.PutUint64(header[8:], uint64(sequence))
+	header[16] = byte(len(station))
+	_, err := w.Write(append(append(header, station...), payload...))
+	return err
+}
+
+// getInfoRecords splits text into the 512-byte ASCII log records that carry
+// INFO documents in SeedLink 3.
+func getInfoRecords(text string) ([][]byte, error) {
+	samples := make([]int32, len(text))
+	for i := 0; i < len(text); i++ {
+		samples[i] = int32(text[i])
+	}
+
+	var m mseedio.MiniSeedData
+	_ = m.Init(mseedio.ASCII, mseedio.MSBFIRST)
+	err := m.Append(samples, &mseedio.AppendOptions{
+		StationCode: "INFO", ChannelCode: "LOG", NetworkCode: "SL",
+		StartTime: time.Now().UTC(), RecordLength: RECORD_LENGTH,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		return nil, err
+	}
+
+	var records [][]byte
+	for i := 0; i < len(encoded); i += RECORD_LENGTH {
+		records = append(records, encoded[i:i+RECORD_LENGTH])
+	}
+	return records, nil
+}
+
+// getStationID joins network and station codes as SeedLink 4 does.
+func getStationID(network, station string) string {
+	return network + "_" + station
+}
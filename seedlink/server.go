@@ -0,0 +1,307 @@
+package seedlink
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+const (
+	// DEFAULT_CAPACITY is the number of records kept per station when
+	// NewServer is given no capacity.
+	DEFAULT_CAPACITY = 1000
+	// DEFAULT_WRITE_TIMEOUT is the write timeout set by NewServer.
+	DEFAULT_WRITE_TIMEOUT = time.Minute
+	// MAX_COMMAND_LENGTH is the longest command line read from a client,
+	// which is dropped past it.
+	MAX_COMMAND_LENGTH = 1024
+	// MAX_STATIONS is the number of STATION commands a client may send.
+	MAX_STATIONS = 1000
+	// MAX_SELECTORS is the number of SELECT patterns a client may give a
+	// station.
+	MAX_SELECTORS = 100
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close.
+var ErrServerClosed = errors.New("seedlink: server closed")
+
+// bufferedRecord is a record held by a station buffer.
+type bufferedRecord struct {
+	sequence  int64
+	location  string
+	channel   string
+	startTime time.Time
+	endTime   time.Time
+	record    []byte
+}
+
+// stationBuffer is the ring buffer of the latest records of one station.
+// Sequence numbers count up from 0 and are never reused.
+type stationBuffer struct {
+	network string
+	station string
+	records []bufferedRecord
+	head    int   // Index of the oldest record
+	count   int   // Records held
+	next    int64 // Sequence number of the next record
+}
+
+// push adds a record, dropping the oldest one if the buffer is full.
+func (b *stationBuffer) push(r bufferedRecord) {
+	r.sequence = b.next
+	b.next++
+
+	if b.count < len(b.records) {
+		b.records[(b.head+b.count)%len(b.records)] = r
+		b.count++
+		return
+	}
+	b.records[b.head] = r
+	b.head = (b.head + 1) % len(b.records)
+}
+
+// first returns the sequence number of the oldest record held.
+func (b *stationBuffer) first() int64 {
+	return b.next - int64(b.count)
+}
+
+// get returns the record with the given sequence number, nil if it is not
+// held (anymore).
+func (b *stationBuffer) get(sequence int64) *bufferedRecord {
+	if sequence < b.first() || sequence >= b.next {
+		return nil
+	}
+
+	return &b.records[(b.head+int(sequence-b.first()))%len(b.records)]
+}
+
+// Server serves the miniSEED records written to it to SeedLink 3 and 4
+// clients, keeping the latest records of every station in a ring buffer.
+type Server struct {
+	Software     string // First line of the HELLO reply, before the protocol versions
+	Organization string // Second line of the HELLO reply
+
+	// WriteTimeout is how long a client may take to accept a write before it
+	// is dropped, 0 for no limit. Set it before serving.
+	WriteTimeout time.Duration
+
+	capacity  int
+	mu        sync.Mutex
+	buffers   map[string]*stationBuffer // Keyed by NET_STA
+	changed   chan struct{}             // Closed and replaced on every record
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	done      chan struct{}
+	started   time.Time
+}
+
+// NewServer returns a server keeping up to capacity records per station,
+// DEFAULT_CAPACITY if capacity < 1.
+func NewServer(organization string, capacity int) *Server {
+	if capacity < 1 {
+		capacity = DEFAULT_CAPACITY
+	}
+
+	return &Server{
+		Software:     "SeedLink v4.0 (mseedio)",
+		Organization: organization,
+		WriteTimeout: DEFAULT_WRITE_TIMEOUT,
+		capacity:     capacity,
+		buffers:      map[string]*stationBuffer{},
+		changed:      make(chan struct{}),
+		listeners:    map[net.Listener]struct{}{},
+		conns:        map[net.Conn]struct{}{},
+		done:         make(chan struct{}),
+		started:      time.Now().UTC(),
+	}
+}
+
+// Write buffers p, one or more 512-byte miniSEED records such as those made
+// by MiniSeedData.Encode with AppendOptions.RecordLength set to
+// RECORD_LENGTH, and sends them to the clients streaming their station. It
+// returns the number of bytes of the records buffered before an error.
+func (s *Server) Write(p []byte) (int, error) {
+	for n := 0; n < len(p); n += RECORD_LENGTH {
+		if len(p)-n < RECORD_LENGTH {
+			return n, fmt.Errorf("short record of %d bytes, want %d", len(p)-n, RECORD_LENGTH)
+		}
+		if err := s.feed(p[n : n+RECORD_LENGTH]); err != nil {
+			return n, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// feed parses a record header and adds a copy of the record to the buffer of
+// its station.
+func (s *Server) feed(record []byte) error {
+	var m mseedio.MiniSeedData
+	if err := m.ReadFromReader(bytes.NewReader(record), mseedio.WithLazyDecode()); err != nil {
+		return err
+	}
+	if len(m.Series) != 1 || m.Series[0].BlocketteSection.RecordLength != 9 {
+		return fmt.Errorf("not a single %d-byte record", RECORD_LENGTH)
+	}
+
+	series := &m.Series[0]
+	f := &series.FixedSection
+	network, station := strings.TrimSpace(f.NetworkCode), strings.TrimSpace(f.StationCode)
+	r := bufferedRecord{
+		location:  strings.TrimSpace(f.LocationCode),
+		channel:   strings.TrimSpace(f.ChannelCode),
+		startTime: f.StartTime,
+		endTime:   series.EndTime(),
+		record:    append([]byte(nil), record...),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := getStationID(network, station)
+	b, ok := s.buffers[id]
+	if !ok {
+		b = &stationBuffer{network: network, station: station, records: make([]bufferedRecord, s.capacity)}
+		s.buffers[id] = b
+	}
+	b.push(r)
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return nil
+}
+
+// ListenAndServe listens on the TCP address (host:port) and calls Serve.
+func (s *Server) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l, serving each client in its own goroutine,
+// until Close is called or Accept fails. It closes l when returning.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	default:
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return ErrServerClosed
+			default:
+				return err
+			}
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops every Serve loop and closes the client connections. Buffered
+// records are kept.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	close(s.done)
+
+	for l := range s.listeners {
+		l.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return nil
+}
+
+// serveConn runs the handshake of a client, then streams records to it.
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	c := &session{server: s, conn: conn, version: 3}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 128), MAX_COMMAND_LENGTH)
+	for !c.started {
+		line, err := readCommand(scanner)
+		if err != nil {
+			return
+		}
+		if err := c.handle(line); err != nil {
+			return
+		}
+	}
+
+	commands := make(chan string)
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		defer close(commands)
+		for {
+			line, err := readCommand(scanner)
+			if err != nil {
+				return
+			}
+			select {
+			case commands <- line:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	c.stream(commands)
+}
+
+// readCommand reads a command line without its line ending. Lines longer
+// than the buffer of the scanner fail with bufio.ErrTooLong.
+func readCommand(scanner *bufio.Scanner) (string, error) {
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+
+	return strings.TrimRight(scanner.Text(), "\r"), nil
+}
@@ -0,0 +1,407 @@
+package seedlink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// newTestServer serves on a localhost port until the test ends.
+func newTestServer(t *testing.T, capacity int) (*Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer("Test", capacity)
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return s, l.Addr().String()
+}
+
+// getStreamRecord encodes record i of a 1-second stream starting at
+// 2024-01-01 00:00:00.
+func getStreamRecord(t *testing.T, station, channel string, i int) []byte {
+	samples := make([]int32, 100)
+	for j := range samples {
+		samples[j] = int32(i*100 + j)
+	}
+
+	var m mseedio.MiniSeedData
+	_ = m.Init(mseedio.STEIM2, mseedio.MSBFIRST)
+	err := m.Append(samples, &mseedio.AppendOptions{
+		SampleRate: 100, StartTime: time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+		RecordLength: RECORD_LENGTH,
+		NetworkCode:  "IU", StationCode: station, LocationCode: "00", ChannelCode: channel,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+// writeStream writes n records of a stream to the server.
+func writeStream(t *testing.T, s *Server, station, channel string, n int) {
+	for i := 0; i < n; i++ {
+		if _, err := s.Write(getStreamRecord(t, station, channel, i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// dialTestServer connects a client and adds one station.
+func dialTestServer(t *testing.T, address string, sel Selection, options ...Option) *Client {
+	c, err := Dial(context.Background(), address, append([]Option{WithTimeout(5 * time.Second)}, options...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.AddStation(sel); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// readChannels reads n packets and returns "sequence:channel" for each.
+func readChannels(t *testing.T, c *Client, n int) []string {
+	var got []string
+	for i := 0; i < n; i++ {
+		p, err := c.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d:%s", p.Sequence, p.Series.FixedSection.ChannelCode))
+	}
+	return got
+}
+
+func TestServerLive(t *testing.T) {
+	for _, version := range []int{3, 4} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			s, address := newTestServer(t, 0)
+			writeStream(t, s, "ANMO", "BHZ", 2)
+
+			var clients []*Client
+			for i := 0; i < 3; i++ {
+				c := dialTestServer(t, address, Selection{Network: "IU", Station: "ANMO"}, WithVersion(version), WithKeepalive(10*time.Millisecond))
+				if c.Version != version {
+					t.Fatalf("want version %d, got %d", version, c.Version)
+				}
+				clients = append(clients, c)
+			}
+
+			// Records buffered before END are skipped, so keep feeding until
+			// every client got three of the new ones.
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				for i := 2; ; i++ {
+					select {
+					case <-done:
+						return
+					case <-time.After(20 * time.Millisecond):
+						s.Write(getStreamRecord(t, "ANMO", "BHZ", i))
+					}
+				}
+			}()
+
+			var wg sync.WaitGroup
+			for _, c := range clients {
+				wg.Add(1)
+				go func(c *Client) {
+					defer wg.Done()
+					var last int64 = 1
+					for i := 0; i < 3; i++ {
+						p, err := c.Next()
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if p.Sequence <= last || (i > 0 && p.Sequence != last+1) || p.StationID != "IU_ANMO" {
+							t.Errorf("packet %d: sequence %d of %q after %d", i, p.Sequence, p.StationID, last)
+							return
+						}
+						last = p.Sequence
+					}
+				}(c)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestServerResume(t *testing.T) {
+	for _, version := range []int{3, 4} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			s, address := newTestServer(t, 4)
+			writeStream(t, s, "ANMO", "BHZ", 6)
+
+			c := dialTestServer(t, address, Selection{Network: "IU", Station: "ANMO", Resume: true, Sequence: 3}, WithVersion(version))
+			got := strings.Join(readChannels(t, c, 2), " ")
+			if want := "4:BHZ 5:BHZ"; got != want {
+				t.Fatalf("want %q, got %q", want, got)
+			}
+
+			// Sequence numbers dropped from the buffer resume at the oldest held
+			c = dialTestServer(t, address, Selection{Network: "IU", Station: "ANMO", Resume: true, Sequence: 0}, WithVersion(version))
+			got = strings.Join(readChannels(t, c, 4), " ")
+			if want := "2:BHZ 3:BHZ 4:BHZ 5:BHZ"; got != want {
+				t.Fatalf("want %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestServerFetch(t *testing.T) {
+	for _, version := range []int{3, 4} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			s, address := newTestServer(t, 0)
+			writeStream(t, s, "ANMO", "BHZ", 3)
+			writeStream(t, s, "COLA", "BHZ", 2)
+
+			c := dialTestServer(t, address, Selection{Network: "IU", Station: "ANMO"}, WithVersion(version), WithFetch())
+			got := strings.Join(readChannels(t, c, 3), " ")
+			if want := "0:BHZ 1:BHZ 2:BHZ"; got != want {
+				t.Fatalf("want %q, got %q", want, got)
+			}
+			if _, err := c.Next(); err != io.EOF {
+				t.Fatalf("want io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServerSelect(t *testing.T) {
+	s, address := newTestServer(t, 0)
+	for i := 0; i < 2; i++ {
+		for _, channel := range []string{"BHZ", "BHN", "LHZ"} {
+			if _, err := s.Write(getStreamRecord(t, "ANMO", channel, i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		version   int
+		selectors []string
+		want      string
+	}{
+		{3, []string{"BHN"}, "1:BHN 4:BHN"},
+		{3, []string{"00BH?.D"}, "0:BHZ 1:BHN 3:BHZ 4:BHN"},
+		{3, []string{"!BHN"}, "0:BHZ 2:LHZ 3:BHZ 5:LHZ"},
+		{3, []string{"??Z", "!LHZ"}, "0:BHZ 3:BHZ"},
+		{4, []string{"00_L_H_Z"}, "2:LHZ 5:LHZ"},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.selectors, ","), func(t *testing.T) {
+			sel := Selection{Network: "IU", Station: "ANMO", Selectors: test.selectors}
+			c := dialTestServer(t, address, sel, WithVersion(test.version), WithFetch())
+			got := strings.Join(readChannels(t, c, len(strings.Fields(test.want))), " ")
+			if got != test.want {
+				t.Fatalf("want %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestServerTimeWindow(t *testing.T) {
+	for _, version := range []int{3, 4} {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			s, address := newTestServer(t, 0)
+			writeStream(t, s, "ANMO", "BHZ", 6)
+
+			sel := Selection{
+				Network: "IU", Station: "ANMO",
+				StartTime: time.Date(2024, 1, 1, 0, 0, 2, 0, time.UTC),
+				EndTime:   time.Date(2024, 1, 1, 0, 0, 3, 0, time.UTC),
+			}
+			c := dialTestServer(t, address, sel, WithVersion(version), WithFetch())
+			got := strings.Join(readChannels(t, c, 2), " ")
+			if want := "2:BHZ 3:BHZ"; got != want {
+				t.Fatalf("want %q, got %q", want, got)
+			}
+			if _, err := c.Next(); err != io.EOF {
+				t.Fatalf("want io.EOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServerInfo(t *testing.T) {
+	s, address := newTestServer(t, 0)
+	writeStream(t, s, "ANMO", "BHZ", 3)
+	writeStream(t, s, "COLA", "LHZ", 1)
+
+	tests := []struct {
+		version int
+		level   string
+		want    []string
+	}{
+		{3, "ID", []string{`<seedlink software="SeedLink v4.0 (mseedio)" organization="Test"`}},
+		{3, "STATIONS", []string{`name="ANMO" network="IU" description="" begin_seq="000000" end_seq="000003"`, `name="COLA"`}},
+		{3, "STREAMS", []string{`location="00" seedname="BHZ" type="D" begin_time="2024-01-01T00:00:00Z" end_time="2024-01-01T00:00:02.99Z"`}},
+		{4, "STREAMS", []string{`"id":"IU_ANMO"`, `"begin_seq":0,"end_seq":3`, `"id":"00_B_H_Z","format":"2","subformat":"D"`}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("v%d %s", test.version, test.level), func(t *testing.T) {
+			c, err := Dial(context.Background(), address, WithVersion(test.version), WithTimeout(5*time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			info, err := c.Info(test.level)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(info, want) {
+					t.Errorf("missing %q in %q", want, info)
+				}
+			}
+		})
+	}
+
+	c, err := Dial(context.Background(), address, WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if _, err := c.Info("CONNECTIONS"); err == nil {
+		t.Fatal("want error for unsupported INFO level")
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	s, address := newTestServer(t, 0)
+
+	record := getStreamRecord(t, "ANMO", "BHZ", 0)
+	if n, err := s.Write(append(record, record[:100]...)); err == nil || n != RECORD_LENGTH {
+		t.Fatalf("want short record error after %d bytes, got %d: %v", RECORD_LENGTH, n, err)
+	}
+	if _, err := s.Write(make([]byte, RECORD_LENGTH)); err == nil {
+		t.Fatal("want error for a record of zeros")
+	}
+
+	c, err := Dial(context.Background(), address, WithVersion(3), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.command("SELECT BHZ"); err == nil {
+		t.Fatal("want error for SELECT before STATION")
+	}
+	if err := c.command("DATA ZZZ"); err == nil {
+		t.Fatal("want error for DATA before STATION")
+	}
+	if err := c.command("STATION ANMO IU"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.command("DATA ZZZ"); err == nil {
+		t.Fatal("want error for an invalid sequence number")
+	}
+	if err := c.command("TIME 2024,13"); err == nil {
+		t.Fatal("want error for an invalid time")
+	}
+
+	s.Close()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(l); !errors.Is(err, ErrServerClosed) {
+		t.Fatalf("want ErrServerClosed, got %v", err)
+	}
+}
+
+func TestServerWriteTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const records = 20000
+	s := NewServer("Test", records)
+	s.WriteTimeout = 100 * time.Millisecond
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+
+	record := getStreamRecord(t, "ANMO", "BHZ", 0)
+	for i := 0; i < records; i++ {
+		if _, err := s.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A client fetching 10 MB of records without reading them
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "STATION ANMO IU\r\nFETCH\r\nEND\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dropped once the socket buffers are full, well before the last record
+	time.Sleep(500 * time.Millisecond)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	n, err := io.Copy(io.Discard, conn)
+	if n >= records*RECORD_LENGTH {
+		t.Fatalf("want the client dropped, got all %d bytes: %v", n, err)
+	}
+}
+
+func TestServerLimits(t *testing.T) {
+	_, address := newTestServer(t, 0)
+
+	// A command line without end is cut off at MAX_COMMAND_LENGTH
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write([]byte(strings.Repeat("A", 4*MAX_COMMAND_LENGTH))); err != nil {
+		t.Fatal(err)
+	}
+	var timeout net.Error
+	if n, err := io.Copy(io.Discard, conn); n != 0 || errors.As(err, &timeout) && timeout.Timeout() {
+		t.Errorf("want the client dropped, got %d bytes: %v", n, err)
+	}
+
+	c, err := Dial(context.Background(), address, WithVersion(3), WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < MAX_STATIONS; i++ {
+		if err := c.command("STATION ANMO IU"); err != nil {
+			t.Fatalf("station %d: %v", i, err)
+		}
+	}
+	if err := c.command("STATION ANMO IU"); err == nil {
+		t.Error("want error past MAX_STATIONS stations")
+	}
+	if err := c.command("SELECT " + strings.Repeat("BHZ ", MAX_SELECTORS)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.command("SELECT BHN"); err == nil {
+		t.Error("want error past MAX_SELECTORS selectors")
+	}
+}
@@ -0,0 +1,650 @@
+package seedlink
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// subscription is what a client requested from the stations matching one
+// STATION command.
+type subscription struct {
+	network   string   // Pattern, may hold wildcards
+	station   string   // Pattern, may hold wildcards
+	selectors []string // SELECT patterns
+	sequence  int64    // First sequence number wanted, see all
+	all       bool     // Start from the oldest record held rather than new ones, without a sequence number
+	startTime time.Time
+	endTime   time.Time
+	fetch     bool             // Stop once the buffered records are sent
+	cursors   map[string]int64 // Next sequence number to send, keyed by NET_STA
+}
+
+// matches tells whether the subscription covers a station.
+func (sub *subscription) matches(b *stationBuffer) bool {
+	network, _ := path.Match(sub.network, b.network)
+	station, _ := path.Match(sub.station, b.station)
+	return network && station
+}
+
+// wants tells whether a record passes the selectors and time window.
+func (sub *subscription) wants(r *bufferedRecord) bool {
+	if !sub.startTime.IsZero() && r.endTime.Before(sub.startTime) {
+		return false
+	}
+	if !sub.endTime.IsZero() && r.startTime.After(sub.endTime) {
+		return false
+	}
+
+	return matchSelectors(sub.selectors, r.location, r.channel)
+}
+
+// session is the state of one client connection.
+type session struct {
+	server  *Server
+	conn    net.Conn
+	version int
+	started bool
+	subs    []*subscription
+}
+
+// handle runs a handshake command. It returns an error when the client is
+// gone or said BYE.
+func (c *session) handle(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, args := strings.ToUpper(fields[0]), fields[1:]
+
+	var current *subscription
+	if len(c.subs) > 0 {
+		current = c.subs[len(c.subs)-1]
+	}
+
+	switch cmd {
+	case "HELLO":
+		return c.reply(c.server.Software+" :: SLPROTO:4.0 SLPROTO:3.1", c.server.Organization)
+	case "SLPROTO":
+		switch {
+		case len(args) != 1:
+			return c.reject("ARGUMENTS", "expected a protocol version")
+		case args[0] == "4.0" || args[0] == "4":
+			c.version = 4
+		case strings.HasPrefix(args[0], "3."):
+			c.version = 3
+		default:
+			return c.reject("UNSUPPORTED", "protocol version "+args[0])
+		}
+		return c.reply("OK")
+	case "STATION":
+		if len(c.subs) >= MAX_STATIONS {
+			return c.reject("LIMIT", fmt.Sprintf("more than %d stations", MAX_STATIONS))
+		}
+		sub, err := c.getSubscription(args)
+		if err != nil {
+			return c.reject("ARGUMENTS", err.Error())
+		}
+		c.subs = append(c.subs, sub)
+		return c.reply("OK")
+	case "SELECT":
+		if current == nil {
+			return c.reject("UNEXPECTED", "SELECT before STATION")
+		}
+		if len(current.selectors)+len(args) > MAX_SELECTORS {
+			return c.reject("LIMIT", fmt.Sprintf("more than %d selectors", MAX_SELECTORS))
+		}
+		current.selectors = append(current.selectors, args...)
+		return c.reply("OK")
+	case "DATA", "FETCH", "TIME":
+		if current == nil {
+			return c.reject("UNEXPECTED", cmd+" before STATION")
+		}
+		if err := c.setStart(current, cmd, args); err != nil {
+			return c.reject("ARGUMENTS", err.Error())
+		}
+		return c.reply("OK")
+	case "END", "ENDFETCH":
+		if len(c.subs) == 0 {
+			return c.reject("UNEXPECTED", cmd+" before STATION")
+		}
+		for _, sub := range c.subs {
+			sub.fetch = sub.fetch || cmd == "ENDFETCH"
+		}
+		c.start()
+		return nil
+	case "INFO":
+		if len(args) != 1 {
+			return c.reject("ARGUMENTS", "expected an INFO level")
+		}
+		return c.info(strings.ToUpper(args[0]))
+	case "BYE":
+		return io.EOF
+	}
+
+	return c.reject("UNSUPPORTED", "command "+cmd)
+}
+
+// getSubscription parses the arguments of STATION: "sta [net]" in SeedLink 3,
+// "NET_STA" in SeedLink 4.
+func (c *session) getSubscription(args []string) (*subscription, error) {
+	sub := &subscription{network: "*", sequence: NO_SEQUENCE, cursors: map[string]int64{}}
+	switch {
+	case c.version >= 4 && len(args) == 1:
+		network, station, ok := strings.Cut(args[0], "_")
+		if !ok {
+			return nil, fmt.Errorf("station ID %q is not NET_STA", args[0])
+		}
+		sub.network, sub.station = network, station
+	case c.version < 4 && len(args) == 2:
+		sub.network = args[1]
+		fallthrough
+	case c.version < 4 && len(args) == 1:
+		sub.station = args[0]
+	default:
+		return nil, fmt.Errorf("unexpected STATION arguments %q", args)
+	}
+
+	for _, pattern := range []string{sub.network, sub.station} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return sub, nil
+}
+
+// setStart parses the arguments of DATA, FETCH or TIME. SeedLink 3 takes a
+// hex sequence number and comma-separated times, SeedLink 4 a decimal
+// sequence number or ALL and RFC 3339 times.
+func (c *session) setStart(sub *subscription, cmd string, args []string) error {
+	sub.fetch = cmd == "FETCH"
+	if cmd == "TIME" {
+		if len(args) == 0 {
+			return fmt.Errorf("TIME needs a start time")
+		}
+		args = append([]string{"ALL"}, args...)
+	}
+	if len(args) > 3 {
+		return fmt.Errorf("too many arguments")
+	}
+
+	if len(args) > 0 && !strings.EqualFold(args[0], "ALL") {
+		base := 16
+		if c.version >= 4 {
+			base = 10
+		}
+		sequence, err := strconv.ParseInt(args[0], base, 64)
+		if err != nil || sequence < 0 {
+			return fmt.Errorf("invalid sequence number %q", args[0])
+		}
+		sub.sequence = sequence
+	} else {
+		sub.all = len(args) > 0
+	}
+
+	if len(args) < 2 {
+		return nil
+	}
+	times := []*time.Time{&sub.startTime, &sub.endTime}
+	for i, arg := range args[1:] {
+		t, err := c.parseTime(arg)
+		if err != nil {
+			return err
+		}
+		*times[i] = t
+	}
+	return nil
+}
+
+// parseTime parses a time argument of DATA, FETCH or TIME.
+func (c *session) parseTime(arg string) (time.Time, error) {
+	if c.version >= 4 {
+		return time.Parse(time.RFC3339Nano, arg)
+	}
+
+	var fields [6]int
+	parts := strings.Split(arg, ",")
+	if len(parts) < 3 || len(parts) > 6 {
+		return time.Time{}, fmt.Errorf("invalid time %q", arg)
+	}
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", arg)
+		}
+		fields[i] = v
+	}
+	return time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, time.UTC), nil
+}
+
+// start ends the handshake, pointing the cursors of subscriptions wanting
+// new records only past the records already buffered. FETCH without a
+// sequence number sends the buffered records.
+func (c *session) start() {
+	c.started = true
+
+	s := c.server
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range c.subs {
+		if sub.sequence != NO_SEQUENCE || sub.all || sub.fetch {
+			continue
+		}
+		for id, b := range s.buffers {
+			if sub.matches(b) {
+				sub.cursors[id] = b.next
+			}
+		}
+	}
+}
+
+// outgoing is a record due to a client.
+type outgoing struct {
+	sequence int64
+	station  string
+	record   []byte
+}
+
+// stream sends records as they come until the client leaves, the server
+// closes, or every station is done, see collect. Meanwhile it answers INFO commands.
+func (c *session) stream(commands <-chan string) {
+	s := c.server
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		pending, over := c.collect()
+		s.mu.Unlock()
+
+		for _, p := range pending {
+			if err := c.send(p); err != nil {
+				return
+			}
+		}
+		if over {
+			_, _ = io.WriteString(c, "END")
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-s.done:
+			return
+		case line, ok := <-commands:
+			if !ok {
+				return
+			}
+			fields := strings.Fields(strings.ToUpper(line))
+			switch {
+			case len(fields) == 2 && fields[0] == "INFO":
+				if c.info(fields[1]) != nil {
+					return
+				}
+			case len(fields) > 0 && fields[0] == "BYE":
+				return
+			}
+		}
+	}
+}
+
+// collect gathers the records due to the client and advances the cursors,
+// telling whether every subscription is a FETCH or a time window that is
+// over. It must be called
+// with the server lock held.
+func (c *session) collect() ([]outgoing, bool) {
+	s := c.server
+	ids := make([]string, 0, len(s.buffers))
+	for id := range s.buffers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var pending []outgoing
+	over := true
+	for _, sub := range c.subs {
+		// A time window is over once records past its end have come in
+		windowOver, matched := !sub.endTime.IsZero(), false
+		for _, id := range ids {
+			b := s.buffers[id]
+			if !sub.matches(b) {
+				continue
+			}
+			matched = true
+			if latest := b.get(b.next - 1); latest == nil || !latest.endTime.After(sub.endTime) {
+				windowOver = false
+			}
+
+			cursor, ok := sub.cursors[id]
+			if !ok {
+				cursor = c.getCursor(sub, b)
+			}
+			if cursor < b.first() {
+				cursor = b.first()
+			}
+			for ; cursor < b.next; cursor++ {
+				if r := b.get(cursor); sub.wants(r) {
+					pending = append(pending, outgoing{r.sequence, id, r.record})
+				}
+			}
+			sub.cursors[id] = cursor
+		}
+		over = over && (sub.fetch || windowOver && matched)
+	}
+
+	return pending, over
+}
+
+// getCursor returns where a subscription starts in a station buffer it has
+// not sent from yet. SeedLink 3 sequence numbers are matched on their lower
+// 24 bits against the latest ones.
+func (c *session) getCursor(sub *subscription, b *stationBuffer) int64 {
+	if sub.sequence == NO_SEQUENCE {
+		return b.first()
+	}
+	if c.version >= 4 {
+		return sub.sequence
+	}
+
+	sequence := b.next&^V3_MAX_SEQUENCE | sub.sequence&V3_MAX_SEQUENCE
+	if sequence > b.next {
+		sequence -= V3_MAX_SEQUENCE + 1
+	}
+	return sequence
+}
+
+// send frames a record for the protocol version of the client.
+func (c *session) send(p outgoing) error {
+	if c.version >= 4 {
+		return writeV4Packet(c, V4_FORMAT_MSEED2, V4_SUBFORMAT_DATA, p.sequence, p.station, p.record)
+	}
+
+	return writeV3Packet(c, p.sequence, p.record)
+}
+
+// Write writes to the client within the write timeout of the server. A
+// client that stops reading fails the write, ending its session.
+func (c *session) Write(p []byte) (int, error) {
+	if timeout := c.server.WriteTimeout; timeout > 0 {
+		if err := c.conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	return c.conn.Write(p)
+}
+
+// reply sends reply lines.
+func (c *session) reply(lines ...string) error {
+	for _, line := range lines {
+		if _, err := io.WriteString(c, line+"\r\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reject replies ERROR, with a code and description in SeedLink 4.
+func (c *session) reject(code, description string) error {
+	if c.version >= 4 {
+		return c.reply("ERROR " + code + " " + description)
+	}
+
+	return c.reply("ERROR")
+}
+
+// info sends the INFO document of a level, XML in log records in SeedLink 3
+// and a JSON packet in SeedLink 4.
+func (c *session) info(level string) error {
+	switch level {
+	case "ID", "CAPABILITIES", "STATIONS", "STREAMS":
+	default:
+		return c.reject("UNSUPPORTED", "INFO level "+level)
+	}
+
+	doc := c.server.getInfo(level)
+	if c.version >= 4 {
+		payload, err := json.Marshal(doc.v4())
+		if err != nil {
+			return err
+		}
+		return writeV4Packet(c, V4_FORMAT_JSON, V4_SUBFORMAT_INFO, 0, "", payload)
+	}
+
+	text, err := xml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	records, err := getInfoRecords(xml.Header + string(text))
+	if err != nil {
+		return err
+	}
+	for i, record := range records {
+		if err := writeV3Info(c, i < len(records)-1, record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchSelectors tells whether a stream passes SELECT patterns. A stream
+// passes if it matches no negated ("!") pattern and either matches one of
+// the others or there are none.
+func matchSelectors(selectors []string, location, channel string) bool {
+	included, positive := false, false
+	for _, selector := range selectors {
+		negated := strings.HasPrefix(selector, "!")
+		locPattern, chaPattern, ok := parseSelector(strings.TrimPrefix(selector, "!"))
+		if !ok {
+			continue
+		}
+
+		locMatch, _ := path.Match(locPattern, location)
+		chaMatch, _ := path.Match(chaPattern, channel)
+		matched := locMatch && chaMatch
+		if negated {
+			if matched {
+				return false
+			}
+			continue
+		}
+		positive = true
+		included = included || matched
+	}
+
+	return included || !positive
+}
+
+// parseSelector splits a SELECT pattern into location and channel patterns:
+// "LLCCC.T" (location optional) in SeedLink 3, "LL_B_S_SS" in SeedLink 4.
+// Only selectors of data records (type D) match. A "--" location matches an
+// empty one.
+func parseSelector(selector string) (string, string, bool) {
+	if i := strings.LastIndexByte(selector, '.'); i >= 0 {
+		if matched, _ := path.Match(selector[i+1:], "D"); !matched {
+			return "", "", false
+		}
+		selector = selector[:i]
+	}
+
+	location, channel := "*", selector
+	if parts := strings.Split(selector, "_"); len(parts) > 1 {
+		location, channel = parts[0], strings.Join(parts[1:], "")
+	} else if len(selector) > 3 {
+		location, channel = selector[:len(selector)-3], selector[len(selector)-3:]
+	}
+	if location == "--" {
+		location = ""
+	}
+
+	return location, channel, true
+}
+
+// infoDocument is an INFO reply, marshalled as the XML of SeedLink 3.
+type infoDocument struct {
+	XMLName      xml.Name      `xml:"seedlink"`
+	Software     string        `xml:"software,attr"`
+	Organization string        `xml:"organization,attr"`
+	Started      string        `xml:"started,attr"`
+	Capabilities []string      `xml:"capability,omitempty"`
+	Stations     []infoStation `xml:"station,omitempty"`
+}
+
+// infoStation is a station of an INFO STATIONS or STREAMS reply.
+type infoStation struct {
+	Name          string       `xml:"name,attr"`
+	Network       string       `xml:"network,attr"`
+	Description   string       `xml:"description,attr"`
+	BeginSequence int64        `xml:"-"`
+	EndSequence   int64        `xml:"-"`
+	BeginSeq      string       `xml:"begin_seq,attr"` // BeginSequence as SeedLink 3 hex
+	EndSeq        string       `xml:"end_seq,attr"`   // EndSequence as SeedLink 3 hex
+	Streams       []infoStream `xml:"stream,omitempty"`
+}
+
+// infoStream is a stream of an INFO STREAMS reply.
+type infoStream struct {
+	Location  string    `xml:"location,attr"`
+	Channel   string    `xml:"seedname,attr"`
+	Type      string    `xml:"type,attr"`
+	StartTime time.Time `xml:"-"`
+	EndTime   time.Time `xml:"-"`
+	Begin     string    `xml:"begin_time,attr"`
+	End       string    `xml:"end_time,attr"`
+}
+
+// getInfo describes the server and, for STATIONS and STREAMS, its buffers.
+func (s *Server) getInfo(level string) *infoDocument {
+	doc := &infoDocument{
+		Software:     s.Software,
+		Organization: s.Organization,
+		Started:      s.started.Format(time.RFC3339),
+	}
+	if level == "CAPABILITIES" {
+		doc.Capabilities = []string{"SLPROTO:3.1", "SLPROTO:4.0", "NSWILDCARD"}
+	}
+	if level != "STATIONS" && level != "STREAMS" {
+		return doc
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.buffers))
+	for id := range s.buffers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		b := s.buffers[id]
+		station := infoStation{
+			Name:          b.station,
+			Network:       b.network,
+			BeginSequence: b.first(),
+			EndSequence:   b.next,
+			BeginSeq:      fmt.Sprintf("%06X", b.first()&V3_MAX_SEQUENCE),
+			EndSeq:        fmt.Sprintf("%06X", b.next&V3_MAX_SEQUENCE),
+		}
+		if level == "STREAMS" {
+			station.Streams = b.getStreams()
+		}
+		doc.Stations = append(doc.Stations, station)
+	}
+	return doc
+}
+
+// getStreams lists the streams of the records held, with their time span.
+func (b *stationBuffer) getStreams() []infoStream {
+	var streams []infoStream
+	index := map[string]int{}
+	for sequence := b.first(); sequence < b.next; sequence++ {
+		r := b.get(sequence)
+		key := r.location + "." + r.channel
+		i, ok := index[key]
+		if !ok {
+			i = len(streams)
+			index[key] = i
+			streams = append(streams, infoStream{
+				Location: r.location, Channel: r.channel, Type: "D",
+				StartTime: r.startTime, EndTime: r.endTime,
+			})
+		}
+
+		stream := &streams[i]
+		if r.startTime.Before(stream.StartTime) {
+			stream.StartTime = r.startTime
+		}
+		if r.endTime.After(stream.EndTime) {
+			stream.EndTime = r.endTime
+		}
+	}
+
+	sort.Slice(streams, func(i, j int) bool {
+		return streams[i].Location+streams[i].Channel < streams[j].Location+streams[j].Channel
+	})
+	for i := range streams {
+		streams[i].Begin = streams[i].StartTime.Format(time.RFC3339Nano)
+		streams[i].End = streams[i].EndTime.Format(time.RFC3339Nano)
+	}
+	return streams
+}
+
+// infoV4Document is an INFO reply in the JSON of SeedLink 4.
+type infoV4Document struct {
+	Software     string          `json:"software"`
+	Organization string          `json:"organization"`
+	Capabilities []string        `json:"capability,omitempty"`
+	Stations     []infoV4Station `json:"station,omitempty"`
+}
+
+type infoV4Station struct {
+	ID            string         `json:"id"`
+	Description   string         `json:"description"`
+	BeginSequence int64          `json:"begin_seq"`
+	EndSequence   int64          `json:"end_seq"`
+	Streams       []infoV4Stream `json:"stream,omitempty"`
+}
+
+type infoV4Stream struct {
+	ID        string `json:"id"`
+	Format    string `json:"format"`
+	Subformat string `json:"subformat"`
+	BeginTime string `json:"begin_time"`
+	EndTime   string `json:"end_time"`
+}
+
+// v4 converts the document to the JSON shape of SeedLink 4, where streams
+// are named LOC_B_S_SS.
+func (doc *infoDocument) v4() *infoV4Document {
+	v4 := &infoV4Document{
+		Software:     doc.Software,
+		Organization: doc.Organization,
+		Capabilities: doc.Capabilities,
+	}
+	for _, station := range doc.Stations {
+		s := infoV4Station{
+			ID:            getStationID(station.Network, station.Name),
+			Description:   station.Description,
+			BeginSequence: station.BeginSequence,
+			EndSequence:   station.EndSequence,
+		}
+		for _, stream := range station.Streams {
+			s.Streams = append(s.Streams, infoV4Stream{
+				ID:        stream.Location + "_" + strings.Join(strings.Split(stream.Channel, ""), "_"),
+				Format:    string(rune(V4_FORMAT_MSEED2)),
+				Subformat: string(rune(V4_SUBFORMAT_DATA)),
+				BeginTime: stream.Begin,
+				EndTime:   stream.End,
+			})
+		}
+		v4.Stations = append(v4.Stations, s)
+	}
+	return v4
+}
@@ -0,0 +1,20 @@
+// Package stationxml parses FDSN StationXML 1.x documents into networks,
+// stations, channels and their instrument responses, and looks channels up
+// by the codes and times of miniSEED records.
+//
+//	inv, err := stationxml.ReadFile("IU.ANMO.xml")
+//	if err != nil {
+//		// handle error
+//	}
+//	for _, series := range m.Series {
+//		c, err := inv.LookupRecord(&series.FixedSection)
+//		if errors.Is(err, stationxml.ErrNotFound) {
+//			continue
+//		}
+//		fmt.Println(c.Latitude, c.Longitude, c.Response.InstrumentSensitivity.Value)
+//	}
+//
+// Networks, stations and channels each have epochs, from StartDate up to
+// but not including EndDate, a zero EndDate leaving the epoch open. Lookups
+// pick the epoch holding the given time at every level.
+package stationxml
@@ -0,0 +1,127 @@
+package stationxml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// ErrNotFound is returned, wrapped with the identifier, when no epoch matches
+// a lookup.
+var ErrNotFound = errors.New("not found in inventory")
+
+// Parse reads a StationXML 1.x document.
+func Parse(r io.Reader) (*FDSNStationXML, error) {
+	var d FDSNStationXML
+	if err := xml.NewDecoder(r).Decode(&d); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(d.SchemaVersion, "1.") && d.SchemaVersion != "1" {
+		return nil, fmt.Errorf("unsupported StationXML schema version %q", d.SchemaVersion)
+	}
+
+	return &d, nil
+}
+
+// ReadFile reads a StationXML 1.x document from a file.
+func ReadFile(name string) (*FDSNStationXML, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// Write writes the document with an XML header, setting the schema version
+// when empty.
+func (d *FDSNStationXML) Write(w io.Writer) error {
+	if d.SchemaVersion == "" {
+		d.SchemaVersion = SCHEMA_VERSION
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(d); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// LookupStation returns the station epoch holding t.
+func (d *FDSNStationXML) LookupStation(network, station string, t time.Time) (*Station, error) {
+	network, station = strings.TrimSpace(network), strings.TrimSpace(station)
+	for i := range d.Networks {
+		n := &d.Networks[i]
+		if n.Code != network || !covers(n.StartDate, n.EndDate, t) {
+			continue
+		}
+		for j := range n.Stations {
+			s := &n.Stations[j]
+			if s.Code == station && covers(s.StartDate, s.EndDate, t) {
+				return s, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s.%s at %s: %w", network, station, t.Format(time.RFC3339), ErrNotFound)
+}
+
+// Lookup returns the channel epoch holding t. Codes may carry the space
+// padding of FixedSection, and the location code may be given as "--" when
+// empty.
+func (d *FDSNStationXML) Lookup(network, station, location, channel string, t time.Time) (*Channel, error) {
+	location, channel = strings.TrimSpace(location), strings.TrimSpace(channel)
+	if location == "--" {
+		location = ""
+	}
+
+	for i := range d.Networks {
+		n := &d.Networks[i]
+		if n.Code != strings.TrimSpace(network) || !covers(n.StartDate, n.EndDate, t) {
+			continue
+		}
+		for j := range n.Stations {
+			s := &n.Stations[j]
+			if s.Code != strings.TrimSpace(station) || !covers(s.StartDate, s.EndDate, t) {
+				continue
+			}
+			for k := range s.Channels {
+				c := &s.Channels[k]
+				if c.LocationCode == location && c.Code == channel && covers(c.StartDate, c.EndDate, t) {
+					return c, nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("%s.%s.%s.%s at %s: %w", strings.TrimSpace(network), strings.TrimSpace(station),
+		location, channel, t.Format(time.RFC3339), ErrNotFound)
+}
+
+// LookupRecord returns the channel epoch of a record, by its codes and start
+// time.
+func (d *FDSNStationXML) LookupRecord(f *mseedio.FixedSection) (*Channel, error) {
+	return d.Lookup(f.NetworkCode, f.StationCode, f.LocationCode, f.ChannelCode, f.StartTime)
+}
+
+// LookupTrace returns the channel epoch of a trace, by its codes and start
+// time.
+func (d *FDSNStationXML) LookupTrace(t *mseedio.Trace) (*Channel, error) {
+	return d.Lookup(t.NetworkCode, t.StationCode, t.LocationCode, t.ChannelCode, t.StartTime)
+}
@@ -0,0 +1,194 @@
+package stationxml
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+func TestParse(t *testing.T) {
+	d, err := ReadFile("testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.SchemaVersion != "1.1" || d.Source != "IRIS-DMC" || !d.Created.Equal(time.Date(2024, 1, 15, 10, 20, 30, 123400000, time.UTC)) {
+		t.Fatalf("unexpected header %q %q %s", d.SchemaVersion, d.Source, d.Created)
+	}
+	if len(d.Networks) != 1 || len(d.Networks[0].Stations) != 1 || len(d.Networks[0].Stations[0].Channels) != 3 {
+		t.Fatalf("want 1 network, 1 station and 3 channels, got %+v", d.Networks)
+	}
+
+	s := &d.Networks[0].Stations[0]
+	if s.Latitude != 34.945981 || s.Longitude != -106.457133 || s.Site.Name != "Albuquerque, New Mexico, USA" {
+		t.Errorf("unexpected station %+v", s)
+	}
+
+	c := &s.Channels[0]
+	if !c.StartDate.Equal(time.Date(2018, 7, 9, 20, 45, 0, 0, time.UTC)) || c.Dip != -90 || c.SampleRate != 40 {
+		t.Errorf("unexpected channel %+v", c)
+	}
+	if len(c.Types) != 2 || c.Sensor == nil || !strings.HasPrefix(c.Sensor.Description, "Streckeisen") {
+		t.Errorf("unexpected types %v and sensor %+v", c.Types, c.Sensor)
+	}
+
+	r := c.Response
+	if r == nil || r.InstrumentSensitivity.Value != 1.88802e9 || r.InstrumentSensitivity.InputUnits.Name != "M/S" || len(r.Stages) != 3 {
+		t.Fatalf("unexpected response %+v", r)
+	}
+	pz := r.Stages[0].PolesZeros
+	if pz == nil || pz.PzTransferFunctionType != LAPLACE_RADIANS || len(pz.Zeros) != 2 || len(pz.Poles) != 4 {
+		t.Fatalf("unexpected poles and zeros %+v", pz)
+	}
+	if pz.Poles[2].Complex() != complex(-39.18, 49.12) || pz.NormalizationFactor != 3948.58 || pz.OutputUnits.Name != "V" {
+		t.Errorf("unexpected pole %v, A0 %v or units %q", pz.Poles[2], pz.NormalizationFactor, pz.OutputUnits.Name)
+	}
+	if cf := r.Stages[1].Coefficients; cf == nil || cf.CfTransferFunctionType != DIGITAL || r.Stages[1].StageGain.Value != 1258700 {
+		t.Errorf("unexpected stage 2 %+v", r.Stages[1])
+	}
+	fir := r.Stages[2].FIR
+	if fir == nil || fir.Name != "FIR_3" || fir.Symmetry != SYMMETRY_EVEN || len(fir.NumeratorCoefficients) != 2 {
+		t.Errorf("unexpected FIR %+v", fir)
+	}
+	if dec := r.Stages[2].Decimation; dec == nil || dec.Factor != 1 || dec.Delay != 0.0375 {
+		t.Errorf("unexpected decimation %+v", dec)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	d, err := ReadFile("testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		network, station, location, channel string
+		t                                   time.Time
+		want                                float64 // Sensitivity, 0 for no response, -1 for not found
+	}{
+		{"IU", "ANMO", "00", "BHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 1.88802e9},
+		{"IU", "ANMO", "00", "BHZ", time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), 2.5e9},
+		{"IU", "ANMO", "00", "BHZ", time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), 2.5e9},
+		{"IU  ", "ANMO ", "  ", "LHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{"IU", "ANMO", "--", "LHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 0},
+		{"IU", "ANMO", "00", "BHZ", time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC), -1},
+		{"IU", "ANMO", "10", "BHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), -1},
+		{"II", "ANMO", "00", "BHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), -1},
+	}
+	for _, test := range tests {
+		c, err := d.Lookup(test.network, test.station, test.location, test.channel, test.t)
+		switch {
+		case test.want < 0:
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s.%s.%s.%s at %s: want ErrNotFound, got %v", test.network, test.station, test.location, test.channel, test.t, err)
+			}
+		case err != nil:
+			t.Errorf("%s.%s.%s.%s at %s: %v", test.network, test.station, test.location, test.channel, test.t, err)
+		case test.want == 0 && c.Response != nil, test.want > 0 && c.Response.InstrumentSensitivity.Value != test.want:
+			t.Errorf("%s.%s.%s.%s at %s: unexpected response %+v", test.network, test.station, test.location, test.channel, test.t, c.Response)
+		}
+	}
+
+	if s, err := d.LookupStation("IU", "ANMO", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("want ErrNotFound before the station epoch, got %+v", s)
+	}
+}
+
+func TestLookupRecord(t *testing.T) {
+	d, err := ReadFile("testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m mseedio.MiniSeedData
+	_ = m.Init(mseedio.STEIM2, mseedio.MSBFIRST)
+	err = m.Append(make([]int32, 400), &mseedio.AppendOptions{
+		SampleRate: 40, StartTime: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC), SequenceNumber: "000001",
+		NetworkCode: "IU", StationCode: "ANMO", LocationCode: "00", ChannelCode: "BHZ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := m.Encode(mseedio.OVERWRITE, mseedio.MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var read mseedio.MiniSeedData
+	if err := read.ReadFromReader(bytes.NewReader(record)); err != nil {
+		t.Fatal(err)
+	}
+	c, err := d.LookupRecord(&read.Series[0].FixedSection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Response.InstrumentSensitivity.Value != 1.88802e9 {
+		t.Errorf("want the 2018 epoch, got %+v", c)
+	}
+
+	traces := read.Traces()
+	if c, err := d.LookupTrace(&traces[0]); err != nil || c.SampleRate != 40 {
+		t.Errorf("want the BHZ channel for the trace, got %+v, %v", c, err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	d, err := ReadFile("testdata/IU.ANMO.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := d.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), `endDate=""`) || !strings.Contains(buf.String(), `xmlns="http://www.fdsn.org/xml/station/1"`) {
+		t.Fatalf("unexpected document %s", buf.String())
+	}
+
+	again, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := again.Lookup("IU", "ANMO", "00", "BHZ", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.EndDate.Equal(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)) || len(c.Response.Stages) != 3 ||
+		c.Response.Stages[0].PolesZeros.Poles[3].Complex() != complex(-39.18, -49.12) {
+		t.Fatalf("channel changed by writing it: %+v", c)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, doc := range []string{
+		`<FDSNStationXML xmlns="http://www.fdsn.org/xml/station/1" schemaVersion="2.0"></FDSNStationXML>`,
+		`<FDSNStationXML xmlns="http://www.fdsn.org/xml/station/1" schemaVersion="1.2"><Network code="IU" startDate="yesterday"/></FDSNStationXML>`,
+		`<quakeml xmlns="http://quakeml.org/xmlns/bed/1.2"></quakeml>`,
+		`<FDSNStationXML`,
+	} {
+		if _, err := Parse(strings.NewReader(doc)); err == nil {
+			t.Errorf("want error parsing %s", doc)
+		}
+	}
+}
+
+func TestResponseStages(t *testing.T) {
+	r := &Response{InstrumentSensitivity: &Sensitivity{Value: 1e9}}
+	r.Stage(1).PolesZeros = &PolesZeros{Filter: Filter{InputUnits: Units{Name: "M/S"}, OutputUnits: Units{Name: "V"}}}
+	r.Stage(2).StageGain = &Gain{Value: 4e5}
+	r.Stage(2).Coefficients = &Coefficients{Filter: Filter{InputUnits: Units{Name: "V"}, OutputUnits: Units{Name: "COUNTS"}}}
+	r.Stage(3).StageGain = &Gain{Value: 1}
+
+	if len(r.Stages) != 3 || r.Stage(2).StageGain == nil || r.Stage(3).Filter() != nil {
+		t.Fatalf("unexpected stages %+v", r.Stages)
+	}
+	r.SetSensitivityUnits()
+	if s := r.InstrumentSensitivity; s.InputUnits.Name != "M/S" || s.OutputUnits.Name != "COUNTS" {
+		t.Errorf("want sensitivity from M/S to COUNTS, got %+v", s)
+	}
+}
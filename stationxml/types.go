@@ -0,0 +1,343 @@
+package stationxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	NAMESPACE      = "http://www.fdsn.org/xml/station/1"
+	SCHEMA_VERSION = "1.2"
+)
+
+// Transfer function types of PolesZeros.
+const (
+	LAPLACE_RADIANS = "LAPLACE (RADIANS/SECOND)"
+	LAPLACE_HERTZ   = "LAPLACE (HERTZ)"
+	DIGITAL_Z       = "DIGITAL (Z-TRANSFORM)"
+)
+
+// Transfer function types of Coefficients.
+const (
+	ANALOG_RADIANS = "ANALOG (RADIANS/SECOND)"
+	ANALOG_HERTZ   = "ANALOG (HERTZ)"
+	DIGITAL        = "DIGITAL"
+)
+
+// Symmetries of FIR.
+const (
+	SYMMETRY_NONE = "NONE"
+	SYMMETRY_EVEN = "EVEN"
+	SYMMETRY_ODD  = "ODD"
+)
+
+// FDSNStationXML is the root of a StationXML document. Elements and
+// attributes not modeled here are skipped when parsing, and float values
+// keep their number only, without uncertainties or units.
+type FDSNStationXML struct {
+	XMLName       xml.Name  `xml:"http://www.fdsn.org/xml/station/1 FDSNStationXML"`
+	SchemaVersion string    `xml:"schemaVersion,attr"`
+	Source        string    `xml:"Source"`
+	Sender        string    `xml:"Sender,omitempty"`
+	Module        string    `xml:"Module,omitempty"`
+	ModuleURI     string    `xml:"ModuleURI,omitempty"`
+	Created       Time      `xml:"Created"`
+	Networks      []Network `xml:"Network"`
+}
+
+// Network is an epoch of a network.
+type Network struct {
+	Code             string    `xml:"code,attr"`
+	StartDate        Time      `xml:"startDate,attr"`
+	EndDate          Time      `xml:"endDate,attr"`
+	RestrictedStatus string    `xml:"restrictedStatus,attr,omitempty"`
+	Description      string    `xml:"Description,omitempty"`
+	Stations         []Station `xml:"Station"`
+}
+
+// Station is an epoch of a station.
+type Station struct {
+	Code             string    `xml:"code,attr"`
+	StartDate        Time      `xml:"startDate,attr"`
+	EndDate          Time      `xml:"endDate,attr"`
+	RestrictedStatus string    `xml:"restrictedStatus,attr,omitempty"`
+	Description      string    `xml:"Description,omitempty"`
+	Latitude         float64   `xml:"Latitude"`
+	Longitude        float64   `xml:"Longitude"`
+	Elevation        float64   `xml:"Elevation"` // Meters
+	Site             Site      `xml:"Site"`
+	Channels         []Channel `xml:"Channel"`
+}
+
+// Site describes where a station is.
+type Site struct {
+	Name        string `xml:"Name"`
+	Description string `xml:"Description,omitempty"`
+	Town        string `xml:"Town,omitempty"`
+	County      string `xml:"County,omitempty"`
+	Region      string `xml:"Region,omitempty"`
+	Country     string `xml:"Country,omitempty"`
+}
+
+// Channel is an epoch of a channel, the empty LocationCode being written
+// "--" in SEED identifiers.
+type Channel struct {
+	Code             string     `xml:"code,attr"`
+	LocationCode     string     `xml:"locationCode,attr"`
+	StartDate        Time       `xml:"startDate,attr"`
+	EndDate          Time       `xml:"endDate,attr"`
+	RestrictedStatus string     `xml:"restrictedStatus,attr,omitempty"`
+	Description      string     `xml:"Description,omitempty"`
+	Latitude         float64    `xml:"Latitude"`
+	Longitude        float64    `xml:"Longitude"`
+	Elevation        float64    `xml:"Elevation"` // Meters
+	Depth            float64    `xml:"Depth"`     // Meters below Elevation
+	Azimuth          float64    `xml:"Azimuth"`   // Degrees from north, clockwise
+	Dip              float64    `xml:"Dip"`       // Degrees down from horizontal
+	Types            []string   `xml:"Type,omitempty"`
+	SampleRate       float64    `xml:"SampleRate"`
+	Sensor           *Equipment `xml:"Sensor,omitempty"`
+	DataLogger       *Equipment `xml:"DataLogger,omitempty"`
+	Response         *Response  `xml:"Response,omitempty"`
+}
+
+// Equipment describes a sensor or a datalogger.
+type Equipment struct {
+	Type         string `xml:"Type,omitempty"`
+	Description  string `xml:"Description,omitempty"`
+	Manufacturer string `xml:"Manufacturer,omitempty"`
+	Model        string `xml:"Model,omitempty"`
+	SerialNumber string `xml:"SerialNumber,omitempty"`
+}
+
+// Response is the instrument response of a channel, as its overall
+// sensitivity and the stages it is made of, from the sensor to the
+// digitizer and its filters.
+type Response struct {
+	InstrumentSensitivity *Sensitivity `xml:"InstrumentSensitivity,omitempty"`
+	Stages                []Stage      `xml:"Stage"`
+}
+
+// Stage returns the stage of a number, adding it if missing.
+func (r *Response) Stage(number int) *Stage {
+	for i := range r.Stages {
+		if r.Stages[i].Number == number {
+			return &r.Stages[i]
+		}
+	}
+
+	r.Stages = append(r.Stages, Stage{Number: number})
+	return &r.Stages[len(r.Stages)-1]
+}
+
+// SetSensitivityUnits gives the overall sensitivity, if any, the input units
+// of the first filter and the output units of the last.
+func (r *Response) SetSensitivityUnits() {
+	s := r.InstrumentSensitivity
+	if s == nil {
+		return
+	}
+
+	for i := range r.Stages {
+		f := r.Stages[i].Filter()
+		if f == nil {
+			continue
+		}
+		if s.InputUnits.Name == "" {
+			s.InputUnits = f.InputUnits
+		}
+		s.OutputUnits = f.OutputUnits
+	}
+}
+
+// Sensitivity is the overall gain of a response at a frequency.
+type Sensitivity struct {
+	Value       float64 `xml:"Value"`
+	Frequency   float64 `xml:"Frequency"`
+	InputUnits  Units   `xml:"InputUnits"`
+	OutputUnits Units   `xml:"OutputUnits"`
+}
+
+// Stage is one stage of a response, holding at most one filter.
+type Stage struct {
+	Number       int           `xml:"number,attr"`
+	PolesZeros   *PolesZeros   `xml:"PolesZeros,omitempty"`
+	Coefficients *Coefficients `xml:"Coefficients,omitempty"`
+	ResponseList *ResponseList `xml:"ResponseList,omitempty"`
+	FIR          *FIR          `xml:"FIR,omitempty"`
+	Polynomial   *Polynomial   `xml:"Polynomial,omitempty"`
+	Decimation   *Decimation   `xml:"Decimation,omitempty"`
+	StageGain    *Gain         `xml:"StageGain,omitempty"`
+}
+
+// Filter returns the filter of the stage, nil for a gain-only one.
+func (s *Stage) Filter() *Filter {
+	switch {
+	case s.PolesZeros != nil:
+		return &s.PolesZeros.Filter
+	case s.Coefficients != nil:
+		return &s.Coefficients.Filter
+	case s.ResponseList != nil:
+		return &s.ResponseList.Filter
+	case s.FIR != nil:
+		return &s.FIR.Filter
+	case s.Polynomial != nil:
+		return &s.Polynomial.Filter
+	}
+	return nil
+}
+
+// Units names the physical units of a signal, such as M/S or COUNTS.
+type Units struct {
+	Name        string `xml:"Name"`
+	Description string `xml:"Description,omitempty"`
+}
+
+// Filter holds the elements shared by all filters of a stage.
+type Filter struct {
+	Name        string `xml:"name,attr,omitempty"`
+	Description string `xml:"Description,omitempty"`
+	InputUnits  Units  `xml:"InputUnits"`
+	OutputUnits Units  `xml:"OutputUnits"`
+}
+
+// PolesZeros is an analog or digital filter given by its poles and zeros.
+type PolesZeros struct {
+	Filter
+	PzTransferFunctionType string     `xml:"PzTransferFunctionType"`
+	NormalizationFactor    float64    `xml:"NormalizationFactor"`
+	NormalizationFrequency float64    `xml:"NormalizationFrequency"`
+	Zeros                  []PoleZero `xml:"Zero"`
+	Poles                  []PoleZero `xml:"Pole"`
+}
+
+// PoleZero is a complex pole or zero.
+type PoleZero struct {
+	Number    int     `xml:"number,attr"`
+	Real      float64 `xml:"Real"`
+	Imaginary float64 `xml:"Imaginary"`
+}
+
+// Complex returns the pole or zero as a complex number.
+func (p PoleZero) Complex() complex128 {
+	return complex(p.Real, p.Imaginary)
+}
+
+// Coefficients is a filter given by the coefficients of its numerator and
+// denominator, FIR filters having no denominator.
+type Coefficients struct {
+	Filter
+	CfTransferFunctionType string    `xml:"CfTransferFunctionType"`
+	Numerators             []float64 `xml:"Numerator"`
+	Denominators           []float64 `xml:"Denominator"`
+}
+
+// ResponseList is a response given by its amplitude and phase at a list of
+// frequencies.
+type ResponseList struct {
+	Filter
+	Elements []ResponseListElement `xml:"ResponseListElement"`
+}
+
+// ResponseListElement is the response at one frequency, the phase in
+// degrees.
+type ResponseListElement struct {
+	Frequency float64 `xml:"Frequency"`
+	Amplitude float64 `xml:"Amplitude"`
+	Phase     float64 `xml:"Phase"`
+}
+
+// FIR is a finite impulse response filter, of which only half of the
+// coefficients are given when it is symmetric.
+type FIR struct {
+	Filter
+	Symmetry              string    `xml:"Symmetry"`
+	NumeratorCoefficients []float64 `xml:"NumeratorCoefficient"`
+}
+
+// Polynomial is a response given as a polynomial of the input, such as that
+// of a thermometer.
+type Polynomial struct {
+	Filter
+	ApproximationType       string    `xml:"ApproximationType"`
+	FrequencyLowerBound     float64   `xml:"FrequencyLowerBound"`
+	FrequencyUpperBound     float64   `xml:"FrequencyUpperBound"`
+	ApproximationLowerBound float64   `xml:"ApproximationLowerBound"`
+	ApproximationUpperBound float64   `xml:"ApproximationUpperBound"`
+	MaximumError            float64   `xml:"MaximumError"`
+	Coefficients            []float64 `xml:"Coefficient"`
+}
+
+// Decimation describes the resampling done by a digital stage, delays being
+// in seconds.
+type Decimation struct {
+	InputSampleRate float64 `xml:"InputSampleRate"`
+	Factor          int     `xml:"Factor"`
+	Offset          int     `xml:"Offset"`
+	Delay           float64 `xml:"Delay"`
+	Correction      float64 `xml:"Correction"`
+}
+
+// Gain is the gain of a stage at a frequency.
+type Gain struct {
+	Value     float64 `xml:"Value"`
+	Frequency float64 `xml:"Frequency"`
+}
+
+// Time is a StationXML date, which may come without time zone (taken as UTC)
+// or without time. The zero Time is an open epoch end, and is left out when
+// written as an attribute.
+type Time struct {
+	time.Time
+}
+
+// timeLayouts are the accepted date layouts, tried in order.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// UnmarshalText parses a date in any of the accepted layouts.
+func (t *Time) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid date %q", s)
+}
+
+// MarshalText writes the date in UTC with up to microsecond precision.
+func (t Time) MarshalText() ([]byte, error) {
+	return []byte(t.UTC().Format("2006-01-02T15:04:05.999999Z")), nil
+}
+
+// UnmarshalXMLAttr parses a date attribute.
+func (t *Time) UnmarshalXMLAttr(attr xml.Attr) error {
+	return t.UnmarshalText([]byte(attr.Value))
+}
+
+// MarshalXMLAttr writes a date attribute, or nothing for the zero Time.
+func (t Time) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if t.IsZero() {
+		return xml.Attr{}, nil
+	}
+	text, err := t.MarshalText()
+	return xml.Attr{Name: name, Value: string(text)}, err
+}
+
+// covers reports whether an epoch from start to end, an open one when end is
+// zero, holds t.
+func covers(start, end Time, t time.Time) bool {
+	return !t.Before(start.Time) && (end.IsZero() || t.Before(end.Time))
+}
@@ -0,0 +1,313 @@
+package mseedio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// The length of a Steim frame, 16 32-bit words
+const STEIM_FRAME_LENGTH = 64
+
+// Steim integrity errors, which leave the decoded samples usable, see
+// WithIntegrity
+var (
+	// ErrSteimXn is returned when the last decoded sample does not match the
+	// reverse integration constant Xn stored in the first frame.
+	ErrSteimXn = errors.New("unpacked samples does not match xn")
+	// ErrSteimCount is returned when the frames hold fewer differences than
+	// the SamplesNumber of the record.
+	ErrSteimCount = errors.New("decoded samples does not match samples number")
+)
+
+// DecodeSteim1Into decodes Steim-1 frames from src into dst, which the caller
+// sizes to the number of samples in the record. It walks the frames once
+// without allocating and returns the number of samples written, which is less
+// than len(dst) if src runs out of differences; the last frame may be cut
+// short. When dst is filled, the last sample is checked against Xn. Steim data
+// is always big-endian.
+func DecodeSteim1Into(dst []int32, src []byte) (int, error) {
+	x0, xn, err := getSteimConstants(src)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for f := 0; f+4 <= len(src) && n < len(dst); f += STEIM_FRAME_LENGTH {
+		frame := getSteimFrame(src, f)
+		w0 := binary.BigEndian.Uint32(frame)
+		for w := 1; w < len(frame)/4 && n < len(dst); w++ {
+			word := binary.BigEndian.Uint32(frame[w*4:])
+			switch (w0 >> (30 - 2*w)) & 0x03 {
+			case 0: // Non-data information
+			case 1: // Contains four 8-bit differences
+				for i := 0; i < 4; i++ {
+					n = putSteimDiff(dst, n, x0, getSignedBits(word>>(24-8*i), 8))
+				}
+			case 2: // Contains two 16-bit differences
+				for i := 0; i < 2; i++ {
+					n = putSteimDiff(dst, n, x0, getSignedBits(word>>(16-16*i), 16))
+				}
+			case 3: // Contains one 32-bit difference
+				n = putSteimDiff(dst, n, x0, int32(word))
+			}
+		}
+	}
+
+	return n, checkSteimXn(dst, n, xn)
+}
+
+// DecodeSteim2Into decodes Steim-2 frames from src into dst, see
+// DecodeSteim1Into.
+func DecodeSteim2Into(dst []int32, src []byte) (int, error) {
+	x0, xn, err := getSteimConstants(src)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for f := 0; f+4 <= len(src) && n < len(dst); f += STEIM_FRAME_LENGTH {
+		frame := getSteimFrame(src, f)
+		w0 := binary.BigEndian.Uint32(frame)
+		for w := 1; w < len(frame)/4 && n < len(dst); w++ {
+			word := binary.BigEndian.Uint32(frame[w*4:])
+			dnib := word >> 30
+			switch (w0 >> (30 - 2*w)) & 0x03 {
+			case 0: // Non-data information
+			case 1: // Contains four 8-bit differences
+				for i := 0; i < 4; i++ {
+					n = putSteimDiff(dst, n, x0, getSignedBits(word>>(24-8*i), 8))
+				}
+			case 2: // Determine from dnib
+				switch dnib {
+				case 1: // Wn contains one 30-bit difference
+					n = putSteimDiff(dst, n, x0, getSignedBits(word, 30))
+				case 2: // Wn contains two 15-bit differences
+					for i := 0; i < 2; i++ {
+						n = putSteimDiff(dst, n, x0, getSignedBits(word>>(15-15*i), 15))
+					}
+				case 3: // Wn contains three 10-bit differences
+					for i := 0; i < 3; i++ {
+						n = putSteimDiff(dst, n, x0, getSignedBits(word>>(20-10*i), 10))
+					}
+				default:
+					return n, fmt.Errorf("illegal decode nibble")
+				}
+			case 3: // Determine from dnib
+				switch dnib {
+				case 0: // Wn contains five 6-bit differences
+					for i := 0; i < 5; i++ {
+						n = putSteimDiff(dst, n, x0, getSignedBits(word>>(24-6*i), 6))
+					}
+				case 1: // Wn contains six 5-bit differences
+					for i := 0; i < 6; i++ {
+						n = putSteimDiff(dst, n, x0, getSignedBits(word>>(25-5*i), 5))
+					}
+				case 2: // Wn contains seven 4-bit differences
+					for i := 0; i < 7; i++ {
+						n = putSteimDiff(dst, n, x0, getSignedBits(word>>(24-4*i), 4))
+					}
+				default:
+					return n, fmt.Errorf("illegal decode nibble")
+				}
+			}
+		}
+	}
+
+	return n, checkSteimXn(dst, n, xn)
+}
+
+// getSteimFrame returns the frame starting at offset f of src, which may be
+// cut short at the end of src.
+func getSteimFrame(src []byte, f int) []byte {
+	if f+STEIM_FRAME_LENGTH > len(src) {
+		return src[f:]
+	}
+	return src[f : f+STEIM_FRAME_LENGTH]
+}
+
+// getSteimConstants reads the forward (X0) and reverse (Xn) integration
+// constants from words 1 and 2 of the first frame.
+func getSteimConstants(src []byte) (x0, xn int32, err error) {
+	if len(src) < 12 {
+		return 0, 0, fmt.Errorf("Steim data requires at least 12 bytes, got %d", len(src))
+	}
+
+	x0 = int32(binary.BigEndian.Uint32(src[4:8]))
+	xn = int32(binary.BigEndian.Uint32(src[8:12]))
+	return x0, xn, nil
+}
+
+// putSteimDiff integrates difference d into dst[n] and returns the next
+// index. The first difference is replaced by X0; differences beyond the end
+// of dst are dropped.
+func putSteimDiff(dst []int32, n int, x0, d int32) int {
+	switch {
+	case n >= len(dst):
+		return n
+	case n == 0:
+		dst[0] = x0
+	default:
+		dst[n] = dst[n-1] + d
+	}
+
+	return n + 1
+}
+
+// getSignedBits sign-extends the low width bits of v.
+func getSignedBits(v uint32, width uint) int32 {
+	return int32(v<<(32-width)) >> (32 - width)
+}
+
+// checkSteimXn compares the last of n decoded samples with Xn once dst is
+// filled.
+func checkSteimXn(dst []int32, n int, xn int32) error {
+	if n == 0 || n < len(dst) || dst[n-1] == xn {
+		return nil
+	}
+
+	return ErrSteimXn
+}
+
+// isSteimIntegrityError reports whether err is a Steim integrity error, after
+// which the decoded samples are kept.
+func isSteimIntegrityError(err error) bool {
+	return errors.Is(err, ErrSteimXn) || errors.Is(err, ErrSteimCount)
+}
+
+// steimPacking is one way of packing differences into a 32-bit Steim word.
+type steimPacking struct {
+	count int    // Differences per word
+	width uint   // Bits per difference
+	nib   uint32 // 2-bit compression flag in w0
+	dnib  uint32 // 2-bit decode nibble heading the word, Steim-2 only
+}
+
+var (
+	// Steim-1 packings, the most differences per word first
+	steim1Packings = []steimPacking{
+		{4, 8, 1, 0}, {2, 16, 2, 0}, {1, 32, 3, 0},
+	}
+	// Steim-2 packings, the most differences per word first
+	steim2Packings = []steimPacking{
+		{7, 4, 3, 2}, {6, 5, 3, 1}, {5, 6, 3, 0}, {4, 8, 1, 0},
+		{3, 10, 2, 3}, {2, 15, 2, 2}, {1, 30, 2, 1},
+	}
+)
+
+// EncodeSteim1 packs samples into at most frames Steim-1 frames, or as many
+// as needed if frames < 1, and returns the frames along with the number of
+// leading samples they hold. Leftover samples can go into the next record.
+// Like libmseed, every word greedily takes the packing that holds the most of
+// the upcoming differences: the packing is not frame-optimal, and a search
+// over packings could fit a few more samples in some records.
+func EncodeSteim1(samples []int32, frames int) ([]byte, int, error) {
+	return encodeSteim(samples, frames, steim1Packings, 32)
+}
+
+// EncodeSteim2 packs samples into at most frames Steim-2 frames, greedily as
+// EncodeSteim1 does. Differences that do not fit in 30 bits cannot be
+// represented in Steim-2 and yield an error.
+func EncodeSteim2(samples []int32, frames int) ([]byte, int, error) {
+	return encodeSteim(samples, frames, steim2Packings, 30)
+}
+
+// encodeSteim packs samples into Steim frames using packings, whose widest
+// difference has maxWidth bits.
+func encodeSteim(samples []int32, frames int, packings []steimPacking, maxWidth uint) ([]byte, int, error) {
+	if len(samples) == 0 {
+		return nil, 0, nil
+	}
+
+	// Reserve the worst case of one difference per word
+	size := frames
+	if size < 1 {
+		size = len(samples)/15 + 1
+	}
+	var (
+		out      = make([]byte, 0, size*STEIM_FRAME_LENGTH)
+		n        int
+		widths   [8]uint // Bits needed by difference i, at i%8
+		measured int     // Differences measured so far
+		widest   [7]uint // Bits needed by the widest of the upcoming differences so far
+		maxNext  = packings[0].count
+	)
+	for f := 0; (frames < 1 || f < frames) && n < len(samples); f++ {
+		var frame [16]uint32
+		w := 1
+		if f == 0 {
+			w = 3 // Words 1 and 2 hold X0 and Xn
+		}
+
+		for ; w < 16 && n < len(samples); w++ {
+			// Measure the differences the widest packing could take
+			available := len(samples) - n
+			if available > maxNext {
+				available = maxNext
+			}
+			for ; measured < n+available; measured++ {
+				widths[measured%8] = getSteimWidth(getSteimDiff(samples, measured))
+			}
+			widest[0] = widths[n%8]
+			for i := 1; i < available; i++ {
+				widest[i] = widest[i-1]
+				if w := widths[(n+i)%8]; w > widest[i] {
+					widest[i] = w
+				}
+			}
+			if widest[0] > maxWidth {
+				return nil, 0, fmt.Errorf("difference at sample %d does not fit in %d bits", n, maxWidth)
+			}
+
+			// Take the first packing the differences fit in
+			for _, p := range packings {
+				if p.count > available || widest[p.count-1] > p.width {
+					continue
+				}
+
+				word := p.dnib << 30
+				mask := uint32(1)<<p.width - 1
+				if p.width == 32 {
+					mask = ^uint32(0)
+				}
+				for i := 0; i < p.count; i++ {
+					shift := p.width * uint(p.count-1-i)
+					word |= uint32(getSteimDiff(samples, n+i)) & mask << shift
+				}
+				frame[w] = word
+				frame[0] |= p.nib << (30 - 2*uint(w))
+				n += p.count
+				break
+			}
+		}
+
+		if f == 0 {
+			frame[1] = uint32(samples[0])
+		}
+		for _, word := range frame {
+			out = binary.BigEndian.AppendUint32(out, word)
+		}
+	}
+
+	// Xn is the last sample that made it into the frames
+	binary.BigEndian.PutUint32(out[8:12], uint32(samples[n-1]))
+	return out, n, nil
+}
+
+// getSteimDiff returns the difference leading up to sample i; the first one
+// is 0, as X0 carries the first sample.
+func getSteimDiff(samples []int32, i int) int32 {
+	if i == 0 {
+		return 0
+	}
+	return samples[i] - samples[i-1]
+}
+
+// getSteimWidth returns the number of bits needed to hold d as a signed value.
+func getSteimWidth(d int32) uint {
+	if d < 0 {
+		d = ^d
+	}
+	return uint(bits.Len32(uint32(d))) + 1
+}
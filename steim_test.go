@@ -0,0 +1,289 @@
+package mseedio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// getSteimTestSamples returns a random walk mixing small and large steps, so
+// that every Steim packing width is exercised.
+func getSteimTestSamples(n int, seed int64) []int32 {
+	r := rand.New(rand.NewSource(seed))
+	samples := make([]int32, n)
+	for i := 1; i < n; i++ {
+		step := int32(r.Intn(16)) - 8
+		switch r.Intn(10) {
+		case 0:
+			step = int32(r.Intn(1<<20)) - 1<<19
+		case 1:
+			step = int32(r.Intn(1<<12)) - 1<<11
+		}
+		samples[i] = samples[i-1] + step
+	}
+	return samples
+}
+
+// getPaddedFrames zero-pads packed Steim data to whole frames, as it is
+// within a record.
+func getPaddedFrames(packed []byte) []byte {
+	frames := (len(packed) + STEIM_FRAME_LENGTH - 1) / STEIM_FRAME_LENGTH
+	return append(packed, make([]byte, frames*STEIM_FRAME_LENGTH-len(packed))...)
+}
+
+// getShortFrames cuts the unused words, of nibble 0, off the last frame of
+// packed Steim data, as found at the end of a truncated record.
+func getShortFrames(packed []byte) []byte {
+	last := len(packed) - STEIM_FRAME_LENGTH
+	nibbles := binary.BigEndian.Uint32(packed[last:])
+	words := 16
+	for words > 1 && nibbles>>(2*(16-words))&3 == 0 {
+		words--
+	}
+	return packed[:last+4*words]
+}
+
+// TestDecodeSteimInto checks the single-pass decoders against the original
+// samples, from whole and short last frames.
+func TestDecodeSteimInto(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		pack func([]int32, int) ([]byte, int, error)
+		into func([]int32, []byte) (int, error)
+	}{
+		{"Steim1", EncodeSteim1, DecodeSteim1Into},
+		{"Steim2", EncodeSteim2, DecodeSteim2Into},
+	} {
+		for seed := int64(1); seed <= 20; seed++ {
+			samples := getSteimTestSamples(50+int(seed)*37, seed)
+			packed, _, err := c.pack(samples, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := make([]int32, len(samples))
+			n, err := c.into(dst, packed)
+			if err != nil || n != len(samples) {
+				t.Fatalf("%s seed %d: decoded %d of %d samples: %v", c.name, seed, n, len(samples), err)
+			}
+			for i := range samples {
+				if dst[i] != samples[i] {
+					t.Fatalf("%s seed %d sample %d: want %d, got %d", c.name, seed, i, samples[i], dst[i])
+				}
+			}
+			if n, err := c.into(dst, getShortFrames(packed)); err != nil || n != len(samples) {
+				t.Fatalf("%s seed %d: decoded %d of %d samples from a short frame: %v", c.name, seed, n, len(samples), err)
+			}
+		}
+	}
+}
+
+// TestEncodeSteim checks that the frame-budgeted encoders round trip and stop
+// at the frame budget.
+func TestEncodeSteim(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		encode func([]int32, int) ([]byte, int, error)
+		decode func([]int32, []byte) (int, error)
+	}{
+		{"Steim1", EncodeSteim1, DecodeSteim1Into},
+		{"Steim2", EncodeSteim2, DecodeSteim2Into},
+	} {
+		for seed := int64(1); seed <= 20; seed++ {
+			samples := getSteimTestSamples(50+int(seed)*37, seed)
+			packed, n, err := c.encode(samples, 0)
+			if err != nil || n != len(samples) {
+				t.Fatalf("%s seed %d: encoded %d of %d samples: %v", c.name, seed, n, len(samples), err)
+			}
+			if len(packed)%STEIM_FRAME_LENGTH != 0 {
+				t.Fatalf("%s seed %d: got %d bytes, not whole frames", c.name, seed, len(packed))
+			}
+
+			// Only the leading samples go into a smaller budget
+			frames := len(packed)/STEIM_FRAME_LENGTH/2 + 1
+			budget, n, err := c.encode(samples, frames)
+			if err != nil || n == 0 || n >= len(samples) || len(budget) != frames*STEIM_FRAME_LENGTH {
+				t.Fatalf("%s seed %d: %d frames hold %d samples in %d bytes: %v", c.name, seed, frames, n, len(budget), err)
+			}
+
+			for _, v := range []struct {
+				packed []byte
+				n      int
+			}{{packed, len(samples)}, {budget, n}} {
+				dst := make([]int32, v.n)
+				if got, err := c.decode(dst, v.packed); err != nil || got != v.n {
+					t.Fatalf("%s seed %d: decoded %d of %d samples: %v", c.name, seed, got, v.n, err)
+				}
+				for i := range dst {
+					if dst[i] != samples[i] {
+						t.Fatalf("%s seed %d sample %d: want %d, got %d", c.name, seed, i, samples[i], dst[i])
+					}
+				}
+			}
+		}
+	}
+
+	if _, _, err := EncodeSteim2([]int32{0, 1 << 30}, 0); err == nil {
+		t.Error("expected an error for a difference beyond 30 bits")
+	}
+}
+
+// TestEncodeSteimLibmseed checks that the encoders pack the samples of the
+// Steim fixtures, written by libmseed, into the same frames, byte for byte.
+// These are the only libmseed-written Steim records at hand, a ramp of 1 to
+// 50 taking a single frame.
+func TestEncodeSteimLibmseed(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		encode func([]int32, int) ([]byte, int, error)
+		frames int // Frames used by libmseed
+	}{
+		{"int32_Steim1_bigEndian.mseed", EncodeSteim1, 1},
+		{"int32_Steim2_bigEndian.mseed", EncodeSteim2, 1},
+	} {
+		var m MiniSeedData
+		if err := m.Read(filepath.Join("example/reader/testdata", c.name)); err != nil {
+			t.Fatal(err)
+		}
+		s := &m.Series[0]
+		var samples []int32
+		for _, v := range s.DataSection.Decoded {
+			samples = append(samples, v.(int32))
+		}
+		want := s.DataSection.RawData[:c.frames*STEIM_FRAME_LENGTH]
+		if len(samples) != 50 || !bytes.Equal(s.DataSection.RawData[len(want):], make([]byte, len(s.DataSection.RawData)-len(want))) {
+			t.Fatalf("%s: want 50 samples in %d frames, got %d samples", c.name, c.frames, len(samples))
+		}
+
+		// Unbounded and within the frames of the record alike
+		for _, budget := range []int{0, len(s.DataSection.RawData) / STEIM_FRAME_LENGTH} {
+			packed, n, err := c.encode(samples, budget)
+			if err != nil || n != len(samples) {
+				t.Fatalf("%s: encoded %d of %d samples: %v", c.name, n, len(samples), err)
+			}
+			if !bytes.Equal(getLibmseedFrames(packed), want) {
+				t.Errorf("%s, budget %d: want\n% x\ngot\n% x", c.name, budget, want, packed)
+			}
+		}
+	}
+}
+
+// getLibmseedFrames returns the frames of packed up to the last one in use,
+// libmseed leaving the remaining frames of a record zeroed.
+func getLibmseedFrames(packed []byte) []byte {
+	for len(packed) >= STEIM_FRAME_LENGTH && bytes.Equal(packed[len(packed)-STEIM_FRAME_LENGTH:], make([]byte, STEIM_FRAME_LENGTH)) {
+		packed = packed[:len(packed)-STEIM_FRAME_LENGTH]
+	}
+	return packed
+}
+
+// TestAppendRecordLength checks that Append splits samples across records of
+// the requested length, continuing sequence numbers and start times.
+func TestAppendRecordLength(t *testing.T) {
+	for _, encoding := range []int{STEIM1, STEIM2, INT32} {
+		samples := getSteimTestSamples(5000, int64(encoding))
+		start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+		var m MiniSeedData
+		_ = m.Init(encoding, MSBFIRST)
+		err := m.Append(samples, &AppendOptions{
+			SampleRate: 100, StartTime: start, SequenceNumber: "000010", RecordLength: 512,
+			StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Records < 2 {
+			t.Fatalf("encoding %d: expected several records, got %d", encoding, m.Records)
+		}
+
+		encoded, err := m.Encode(OVERWRITE, MSBFIRST)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encoded) != m.Records*512 {
+			t.Fatalf("encoding %d: got %d bytes for %d records", encoding, len(encoded), m.Records)
+		}
+
+		var r MiniSeedData
+		if err := r.ReadFromReader(bytes.NewReader(encoded)); err != nil {
+			t.Fatal(err)
+		}
+		for i, s := range r.Series {
+			if want := fmt.Sprintf("%06d", 10+i); s.FixedSection.SequenceNumber != want {
+				t.Errorf("encoding %d record %d: want sequence number %s, got %s", encoding, i, want, s.FixedSection.SequenceNumber)
+			}
+		}
+		traces := r.Traces()
+		if len(traces) != 1 || len(traces[0].Samples) != len(samples) || !traces[0].StartTime.Equal(start) {
+			t.Fatalf("encoding %d: expected one trace of %d samples", encoding, len(samples))
+		}
+		for i, v := range traces[0].Samples {
+			if v.(int32) != samples[i] {
+				t.Fatalf("encoding %d sample %d: want %d, got %v", encoding, i, samples[i], v)
+			}
+		}
+	}
+}
+
+// benchmarkSteim decodes a 4096-byte record worth of frames per iteration.
+func benchmarkSteim(b *testing.B, pack func([]int32, int) ([]byte, error), decode func(dst []int32, src []byte) error) {
+	samples := getSteimTestSamples(2000, 42)
+	packed, err := pack(samples, MSBFIRST)
+	if err != nil {
+		b.Fatal(err)
+	}
+	packed = getPaddedFrames(packed)
+	frames := 4032 / STEIM_FRAME_LENGTH
+	if len(packed) > frames*STEIM_FRAME_LENGTH {
+		b.Fatalf("test data does not fit in %d frames", frames)
+	}
+
+	dst := make([]int32, len(samples))
+	b.SetBytes(int64(len(packed)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := decode(dst, packed); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkEncodeSteim packs 2000 samples per iteration.
+func benchmarkEncodeSteim(b *testing.B, encode func([]int32) ([]byte, error)) {
+	samples := getSteimTestSamples(2000, 42)
+	b.SetBytes(int64(len(samples) * 4))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := encode(samples); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeSteim2(b *testing.B) {
+	benchmarkEncodeSteim(b, func(samples []int32) ([]byte, error) {
+		packed, _, err := EncodeSteim2(samples, 0)
+		return packed, err
+	})
+}
+
+func BenchmarkDecodeSteim1Into(b *testing.B) {
+	benchmarkSteim(b, packSteim1, func(dst []int32, src []byte) error {
+		_, err := DecodeSteim1Into(dst, src)
+		return err
+	})
+}
+
+func BenchmarkDecodeSteim2Into(b *testing.B) {
+	benchmarkSteim(b, packSteim2, func(dst []int32, src []byte) error {
+		_, err := DecodeSteim2Into(dst, src)
+		return err
+	})
+}
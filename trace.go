@@ -0,0 +1,369 @@
+package mseedio
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trace is a continuous run of samples assembled from one or more records of
+// the same channel. Codes are stored without their space padding.
+type Trace struct {
+	NetworkCode  string
+	StationCode  string
+	LocationCode string
+	ChannelCode  string
+	DataQuality  string
+	SampleRate   float64
+	StartTime    time.Time
+	EndTime      time.Time // Time of the last sample
+	Samples      []any
+}
+
+// Gap is a break between two consecutive traces of the same channel, a
+// negative Duration being an overlap.
+type Gap struct {
+	NetworkCode  string
+	StationCode  string
+	LocationCode string
+	ChannelCode  string
+	LastSample   time.Time // Last sample before the gap
+	NextSample   time.Time // First sample after the gap
+	Duration     time.Duration
+}
+
+// QualityRank ranks a data quality indicator so that, as is common data
+// center practice, M beats Q beats R beats D. Invalid indicators rank 0.
+// MiniSeedData.Traces keeps the samples of the best ranked records.
+func QualityRank(quality string) int {
+	switch quality {
+	case "D":
+		return 1
+	case "R":
+		return 2
+	case "Q":
+		return 3
+	case "M":
+		return 4
+	}
+
+	return 0
+}
+
+// SampleRate returns the sample rate in Hz from SampleFactor and
+// SampleMultiplier, following the SEED sign conventions.
+func (f *FixedSection) SampleRate() float64 {
+	factor, multiplier := float64(f.SampleFactor), float64(f.SampleMultiplier)
+	switch {
+	case factor == 0 || multiplier == 0:
+		return 0
+	case factor > 0 && multiplier > 0:
+		return factor * multiplier
+	case factor > 0 && multiplier < 0:
+		return -factor / multiplier
+	case factor < 0 && multiplier > 0:
+		return -multiplier / factor
+	default:
+		return 1 / (factor * multiplier)
+	}
+}
+
+// EndTime returns the time of the last sample of the record. time.Time has
+// no leap seconds, so when the activity flags report a leap second inside the
+// record, the inserted (or skipped) second is taken off (or added to) the span.
+func (d *DataSeries) EndTime() time.Time {
+	if d.FixedSection.SamplesNumber <= 1 {
+		return d.FixedSection.StartTime
+	}
+
+	return d.sampleTime(int(d.FixedSection.SamplesNumber) - 1)
+}
+
+// sampleTime returns the time of sample i of the record, see EndTime.
+func (d *DataSeries) sampleTime(i int) time.Time {
+	f := &d.FixedSection
+	rate := f.SampleRate()
+	if rate <= 0 {
+		return f.StartTime
+	}
+	t := f.StartTime.Add(time.Duration(math.Round(float64(i) / rate * float64(time.Second))))
+
+	// Leap seconds are inserted or removed at the end of a UTC day
+	midnight := f.StartTime.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	switch {
+	case f.ActivityFlags&ACTIVITY_LEAP_POSITIVE != 0:
+		// A start inside the leap second reads as just after its midnight
+		if f.StartBTime.IsLeapSecond() {
+			midnight = midnight.Add(-24 * time.Hour)
+		}
+		// Samples inside the leap second are held at midnight, so that times
+		// never go backwards, and those after it are one second early
+		if !t.Before(midnight.Add(time.Second)) {
+			t = t.Add(-time.Second)
+		} else if !t.Before(midnight) {
+			t = midnight
+		}
+	case f.ActivityFlags&ACTIVITY_LEAP_NEGATIVE != 0:
+		if !t.Add(time.Second).Before(midnight) {
+			t = t.Add(time.Second)
+		}
+	}
+
+	return t
+}
+
+// segment is a run of samples [from, to) of a record that made it into a trace.
+type segment struct {
+	series   *DataSeries
+	samples  []any
+	from, to int
+}
+
+// start returns the time of the first sample of the segment.
+func (s *segment) start() time.Time { return s.series.sampleTime(s.from) }
+
+// end returns the time of the last sample of the segment.
+func (s *segment) end() time.Time { return s.series.sampleTime(s.to - 1) }
+
+// Traces merges the records into continuous traces, one per run of records of
+// the same channel whose samples follow on within half a sample period.
+// Where records overlap, the samples of the better data quality are kept
+// (M beats Q beats R beats D, as is common data center practice) and the
+// trace takes the best quality it holds. Traces are ordered by channel codes,
+// then by start time.
+func (m *MiniSeedData) Traces() []Trace {
+	// Visit records from the best quality down, earliest first
+	order := make([]*DataSeries, len(m.Series))
+	for i := range m.Series {
+		order[i] = &m.Series[i]
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		ri, rj := QualityRank(order[i].FixedSection.DataQuality), QualityRank(order[j].FixedSection.DataQuality)
+		if ri != rj {
+			return ri > rj
+		}
+		return order[i].FixedSection.StartTime.Before(order[j].FixedSection.StartTime)
+	})
+
+	// Keep the samples not covered by records of better quality
+	var (
+		segments []segment
+		covered  = map[string][]segment{}
+	)
+	for _, s := range order {
+		key := getChannelKey(&s.FixedSection)
+		for _, seg := range getUncovered(s, covered[key]) {
+			segments = append(segments, seg)
+			covered[key] = append(covered[key], seg)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		ki, kj := getChannelKey(&segments[i].series.FixedSection), getChannelKey(&segments[j].series.FixedSection)
+		if ki != kj {
+			return ki < kj
+		}
+		return segments[i].start().Before(segments[j].start())
+	})
+
+	var traces []Trace
+	for i := range segments {
+		seg := &segments[i]
+		f := &seg.series.FixedSection
+		if n := len(traces); n > 0 && traces[n-1].follows(seg) {
+			t := &traces[n-1]
+			t.Samples = append(t.Samples, seg.samples[seg.from:seg.to]...)
+			t.EndTime = seg.end()
+			if QualityRank(f.DataQuality) > QualityRank(t.DataQuality) {
+				t.DataQuality = f.DataQuality
+			}
+			continue
+		}
+
+		traces = append(traces, Trace{
+			NetworkCode:  strings.TrimSpace(f.NetworkCode),
+			StationCode:  strings.TrimSpace(f.StationCode),
+			LocationCode: strings.TrimSpace(f.LocationCode),
+			ChannelCode:  strings.TrimSpace(f.ChannelCode),
+			DataQuality:  f.DataQuality,
+			SampleRate:   f.SampleRate(),
+			StartTime:    seg.start(),
+			EndTime:      seg.end(),
+			Samples:      append([]any{}, seg.samples[seg.from:seg.to]...),
+		})
+	}
+
+	return traces
+}
+
+// getUncovered splits the samples of record s into the segments that do not
+// fall within half a sample period of any covered segment.
+func getUncovered(s *DataSeries, covered []segment) []segment {
+	ds := s.DataSection // Decoded here if read lazily, leaving s untouched
+	if err := ds.Decode(); err != nil && !isSteimIntegrityError(err) {
+		return nil
+	}
+	samples := appendSamples(nil, ds.Decoded)
+	if len(samples) == 0 {
+		return nil
+	}
+	whole := segment{series: s, samples: samples, from: 0, to: len(samples)}
+
+	rate := s.FixedSection.SampleRate()
+	if rate <= 0 {
+		return []segment{whole}
+	}
+	half := time.Duration(float64(time.Second) / rate / 2)
+
+	// Only look at samples one by one when the record overlaps something
+	var overlapping []segment
+	for _, c := range covered {
+		if !whole.start().After(c.end().Add(half)) && !whole.end().Before(c.start().Add(-half)) {
+			overlapping = append(overlapping, c)
+		}
+	}
+	if len(overlapping) == 0 {
+		return []segment{whole}
+	}
+
+	var (
+		segments []segment
+		from     = -1
+	)
+	for i := 0; i <= len(samples); i++ {
+		free := i < len(samples)
+		if free {
+			t := s.sampleTime(i)
+			for _, c := range overlapping {
+				if !t.After(c.end().Add(half)) && !t.Before(c.start().Add(-half)) {
+					free = false
+					break
+				}
+			}
+		}
+
+		switch {
+		case free && from < 0:
+			from = i
+		case !free && from >= 0:
+			segments = append(segments, segment{series: s, samples: samples, from: from, to: i})
+			from = -1
+		}
+	}
+
+	return segments
+}
+
+// Gaps lists the gaps and overlaps between the traces returned by Traces.
+func (m *MiniSeedData) Gaps() []Gap {
+	var (
+		gaps   []Gap
+		traces = m.Traces()
+	)
+	for i := 1; i < len(traces); i++ {
+		prev, next := &traces[i-1], &traces[i]
+		if prev.key() != next.key() {
+			continue
+		}
+
+		gaps = append(gaps, Gap{
+			NetworkCode:  next.NetworkCode,
+			StationCode:  next.StationCode,
+			LocationCode: next.LocationCode,
+			ChannelCode:  next.ChannelCode,
+			LastSample:   prev.EndTime,
+			NextSample:   next.StartTime,
+			Duration:     next.StartTime.Sub(prev.EndTime) - prev.period(),
+		})
+	}
+
+	return gaps
+}
+
+// Float64s returns the samples of the trace as float64, failing on samples
+// that are not numbers, such as the text of ASCII records.
+func (t *Trace) Float64s() ([]float64, error) {
+	values := make([]float64, len(t.Samples))
+	for i, v := range t.Samples {
+		switch v := v.(type) {
+		case int16:
+			values[i] = float64(v)
+		case int32:
+			values[i] = float64(v)
+		case int64:
+			values[i] = float64(v)
+		case int:
+			values[i] = float64(v)
+		case float32:
+			values[i] = float64(v)
+		case float64:
+			values[i] = v
+		default:
+			return nil, fmt.Errorf("sample %d is not numeric (%T)", i, v)
+		}
+	}
+
+	return values, nil
+}
+
+// follows reports whether segment s continues the trace without a gap.
+func (t *Trace) follows(s *segment) bool {
+	f := &s.series.FixedSection
+	if t.key() != getChannelKey(f) {
+		return false
+	}
+
+	rate := f.SampleRate()
+	if t.SampleRate <= 0 || math.Abs(rate-t.SampleRate) > t.SampleRate*1e-6 {
+		return false
+	}
+
+	period := t.period()
+	offset := s.start().Sub(t.EndTime.Add(period))
+	return offset >= -period/2 && offset <= period/2
+}
+
+// period returns the sample period of the trace.
+func (t *Trace) period() time.Duration {
+	if t.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / t.SampleRate)
+}
+
+// key returns the channel key of the trace, see getChannelKey.
+func (t *Trace) key() string {
+	return strings.Join([]string{t.NetworkCode, t.StationCode, t.LocationCode, t.ChannelCode}, ".")
+}
+
+// getChannelKey returns the NET.STA.LOC.CHA key of a record, without padding.
+func getChannelKey(f *FixedSection) string {
+	return strings.Join([]string{
+		strings.TrimSpace(f.NetworkCode),
+		strings.TrimSpace(f.StationCode),
+		strings.TrimSpace(f.LocationCode),
+		strings.TrimSpace(f.ChannelCode),
+	}, ".")
+}
+
+// appendSamples appends decoded samples to dst one by one. Records built by
+// Append hold their samples as a single []int32 element, which is flattened.
+func appendSamples(dst []any, decoded []any) []any {
+	for _, v := range decoded {
+		switch samples := v.(type) {
+		case []int32:
+			for _, sample := range samples {
+				dst = append(dst, sample)
+			}
+		case []float64:
+			for _, sample := range samples {
+				dst = append(dst, sample)
+			}
+		default:
+			dst = append(dst, v)
+		}
+	}
+
+	return dst
+}
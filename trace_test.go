@@ -0,0 +1,212 @@
+package mseedio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// appendTestRecord appends an INT32 record of n samples at 1 Hz, counting up
+// from base.
+func appendTestRecord(t *testing.T, m *MiniSeedData, seq string, start time.Time, n int, base int32, options AppendOptions) {
+	t.Helper()
+	data := make([]int32, n)
+	for i := range data {
+		data[i] = base + int32(i)
+	}
+	options.SampleRate = 1
+	options.StartTime = start
+	options.SequenceNumber = seq
+	options.StationCode, options.LocationCode = "AAAAA", "BB"
+	options.ChannelCode, options.NetworkCode = "EHZ", "CC"
+	if err := m.Append(data, &options); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLeapSecondBTime verifies that a start time inside a leap second is
+// written and read back as second 60.
+func TestLeapSecondBTime(t *testing.T) {
+	start := time.Date(2017, 1, 1, 0, 0, 0, 500000000, time.UTC)
+
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	appendTestRecord(t, &m, "000001", start, 5, 0, AppendOptions{
+		LeapSecond: true, ActivityFlags: ACTIVITY_LEAP_POSITIVE,
+	})
+	dataBytes, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataBytes[24] != 23 || dataBytes[25] != 59 || dataBytes[26] != 60 {
+		t.Fatalf("want 23:59:60 on disk, got %02d:%02d:%02d", dataBytes[24], dataBytes[25], dataBytes[26])
+	}
+
+	var got MiniSeedData
+	if err := got.ReadFromReader(bytes.NewReader(dataBytes)); err != nil {
+		t.Fatal(err)
+	}
+	fs := got.Series[0].FixedSection
+	want := BTime{Year: 2016, Day: 366, Hour: 23, Minute: 59, Second: 60, Ticks: 5000}
+	if fs.StartBTime != want || !fs.StartTime.Equal(start) {
+		t.Fatalf("want %+v at %s, got %+v at %s", want, start, fs.StartBTime, fs.StartTime)
+	}
+	if end := got.Series[0].EndTime(); !end.Equal(start.Add(3 * time.Second)) {
+		t.Fatalf("unexpected end time %s", end)
+	}
+}
+
+// TestLeapSecondOutsideMidnight checks that the leap flag only applies in the
+// first second of a UTC day, as leap seconds are 23:59:60 UTC.
+func TestLeapSecondOutsideMidnight(t *testing.T) {
+	noon := time.Date(2016, 12, 31, 12, 30, 0, 0, time.UTC)
+	if got, want := NewBTime(noon, true), NewBTime(noon, false); got != want || got.Minute != 30 || got.Second != 0 {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	if got := NewBTime(noon.Add(11*time.Hour+30*time.Minute), true); got.Day != 366 || got.Hour != 23 || got.Second != 60 {
+		t.Errorf("want 23:59:60 of day 366, got %+v", got)
+	}
+
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	err := m.Append([]int32{1, 2, 3}, &AppendOptions{
+		SampleRate: 1, StartTime: noon, LeapSecond: true, SequenceNumber: "000001",
+		StationCode: "AAAAA", LocationCode: "BB", ChannelCode: "EHZ", NetworkCode: "CC",
+	})
+	if err == nil {
+		t.Error("want error for a leap second at 12:30:00")
+	}
+}
+
+// TestLeapSecondSampleTimes checks that sample times never go backwards in
+// records starting before and inside a leap second.
+func TestLeapSecondSampleTimes(t *testing.T) {
+	for _, c := range []struct {
+		start time.Time
+		leap  bool
+	}{
+		{time.Date(2016, 12, 31, 23, 59, 59, 500000000, time.UTC), false},
+		{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), true},         // 23:59:60.0000
+		{time.Date(2017, 1, 1, 0, 0, 0, 700000000, time.UTC), true}, // 23:59:60.7000
+	} {
+		var m MiniSeedData
+		_ = m.Init(INT32, MSBFIRST)
+		if err := m.Append(make([]int32, 300), &AppendOptions{
+			SampleRate: 100, StartTime: c.start, SequenceNumber: "000001",
+			LeapSecond: c.leap, ActivityFlags: ACTIVITY_LEAP_POSITIVE,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		s := &m.Series[0]
+		if c.leap && !s.FixedSection.StartBTime.IsLeapSecond() {
+			t.Fatalf("%s: start not inside the leap second", c.start)
+		}
+
+		last := s.sampleTime(0)
+		for i := 1; i < 300; i++ {
+			if next := s.sampleTime(i); next.Before(last) {
+				t.Fatalf("%s: sample %d at %s before %s", c.start, i, next, last)
+			} else {
+				last = next
+			}
+		}
+		// 2.99 s of samples, one of them inside the leap second
+		if want := c.start.Add(1990 * time.Millisecond); !s.EndTime().Equal(want) {
+			t.Errorf("%s: want end %s, got %s", c.start, want, s.EndTime())
+		}
+	}
+}
+
+// TestTracesAcrossLeapSecond merges records around the 2016 leap second and
+// checks that no bogus gap or overlap is reported.
+func TestTracesAcrossLeapSecond(t *testing.T) {
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	// 23:59:50 ... 23:59:59, 23:59:60, 00:00:00 ... 00:00:08
+	appendTestRecord(t, &m, "000001", time.Date(2016, 12, 31, 23, 59, 50, 0, time.UTC), 20, 0, AppendOptions{
+		ActivityFlags: ACTIVITY_LEAP_POSITIVE,
+	})
+	appendTestRecord(t, &m, "000002", time.Date(2017, 1, 1, 0, 0, 9, 0, time.UTC), 10, 0, AppendOptions{})
+	// A gap of 5 samples later on
+	appendTestRecord(t, &m, "000003", time.Date(2017, 1, 1, 0, 0, 24, 0, time.UTC), 10, 0, AppendOptions{})
+
+	traces := m.Traces()
+	if len(traces) != 2 {
+		t.Fatalf("want 2 traces, got %d", len(traces))
+	}
+	if len(traces[0].Samples) != 30 || traces[0].ChannelCode != "EHZ" {
+		t.Fatalf("unexpected first trace %+v", traces[0])
+	}
+
+	gaps := m.Gaps()
+	if len(gaps) != 1 || gaps[0].Duration != 5*time.Second {
+		t.Fatalf("want one 5s gap, got %+v", gaps)
+	}
+}
+
+// TestTracesQualityPriority overlaps raw data with reprocessed Q and M data
+// and checks that the better quality wins sample by sample.
+func TestTracesQualityPriority(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var m MiniSeedData
+	_ = m.Init(INT32, MSBFIRST)
+	appendTestRecord(t, &m, "000001", start, 20, 0, AppendOptions{})
+	appendTestRecord(t, &m, "000002", start.Add(5*time.Second), 5, 100, AppendOptions{DataQuality: "Q"})
+	appendTestRecord(t, &m, "000003", start.Add(8*time.Second), 4, 200, AppendOptions{DataQuality: "M"})
+	if err := m.Append([]int32{0}, &AppendOptions{DataQuality: "X"}); err == nil {
+		t.Fatal("expected an error for an invalid data quality")
+	}
+
+	traces := m.Traces()
+	if len(traces) != 1 || traces[0].DataQuality != "M" || !traces[0].StartTime.Equal(start) {
+		t.Fatalf("want a single M trace, got %+v", traces)
+	}
+	want := []int32{0, 1, 2, 3, 4, 100, 101, 102, 200, 201, 202, 203, 12, 13, 14, 15, 16, 17, 18, 19}
+	for i, v := range traces[0].Samples {
+		if v != want[i] {
+			t.Fatalf("sample %d: want %d, got %v (%v)", i, want[i], v, traces[0].Samples)
+		}
+	}
+	if gaps := m.Gaps(); len(gaps) != 0 {
+		t.Fatalf("want no gaps, got %+v", gaps)
+	}
+
+	dataBytes, err := m.Encode(OVERWRITE, MSBFIRST)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got MiniSeedData
+	if err := got.ReadFromReader(bytes.NewReader(dataBytes), WithQualities("Q", "M")); err != nil {
+		t.Fatal(err)
+	}
+	if got.Records != 2 || got.Samples != 9 || got.Series[0].FixedSection.DataQuality != "Q" {
+		t.Fatalf("quality filter not applied: %d records, %d samples", got.Records, got.Samples)
+	}
+}
+
+func TestTraceFloat64s(t *testing.T) {
+	trace := Trace{Samples: []any{int32(-3), float32(0.5), 2.25, int16(7)}}
+	got, err := trace.Float64s()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{-3, 0.5, 2.25, 7} {
+		if got[i] != want {
+			t.Errorf("sample %d: want %v, got %v", i, want, got[i])
+		}
+	}
+
+	trace.Samples = []any{int32(1), "ABC"}
+	if _, err := trace.Float64s(); err == nil {
+		t.Error("want error for a text sample")
+	}
+}
+
+func TestQualityRank(t *testing.T) {
+	for i, quality := range []string{"X", "D", "R", "Q", "M"} {
+		if rank := QualityRank(quality); rank != i {
+			t.Errorf("%s: want rank %d, got %d", quality, i, rank)
+		}
+	}
+}
@@ -10,6 +10,13 @@ const (
 	BLOCKETTE100X_SECTION_LENGTH = 16
 )
 
+// The length of other blockettes, 2000 is variable beyond its header
+const (
+	BLOCKETTE500_LENGTH         = 200
+	BLOCKETTE1001_LENGTH        = 8
+	BLOCKETTE2000_HEADER_LENGTH = 15
+)
+
 // First significant bit
 const (
 	LSBFIRST = 0
@@ -49,12 +56,13 @@ type FixedSection struct {
 	ChannelCode      string
 	NetworkCode      string
 	StartTime        time.Time
+	StartBTime       BTime // StartTime as stored, may hold a leap second
 	SamplesNumber    int32
 	SampleFactor     int32
 	SampleMultiplier int32
-	ActivityFlags    int32
-	IOClockFlags     int32
-	DataQualityFlags int32
+	ActivityFlags    ActivityFlags
+	IOClockFlags     IOClockFlags
+	DataQualityFlags DataQualityFlags
 	BlockettesFollow int32
 	TimeCorrection   int32
 	DataStartOffset  int32
@@ -64,21 +72,41 @@ type FixedSection struct {
 
 // blocketteSection is the blockette header section of a MiniSeed record
 type BlocketteSection struct {
-	BlocketteCode  int32         // Blockette 100*
-	NextBlockette  int32         // Blockette 100*
-	EncodingFormat int32         // Blockette 1000
-	BitOrder       int32         // Blockette 1000
-	RecordLength   int32         // Blockette 1000
-	TimingQuality  int32         // Blockette 1001
-	Microseconds   int32         // Blockette 1001
-	FrameCount     int32         // Blockette 1001
-	ReaderOffset   SectionOffset // Used when parsing
+	BlocketteCode    int32         // Blockette 100*
+	NextBlockette    int32         // Blockette 100*
+	EncodingFormat   int32         // Blockette 1000
+	BitOrder         int32         // Blockette 1000
+	RecordLength     int32         // Blockette 1000
+	TimingQuality    int32         // Blockette 1001
+	Microseconds     int32         // Blockette 1001, 500
+	FrameCount       int32         // Blockette 1001
+	VCOCorrection    float32       // Blockette 500
+	ExceptionTime    time.Time     // Blockette 500
+	ReceptionQuality int32         // Blockette 500
+	ExceptionCount   int32         // Blockette 500
+	ExceptionType    string        // Blockette 500
+	ClockModel       string        // Blockette 500
+	ClockStatus      string        // Blockette 500
+	RecordNumber     int32         // Blockette 2000
+	OpaqueOrder      int32         // Blockette 2000
+	OpaqueFlags      int32         // Blockette 2000
+	OpaqueTags       []string      // Blockette 2000
+	OpaqueData       []byte        // Blockette 2000
+	Value            any           // Custom blockettes, see RegisterBlockette
+	Payload          []byte        // Unregistered blockettes, kept verbatim
+	ReaderOffset     SectionOffset // Used when parsing
 }
 
 // dataSection includes the decoded data and the raw data
 type DataSection struct {
 	Decoded []any
 	RawData []byte
+
+	// Pending decode, see WithLazyDecode
+	lazy     bool
+	samples  int
+	encoding int
+	bitOrder int
 }
 
 // dataSeries corresponds to a single data series in a MiniSeed record
@@ -86,6 +114,8 @@ type DataSeries struct {
 	DataSection      DataSection
 	FixedSection     FixedSection
 	BlocketteSection BlocketteSection
+	Blockettes       []BlocketteSection // Chained after BlocketteSection
+	Warnings         []error            // Steim integrity errors, see INTEGRITY_WARN
 }
 
 // MiniSeedData is the main struct for a MiniSeed record
@@ -101,11 +131,18 @@ type MiniSeedData struct {
 
 // AppendOptions is used when appending a MiniSeed record
 type AppendOptions struct {
-	SampleRate     float64
-	SequenceNumber string
-	StationCode    string
-	LocationCode   string
-	ChannelCode    string
-	NetworkCode    string
-	StartTime      time.Time
+	SampleRate       float64
+	DataQuality      string // D, R, Q or M, defaults to D
+	SequenceNumber   string
+	StationCode      string
+	LocationCode     string
+	ChannelCode      string
+	NetworkCode      string
+	StartTime        time.Time
+	LeapSecond       bool // StartTime, within the first second of a UTC day, lies in the leap second before, stored as second 60
+	ActivityFlags    ActivityFlags
+	IOClockFlags     IOClockFlags
+	DataQualityFlags DataQualityFlags
+	Blockettes       []BlocketteSection // Chained after blockette 1000 (e.g. 500, 2000)
+	RecordLength     int                // Record size in bytes, a power of 2; splits data across records, 0 sizes one record to fit
 }
@@ -3,8 +3,10 @@ package mseedio
 import (
 	"fmt"
 	"math"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -60,61 +62,6 @@ func setSignToUint(value, bitWidth uint32) int32 {
 	return int32(value)
 }
 
-// getMergedUint combines bytes into an uint number by its space
-func getMergedUint(data []byte, space, bitOrder int) (uint, error) {
-	if space <= 0 || space > 32 {
-		return 0, fmt.Errorf("invalid bits space value")
-	}
-
-	numSegments := 32 / space
-	if len(data) != numSegments {
-		return 0, fmt.Errorf("invalid data length")
-	}
-
-	var number uint
-
-	if bitOrder == LSBFIRST {
-		for i := 0; i < numSegments; i++ {
-			number |= uint(data[i]) << (space * i)
-		}
-		return number, nil
-	}
-
-	for i := numSegments - 1; i >= 0; i-- {
-		number |= uint(data[i]) << (space * (numSegments - 1 - i))
-	}
-
-	return number, nil
-}
-
-// getSplitedBytes splits a number into bytes by the given bit space
-func getSplitedBytes(number uint, space, bitOrder int) ([]byte, error) {
-	if space <= 0 || space > 32 {
-		return nil, fmt.Errorf("invalid bits space value")
-	}
-
-	numSegments := 32 / space
-	dataArray := make([]byte, 0, numSegments)
-	mask := (1 << space) - 1
-
-	if bitOrder == LSBFIRST {
-		for i := 0; i < numSegments; i++ {
-			data := byte(number & uint(mask))
-			dataArray = append(dataArray, data)
-			number >>= space
-		}
-
-		return dataArray, nil
-	}
-
-	for i := numSegments - 1; i >= 0; i-- {
-		data := byte((number >> (space * i)) & uint(mask))
-		dataArray = append(dataArray, data)
-	}
-
-	return dataArray, nil
-}
-
 // getBitOrder returns bit order from SectionEndOffset
 func getBitOrder(buffer []byte) (int, error) {
 	if len(buffer) < 2 {
@@ -134,14 +81,56 @@ func getBitOrder(buffer []byte) (int, error) {
 	return -1, fmt.Errorf("buffer is not SectionEndOffset")
 }
 
-// getBlocketteType returns blockette type
+// getBlocketteType returns blockette type, an unsigned 16-bit field
 func getBlocketteType(buffer []byte, bitOrder int) (int32, error) {
 	if len(buffer) < 2 {
 		return 0, fmt.Errorf("buffer is too short")
 	}
 
-	typ := assembleInt(buffer, 2, bitOrder)
-	return typ, nil
+	typ := assembleUint(buffer, 2, bitOrder)
+	return int32(typ), nil
+}
+
+// getBlocketteLength returns the on-disk length of a parsed blockette, or 0
+// if it cannot be told from the decoded fields (custom blockettes).
+func getBlocketteLength(b *BlocketteSection) int {
+	switch b.BlocketteCode {
+	case 1000:
+		return 8
+	case 1001:
+		return BLOCKETTE1001_LENGTH
+	case 500:
+		return BLOCKETTE500_LENGTH
+	case 2000:
+		length := BLOCKETTE2000_HEADER_LENGTH + len(b.OpaqueData)
+		for _, tag := range b.OpaqueTags {
+			length += len(tag) + 1
+		}
+		return length
+	}
+
+	if b.Payload != nil {
+		return 4 + len(b.Payload)
+	}
+	return 0
+}
+
+// getBlocketteEnd bounds the blockette at start within the record beginning
+// at recordStart: it ends where the next blockette begins or, for the last
+// one, where the data begins.
+func getBlocketteEnd(buffer []byte, recordStart, start, dataStart, bitOrder int) int {
+	end := len(buffer)
+	if dataStart > start && dataStart < end {
+		end = dataStart
+	}
+	if start+4 <= len(buffer) {
+		next := recordStart + int(assembleInt(buffer[start+2:start+4], 2, bitOrder))
+		if next > start && next < end {
+			end = next
+		}
+	}
+
+	return end
 }
 
 // getDaysByDate returns the day of the year (1-366).
@@ -158,3 +147,39 @@ func getMonthByDays(year, days int) time.Time {
 
 	return time.Date(year, time.January, days, 0, 0, 0, 0, time.UTC)
 }
+
+// parallelFor calls fn for every index in [0, n) on up to concurrency
+// goroutines, or GOMAXPROCS of them if concurrency < 1, and waits for all.
+func parallelFor(n, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+	if concurrency <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var (
+		wg      sync.WaitGroup
+		indexes = make(chan int)
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
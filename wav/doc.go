@@ -0,0 +1,19 @@
+// Package wav writes traces as WAV audio, for sonification. Seismic rates
+// are far below hearing, so the audio plays faster by a speed-up factor: a
+// 100 Hz trace sped up 441 times plays at 44.1 kHz, a day lasting about
+// three minutes.
+//
+//	traces := ms.Traces() // Z, N and E of a station
+//	err := wav.WriteFile("ANMO.wav", traces, &wav.Options{
+//		Format:     wav.PCM24,
+//		SpeedUp:    441,
+//		RemoveMean: true,
+//	})
+//
+// Each trace is a channel, so that three components make a three-channel
+// file. Samples are normalized to the peak of all channels, keeping their
+// relative amplitudes, unless Options.Scale sets full scale, and written as
+// 16, 24 or 32-bit PCM or as 32-bit floats. Files of more than 2 channels
+// or 16 bits use WAVE_FORMAT_EXTENSIBLE, without speaker positions, as
+// players expect of them.
+package wav
@@ -0,0 +1,243 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// Sample formats
+const (
+	PCM16   = 1 // 16-bit signed integers
+	PCM24   = 2 // 24-bit signed integers
+	PCM32   = 3 // 32-bit signed integers
+	FLOAT32 = 4 // 32-bit IEEE floats
+)
+
+const (
+	WAVE_FORMAT_PCM        = 1
+	WAVE_FORMAT_IEEE_FLOAT = 3
+	WAVE_FORMAT_EXTENSIBLE = 0xfffe // Format tag given by a sub-format GUID instead
+	MAX_SAMPLE_RATE        = 384000 // Highest audio rate accepted, in Hz
+)
+
+// subFormatSuffix follows the format tag in the sub-format GUID of
+// WAVE_FORMAT_EXTENSIBLE, KSDATAFORMAT_SUBTYPE_PCM being the tag 1 with it.
+var subFormatSuffix = []byte{0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+// Options sets the audio of Write. A nil Options writes 16-bit PCM at the
+// rate of the traces, normalized to their peak.
+type Options struct {
+	Format     int     // PCM16, PCM24, PCM32 or FLOAT32, PCM16 if 0
+	SpeedUp    float64 // Audio rate over the trace rate, 1 if 0, the audio rate being rounded to Hz
+	Scale      float64 // Full scale per trace unit, 0 normalizing the peak of all channels to full scale
+	RemoveMean bool    // Take the mean of each channel off first
+}
+
+// Write writes traces of a same sample rate as the channels of a WAV file,
+// in their order, such as Z, N and E for three components. Channels start
+// at the earliest trace, those starting later or ending sooner being padded
+// with silence. Samples beyond full scale are clipped.
+func Write(w io.Writer, traces []mseedio.Trace, options *Options) error {
+	if options == nil {
+		options = &Options{}
+	}
+	format, speedUp := options.Format, options.SpeedUp
+	if format == 0 {
+		format = PCM16
+	}
+	if speedUp == 0 {
+		speedUp = 1
+	}
+
+	width, tag := 0, WAVE_FORMAT_PCM
+	switch format {
+	case PCM16:
+		width = 2
+	case PCM24:
+		width = 3
+	case PCM32:
+		width = 4
+	case FLOAT32:
+		width, tag = 4, WAVE_FORMAT_IEEE_FLOAT
+	default:
+		return fmt.Errorf("unknown sample format %d", format)
+	}
+
+	// Frames, of a sample per channel, must fit the 16-bit block align
+	if len(traces)*width > math.MaxUint16 {
+		return fmt.Errorf("%d traces, too many channels for %d-byte samples", len(traces), width)
+	}
+	channels, rate, err := getChannels(traces, options.RemoveMean)
+	if err != nil {
+		return err
+	}
+	audioRate := math.Round(rate * speedUp)
+	if speedUp < 0 || audioRate < 1 || audioRate > MAX_SAMPLE_RATE {
+		return fmt.Errorf("audio rate %v Hz of %v Hz sped up %v times out of range", audioRate, rate, speedUp)
+	}
+	blockAlign := len(channels) * width
+	byteRate := int64(audioRate) * int64(blockAlign)
+	if byteRate > math.MaxUint32 {
+		return fmt.Errorf("%d channels at %v Hz, byte rate too high for WAV", len(channels), audioRate)
+	}
+
+	scale := options.Scale
+	if scale == 0 {
+		var peak float64
+		for _, c := range channels {
+			for _, v := range c {
+				peak = math.Max(peak, math.Abs(v))
+			}
+		}
+		if peak > 0 {
+			scale = 1 / peak
+		}
+	}
+
+	frames := len(channels[0])
+	dataLength := frames * blockAlign
+	padding := dataLength % 2
+
+	// More than 2 channels or 16 bits take WAVE_FORMAT_EXTENSIBLE, with
+	// no speaker position given to the channels
+	extensible := len(channels) > 2 || width > 2
+	fmtLength := 16
+	if extensible {
+		fmtLength = 40
+	}
+
+	// RIFF header, fmt chunk, fact chunk for floats, then the data chunk
+	var header []byte
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, 0) // Set below
+	header = append(header, "WAVE"...)
+	header = append(header, "fmt "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(fmtLength))
+	if extensible {
+		header = binary.LittleEndian.AppendUint16(header, WAVE_FORMAT_EXTENSIBLE)
+	} else {
+		header = binary.LittleEndian.AppendUint16(header, uint16(tag))
+	}
+	header = binary.LittleEndian.AppendUint16(header, uint16(len(channels)))
+	header = binary.LittleEndian.AppendUint32(header, uint32(audioRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(byteRate))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(8*width))
+	if extensible {
+		header = binary.LittleEndian.AppendUint16(header, 22)              // Extension size
+		header = binary.LittleEndian.AppendUint16(header, uint16(8*width)) // Valid bits
+		header = binary.LittleEndian.AppendUint32(header, 0)               // Channel mask
+		header = binary.LittleEndian.AppendUint32(header, uint32(tag))
+		header = append(header, subFormatSuffix...)
+	}
+	if tag == WAVE_FORMAT_IEEE_FLOAT {
+		header = append(header, "fact"...)
+		header = binary.LittleEndian.AppendUint32(header, 4)
+		header = binary.LittleEndian.AppendUint32(header, uint32(frames))
+	}
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(dataLength))
+	riffLength := len(header) - 8 + dataLength + padding
+	if int64(riffLength) > math.MaxUint32 {
+		return fmt.Errorf("%d bytes of audio too long for WAV", dataLength)
+	}
+	binary.LittleEndian.PutUint32(header[4:], uint32(riffLength))
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+	sample := make([]byte, width)
+	for i := 0; i < frames; i++ {
+		for _, c := range channels {
+			v := math.Max(-1, math.Min(1, c[i]*scale))
+			switch format {
+			case PCM16:
+				binary.LittleEndian.PutUint16(sample, uint16(int16(math.Round(v*math.MaxInt16))))
+			case PCM24:
+				s := int32(math.Round(v * (1<<23 - 1)))
+				sample[0], sample[1], sample[2] = byte(s), byte(s>>8), byte(s>>16)
+			case PCM32:
+				binary.LittleEndian.PutUint32(sample, uint32(int32(math.Round(v*math.MaxInt32))))
+			case FLOAT32:
+				binary.LittleEndian.PutUint32(sample, math.Float32bits(float32(v)))
+			}
+			bw.Write(sample)
+		}
+	}
+	if padding != 0 {
+		bw.WriteByte(0)
+	}
+
+	return bw.Flush()
+}
+
+// WriteFile writes traces to a WAV file, see Write.
+func WriteFile(name string, traces []mseedio.Trace, options *Options) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := Write(f, traces, options); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// getChannels returns the samples of traces aligned on the earliest start,
+// with their common sample rate.
+func getChannels(traces []mseedio.Trace, removeMean bool) ([][]float64, float64, error) {
+	if len(traces) == 0 {
+		return nil, 0, fmt.Errorf("no trace to write")
+	}
+
+	rate, start := traces[0].SampleRate, traces[0].StartTime
+	for i := range traces {
+		t := &traces[i]
+		if t.SampleRate <= 0 || math.Abs(t.SampleRate-rate) > rate*1e-6 {
+			return nil, 0, fmt.Errorf("trace %d: sample rate %v Hz, want %v Hz", i, t.SampleRate, rate)
+		}
+		if t.StartTime.Before(start) {
+			start = t.StartTime
+		}
+	}
+
+	var (
+		channels = make([][]float64, len(traces))
+		frames   int
+		offsets  = make([]int, len(traces))
+	)
+	for i := range traces {
+		t := &traces[i]
+		samples, err := t.Float64s()
+		if err != nil {
+			return nil, 0, fmt.Errorf("trace %d: %w", i, err)
+		}
+		if removeMean && len(samples) > 0 {
+			var sum float64
+			for _, v := range samples {
+				sum += v
+			}
+			for j := range samples {
+				samples[j] -= sum / float64(len(samples))
+			}
+		}
+
+		offsets[i] = int(math.Round(t.StartTime.Sub(start).Seconds() * rate))
+		channels[i] = samples
+		if n := offsets[i] + len(samples); n > frames {
+			frames = n
+		}
+	}
+	for i, samples := range channels {
+		channels[i] = make([]float64, frames)
+		copy(channels[i][offsets[i]:], samples)
+	}
+
+	return channels, rate, nil
+}
@@ -0,0 +1,175 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bclswl0827/mseedio"
+)
+
+// getTestTraces returns the Z, N and E traces at 100 Hz, N starting 2 samples
+// late and E ending 1 sample early.
+func getTestTraces() []mseedio.Trace {
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	z := mseedio.Trace{ChannelCode: "BHZ", SampleRate: 100, StartTime: start,
+		Samples: []any{int32(0), int32(1000), int32(-2000), int32(500)}}
+	n := mseedio.Trace{ChannelCode: "BHN", SampleRate: 100, StartTime: start.Add(20 * time.Millisecond),
+		Samples: []any{int32(100), int32(-100)}}
+	e := mseedio.Trace{ChannelCode: "BHE", SampleRate: 100, StartTime: start,
+		Samples: []any{float64(10), float64(20), float64(30)}}
+	return []mseedio.Trace{z, n, e}
+}
+
+// getChunks splits a WAV file into its chunks, after checking the RIFF
+// header.
+func getChunks(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	if string(data[:4]) != "RIFF" || string(data[8:12]) != "WAVE" || int(binary.LittleEndian.Uint32(data[4:])) != len(data)-8 {
+		t.Fatalf("invalid RIFF header % x", data[:12])
+	}
+
+	chunks := make(map[string][]byte)
+	for pos := 12; pos+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		chunks[string(data[pos:pos+4])] = data[pos+8 : pos+8+length]
+		pos += 8 + length + length%2
+	}
+	return chunks
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, getTestTraces(), &Options{SpeedUp: 441}); err != nil {
+		t.Fatal(err)
+	}
+	chunks := getChunks(t, buf.Bytes())
+
+	f := chunks["fmt "]
+	if tag, channels, rate := binary.LittleEndian.Uint16(f), binary.LittleEndian.Uint16(f[2:]), binary.LittleEndian.Uint32(f[4:]); tag != WAVE_FORMAT_EXTENSIBLE || channels != 3 || rate != 44100 {
+		t.Errorf("want extensible format, 3 channels at 44100 Hz, got %d, %d at %d", tag, channels, rate)
+	}
+	if byteRate, align, bits := binary.LittleEndian.Uint32(f[8:]), binary.LittleEndian.Uint16(f[12:]), binary.LittleEndian.Uint16(f[14:]); byteRate != 6*44100 || align != 6 || bits != 16 {
+		t.Errorf("want 6-byte frames of 16 bits at %d bytes/s, got %d and %d at %d", 6*44100, align, bits, byteRate)
+	}
+	if len(f) != 40 || binary.LittleEndian.Uint16(f[16:]) != 22 || binary.LittleEndian.Uint16(f[18:]) != 16 ||
+		binary.LittleEndian.Uint32(f[20:]) != 0 || !bytes.Equal(f[24:], append([]byte{1, 0, 0, 0}, subFormatSuffix...)) {
+		t.Errorf("want PCM extension of 16 valid bits, got % x", f[16:])
+	}
+
+	data := chunks["data"]
+	if len(data) != 4*6 {
+		t.Fatalf("want 4 frames, got %d bytes", len(data))
+	}
+	sample := func(frame, channel int) int16 {
+		return int16(binary.LittleEndian.Uint16(data[6*frame+2*channel:]))
+	}
+	// The peak, -2000 on Z, is full scale
+	if sample(2, 0) != -32767 || sample(1, 0) != 16384 || sample(3, 0) != 8192 {
+		t.Errorf("unexpected Z %d %d %d", sample(1, 0), sample(2, 0), sample(3, 0))
+	}
+	if sample(0, 1) != 0 || sample(1, 1) != 0 || sample(2, 1) != 1638 || sample(3, 1) != -1638 {
+		t.Errorf("unexpected N %d %d %d %d", sample(0, 1), sample(1, 1), sample(2, 1), sample(3, 1))
+	}
+	if sample(2, 2) != 492 || sample(3, 2) != 0 {
+		t.Errorf("unexpected E %d %d", sample(2, 2), sample(3, 2))
+	}
+}
+
+func TestWriteFormats(t *testing.T) {
+	traces := getTestTraces()[:1]
+	for _, c := range []struct {
+		format, bits, subFormat int
+		last                    []byte // Last sample, at a quarter of full scale
+	}{
+		{PCM24, 24, WAVE_FORMAT_PCM, []byte{0x00, 0x00, 0x20}},
+		{PCM32, 32, WAVE_FORMAT_PCM, []byte{0x00, 0x00, 0x00, 0x20}},
+		{FLOAT32, 32, WAVE_FORMAT_IEEE_FLOAT, binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.25))},
+	} {
+		var buf bytes.Buffer
+		if err := Write(&buf, traces, &Options{Format: c.format, Scale: 1.0 / 2000}); err != nil {
+			t.Fatal(err)
+		}
+		chunks := getChunks(t, buf.Bytes())
+		f := chunks["fmt "]
+		if bits := binary.LittleEndian.Uint16(f[14:]); int(bits) != c.bits {
+			t.Errorf("format %d: want %d bits, got %d", c.format, c.bits, bits)
+		}
+		if tag, subFormat := binary.LittleEndian.Uint16(f), binary.LittleEndian.Uint32(f[24:]); tag != WAVE_FORMAT_EXTENSIBLE || int(subFormat) != c.subFormat {
+			t.Errorf("format %d: want extensible format of sub-format %d, got %d of %d", c.format, c.subFormat, tag, subFormat)
+		}
+		data := chunks["data"]
+		if got := data[len(data)-len(c.last):]; !bytes.Equal(got, c.last) {
+			t.Errorf("format %d: want last sample % x, got % x", c.format, c.last, got)
+		}
+		if c.format == FLOAT32 && binary.LittleEndian.Uint32(chunks["fact"]) != 4 {
+			t.Errorf("want 4 frames in fact chunk, got %d", binary.LittleEndian.Uint32(chunks["fact"]))
+		}
+	}
+
+	// Up to 2 channels of 16 bits keep the plain PCM format
+	var plain bytes.Buffer
+	if err := Write(&plain, getTestTraces()[:2], nil); err != nil {
+		t.Fatal(err)
+	}
+	if f := getChunks(t, plain.Bytes())["fmt "]; len(f) != 16 || binary.LittleEndian.Uint16(f) != WAVE_FORMAT_PCM {
+		t.Errorf("want 16-byte PCM fmt chunk, got % x", f)
+	}
+
+	// Odd data length padded, samples beyond full scale clipped
+	traces[0].Samples = traces[0].Samples[:3]
+	var buf bytes.Buffer
+	if err := Write(&buf, traces, &Options{Format: PCM24, Scale: 1.0 / 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len()%2 != 0 {
+		t.Errorf("want even file length, got %d", buf.Len())
+	}
+	if data := getChunks(t, buf.Bytes())["data"]; len(data) != 9 || !bytes.Equal(data[6:9], []byte{0x01, 0x00, 0x80}) {
+		t.Errorf("want -full scale in 9 bytes, got % x", data)
+	}
+}
+
+func TestWriteRemoveMean(t *testing.T) {
+	traces := []mseedio.Trace{{SampleRate: 1, Samples: []any{int32(1000), int32(1002), int32(998)}}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, traces, &Options{RemoveMean: true}); err != nil {
+		t.Fatal(err)
+	}
+	data := getChunks(t, buf.Bytes())["data"]
+	if got := []int16{int16(binary.LittleEndian.Uint16(data)), int16(binary.LittleEndian.Uint16(data[2:])), int16(binary.LittleEndian.Uint16(data[4:]))}; got[0] != 0 || got[1] != 32767 || got[2] != -32767 {
+		t.Errorf("want 0, full scale and -full scale, got %v", got)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	mixed := getTestTraces()
+	mixed[1].SampleRate = 40
+	ascii := []mseedio.Trace{{SampleRate: 1, Samples: []any{"GPS ok"}}}
+	// 16384 4-byte channels overflow the block align, 16383 the byte rate
+	many := make([]mseedio.Trace, 16384)
+	for i := range many {
+		many[i] = mseedio.Trace{SampleRate: MAX_SAMPLE_RATE, Samples: []any{int32(i)}}
+	}
+
+	for name, c := range map[string]struct {
+		traces  []mseedio.Trace
+		options *Options
+	}{
+		"no trace":    {nil, nil},
+		"rates":       {mixed, nil},
+		"ascii":       {ascii, nil},
+		"format":      {getTestTraces(), &Options{Format: 8}},
+		"audio rate":  {getTestTraces(), &Options{SpeedUp: 1e4}},
+		"negative up": {getTestTraces(), &Options{SpeedUp: -1}},
+		"block align": {many, &Options{Format: PCM32}},
+		"byte rate":   {many[1:], &Options{Format: PCM32}},
+	} {
+		if err := Write(&bytes.Buffer{}, c.traces, c.options); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+}